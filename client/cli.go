@@ -2,13 +2,19 @@ package client
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"sort"
+	"strings"
 
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"golang.org/x/net/websocket"
 )
 
@@ -73,6 +79,112 @@ func HandleResponse(c *Client, resp *http.Response, pretty bool) {
 	os.Exit(exitStatus)
 }
 
+// DryRunDoer is a Doer that writes the curl command equivalent to each request instead of
+// sending it. The values of the headers listed in Redacted (typically the headers set by signers)
+// are replaced with "<redacted>" so that secrets do not show up in the output.
+type DryRunDoer struct {
+	// Output is the writer the curl commands are written to.
+	Output io.Writer
+	// Redacted lists the names of the headers whose values are not printed.
+	Redacted []string
+}
+
+// Do writes the curl command equivalent to req and returns an empty 200 response.
+func (d *DryRunDoer) Do(req *http.Request) (*http.Response, error) {
+	cmd, err := CurlCommand(req, d.Redacted...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Fprintln(d.Output, cmd); err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+		Request:    req,
+	}, nil
+}
+
+// CurlCommand returns the curl command line that sends a request equivalent to req. Headers are
+// listed in alphabetical order, the values of the headers listed in redacted are replaced with
+// "<redacted>". CurlCommand reads the request body and replaces it with an equivalent reader.
+func CurlCommand(req *http.Request, redacted ...string) (string, error) {
+	hidden := make(map[string]bool, len(redacted))
+	for _, name := range redacted {
+		hidden[http.CanonicalHeaderKey(name)] = true
+	}
+	parts := []string{"curl", "-X", req.Method, shellQuote(req.URL.String())}
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range req.Header[name] {
+			if hidden[http.CanonicalHeaderKey(name)] {
+				value = "<redacted>"
+			}
+			parts = append(parts, "-H", shellQuote(name+": "+value))
+		}
+	}
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return "", err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if len(body) > 0 {
+			parts = append(parts, "--data-binary", shellQuote(string(body)))
+		}
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// shellQuote quotes s so that it is interpreted literally by POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// FlagsFromEnv sets the flags of cmd that are not set on the command line to the value of the
+// corresponding environment variable if it is set. The name of the environment variable is prefix
+// followed by the flag name upper cased with non alphanumeric characters replaced with
+// underscores, e.g. "CELLAR_BOTTLE_ACCOUNT_ID" for the "account-id" flag and the "CELLAR_BOTTLE_"
+// prefix. The precedence is thus: explicit flag, environment variable and flag default.
+func FlagsFromEnv(cmd *cobra.Command, prefix string) error {
+	var err error
+	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed {
+			return
+		}
+		name := envName(prefix + f.Name)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+		if serr := f.Value.Set(value); serr != nil {
+			err = fmt.Errorf("invalid value %q for environment variable %s: %s", value, name, serr)
+		}
+	})
+	return err
+}
+
+// envName returns the environment variable name corresponding to name: name upper cased with non
+// alphanumeric characters replaced with underscores.
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
 // WSWrite sends STDIN lines to a websocket server.
 func WSWrite(ws *websocket.Conn) {
 	scanner := bufio.NewScanner(os.Stdin)
@@ -0,0 +1,130 @@
+package client_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/goadesign/goa/client"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/cobra"
+)
+
+var _ = Describe("CurlCommand", func() {
+	var req *http.Request
+	var redacted []string
+
+	var cmd string
+	var err error
+
+	JustBeforeEach(func() {
+		cmd, err = client.CurlCommand(req, redacted...)
+	})
+
+	Context("with a GET request with a query parameter", func() {
+		BeforeEach(func() {
+			req, _ = http.NewRequest("GET", "http://localhost:8080/bottles?limit=10", nil)
+			req.Header.Set("User-Agent", "cellar-cli/1.0")
+			req.Header.Set("Authorization", "Bearer secret")
+			redacted = []string{"authorization"}
+		})
+
+		It("prints the curl command redacting the signer header", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(cmd).Should(Equal(`curl -X GET 'http://localhost:8080/bottles?limit=10' -H 'Authorization: <redacted>' -H 'User-Agent: cellar-cli/1.0'`))
+		})
+	})
+
+	Context("with a request with a body", func() {
+		BeforeEach(func() {
+			req, _ = http.NewRequest("POST", "http://localhost:8080/bottles", strings.NewReader(`{"name":"it's"}`))
+			redacted = nil
+		})
+
+		It("prints the body and restores it", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(cmd).Should(Equal(`curl -X POST 'http://localhost:8080/bottles' --data-binary '{"name":"it'\''s"}'`))
+			body, err := ioutil.ReadAll(req.Body)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(body)).Should(Equal(`{"name":"it's"}`))
+		})
+	})
+})
+
+var _ = Describe("DryRunDoer", func() {
+	It("writes the curl command and returns an empty response", func() {
+		var out bytes.Buffer
+		doer := &client.DryRunDoer{Output: &out}
+		req, _ := http.NewRequest("GET", "http://localhost:8080/bottles?limit=10", nil)
+		resp, err := doer.Do(req)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp.StatusCode).Should(Equal(200))
+		Ω(out.String()).Should(Equal("curl -X GET 'http://localhost:8080/bottles?limit=10'\n"))
+	})
+})
+
+var _ = Describe("FlagsFromEnv", func() {
+	var cmd *cobra.Command
+	var limit int
+	var sort string
+	var args []string
+
+	var err error
+
+	BeforeEach(func() {
+		limit, sort = 0, ""
+		args = nil
+		cmd = &cobra.Command{
+			Use: "bottle",
+			PreRunE: func(cc *cobra.Command, _ []string) error {
+				return client.FlagsFromEnv(cc, "CELLAR_BOTTLE_")
+			},
+			RunE: func(*cobra.Command, []string) error { return nil },
+		}
+		cmd.Flags().IntVar(&limit, "limit", 10, "")
+		cmd.Flags().StringVar(&sort, "sort-by", "", "")
+		os.Setenv("CELLAR_BOTTLE_LIMIT", "42")
+		os.Setenv("CELLAR_BOTTLE_SORT_BY", "name")
+	})
+
+	JustBeforeEach(func() {
+		cmd.SetArgs(args)
+		err = cmd.Execute()
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("CELLAR_BOTTLE_LIMIT")
+		os.Unsetenv("CELLAR_BOTTLE_SORT_BY")
+	})
+
+	It("sets the flags that are not passed from the environment", func() {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(limit).Should(Equal(42))
+		Ω(sort).Should(Equal("name"))
+	})
+
+	Context("with flags passed on the command line", func() {
+		BeforeEach(func() {
+			args = []string{"--limit", "3"}
+		})
+
+		It("gives precedence to the command line", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(limit).Should(Equal(3))
+			Ω(sort).Should(Equal("name"))
+		})
+	})
+
+	Context("with an invalid environment variable value", func() {
+		BeforeEach(func() {
+			os.Setenv("CELLAR_BOTTLE_LIMIT", "many")
+		})
+
+		It("fails", func() {
+			Ω(err).Should(MatchError(ContainSubstring("CELLAR_BOTTLE_LIMIT")))
+		})
+	})
+})
@@ -28,6 +28,14 @@ type (
 		UserAgent string
 		// Dump indicates whether to dump request response.
 		Dump bool
+		// Doer sends the requests if not nil, the underlying http client is used otherwise.
+		Doer Doer
+	}
+
+	// Doer is the interface implemented by the values used to send HTTP requests.
+	// *http.Client implements Doer.
+	Doer interface {
+		Do(*http.Request) (*http.Response, error)
 	}
 )
 
@@ -40,17 +48,30 @@ func New(c *http.Client) *Client {
 	return &Client{Client: c}
 }
 
+// NewWithDoer creates a new API client that sends requests using doer.
+// The underlying http client is not used to send requests so that settings such as Timeout
+// must be configured on doer directly.
+func NewWithDoer(doer Doer) *Client {
+	return &Client{Client: &http.Client{}, Doer: doer}
+}
+
 // Do wraps the underlying http client Do method and adds logging.
 // The logger should be in the context.
 func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
-	req.Header.Set("User-Agent", c.UserAgent)
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
 	startedAt := time.Now()
 	id := shortID()
 	goa.LogInfo(ctx, "started", "id", id, req.Method, req.URL.String())
 	if c.Dump {
 		c.dumpRequest(ctx, req)
 	}
-	resp, err := c.Client.Do(req)
+	var doer Doer = c.Client
+	if c.Doer != nil {
+		doer = c.Doer
+	}
+	resp, err := doer.Do(req)
 	if err != nil {
 		goa.LogError(ctx, "failed", "err", err)
 		return nil, err
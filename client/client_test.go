@@ -0,0 +1,50 @@
+package client_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/context"
+
+	"github.com/goadesign/goa/client"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// stubDoer records the requests it is given and responds with a 200 status.
+type stubDoer struct {
+	requests []*http.Request
+}
+
+func (d *stubDoer) Do(req *http.Request) (*http.Response, error) {
+	d.requests = append(d.requests, req)
+	return &http.Response{
+		StatusCode: 200,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(strings.NewReader("ok")),
+		Request:    req,
+	}, nil
+}
+
+var _ = Describe("NewWithDoer", func() {
+	var doer *stubDoer
+	var c *client.Client
+
+	BeforeEach(func() {
+		doer = &stubDoer{}
+		c = client.NewWithDoer(doer)
+		c.UserAgent = "test-agent"
+	})
+
+	It("sends the requests with the doer", func() {
+		req, err := http.NewRequest("GET", "http://example.com/bottles", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		resp, err := c.Do(context.Background(), req)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp.StatusCode).Should(Equal(200))
+		Ω(doer.requests).Should(HaveLen(1))
+		Ω(doer.requests[0].URL.String()).Should(Equal("http://example.com/bottles"))
+		Ω(doer.requests[0].Header.Get("User-Agent")).Should(Equal("test-agent"))
+	})
+})
@@ -0,0 +1,188 @@
+package client
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// PayloadField describes an attribute of a payload built from a generic map, see CoerceMap.
+type PayloadField struct {
+	// Name is the name of the attribute in the design, the key of its value in the map.
+	Name string
+	// Key is the key of the attribute in the encoded payload.
+	Key string
+	// Kind is the kind of the attribute values: "boolean", "integer", "number", "string",
+	// "datetime", "uuid", "any", "array", "hash" or "object".
+	Kind string
+	// Required is true if the map must hold a value for the attribute.
+	Required bool
+	// Fields describes the attributes of the values of kind "object".
+	Fields []*PayloadField
+	// Elem describes the elements of the values of kind "array" and "hash".
+	Elem *PayloadField
+}
+
+// uuidRegex matches the canonical string representation of UUIDs.
+var uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// CoerceMap validates data against the given fields and returns a copy of it keyed by the field
+// keys where each value is coerced to the kind of its field, e.g. the string "42" becomes the
+// integer 42 and a time.Time value becomes its RFC3339 representation. The result can be encoded
+// and decoded into the payload type described by fields. CoerceMap returns an error if a
+// required field is missing, if data holds a key that is not a field name or if a value cannot
+// be coerced. Nil values are treated as missing.
+func CoerceMap(data map[string]interface{}, fields []*PayloadField) (map[string]interface{}, error) {
+	return coerceObject("", data, fields)
+}
+
+// coerceObject coerces the values of the object data described by fields, prefix is the path to
+// data used in error messages.
+func coerceObject(prefix string, data map[string]interface{}, fields []*PayloadField) (map[string]interface{}, error) {
+	known := make(map[string]bool, len(fields))
+	res := make(map[string]interface{}, len(data))
+	for _, f := range fields {
+		known[f.Name] = true
+		v, ok := data[f.Name]
+		if !ok || v == nil {
+			if f.Required {
+				return nil, fmt.Errorf("missing required field %q", prefix+f.Name)
+			}
+			continue
+		}
+		cv, err := coerceValue(prefix+f.Name, v, f)
+		if err != nil {
+			return nil, err
+		}
+		res[f.Key] = cv
+	}
+	var unknown []string
+	for k := range data {
+		if !known[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown field %q", prefix+unknown[0])
+	}
+	return res, nil
+}
+
+// coerceValue coerces v to the kind of f, name is the path to v used in error messages.
+func coerceValue(name string, v interface{}, f *PayloadField) (interface{}, error) {
+	if f == nil {
+		return v, nil
+	}
+	invalid := func(err error) error {
+		return fmt.Errorf("invalid value for field %q: %s", name, err)
+	}
+	switch f.Kind {
+	case "boolean":
+		switch b := v.(type) {
+		case bool:
+			return b, nil
+		case string:
+			res, err := strconv.ParseBool(b)
+			if err != nil {
+				return nil, invalid(fmt.Errorf("%q is not a boolean", b))
+			}
+			return res, nil
+		}
+		return nil, invalid(fmt.Errorf("%v is not a boolean", v))
+	case "integer":
+		if s, ok := v.(string); ok {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return nil, invalid(fmt.Errorf("%q is not an integer", s))
+			}
+			return n, nil
+		}
+		n, err := Int64(v)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		return n, nil
+	case "number":
+		if s, ok := v.(string); ok {
+			n, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, invalid(fmt.Errorf("%q is not a number", s))
+			}
+			return n, nil
+		}
+		n, err := Float64(v)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		return n, nil
+	case "string":
+		switch s := v.(type) {
+		case string:
+			return s, nil
+		case fmt.Stringer:
+			return s.String(), nil
+		case bool, int, int64, float64:
+			return fmt.Sprint(s), nil
+		}
+		return nil, invalid(fmt.Errorf("%v is not a string", v))
+	case "datetime":
+		switch t := v.(type) {
+		case time.Time:
+			return t.Format(time.RFC3339Nano), nil
+		case string:
+			if _, err := time.Parse(time.RFC3339, t); err != nil {
+				return nil, invalid(fmt.Errorf("%q is not a RFC3339 date time", t))
+			}
+			return t, nil
+		}
+		return nil, invalid(fmt.Errorf("%v is not a date time", v))
+	case "uuid":
+		s, ok := v.(string)
+		if st, isStringer := v.(fmt.Stringer); !ok && isStringer {
+			s, ok = st.String(), true
+		}
+		if !ok || !uuidRegex.MatchString(s) {
+			return nil, invalid(fmt.Errorf("%v is not a UUID", v))
+		}
+		return s, nil
+	case "array":
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return nil, invalid(fmt.Errorf("%v is not an array", v))
+		}
+		res := make([]interface{}, rv.Len())
+		for i := range res {
+			elem, err := coerceValue(fmt.Sprintf("%s[%d]", name, i), rv.Index(i).Interface(), f.Elem)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = elem
+		}
+		return res, nil
+	case "hash":
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, invalid(fmt.Errorf("%v is not a map", v))
+		}
+		res := make(map[string]interface{}, len(m))
+		for k, e := range m {
+			elem, err := coerceValue(fmt.Sprintf("%s[%s]", name, k), e, f.Elem)
+			if err != nil {
+				return nil, err
+			}
+			res[k] = elem
+		}
+		return res, nil
+	case "object":
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, invalid(fmt.Errorf("%v is not an object", v))
+		}
+		return coerceObject(name+".", m, f.Fields)
+	}
+	return v, nil
+}
@@ -0,0 +1,89 @@
+package client_test
+
+import (
+	"time"
+
+	"github.com/goadesign/goa/client"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CoerceMap", func() {
+	var fields = []*client.PayloadField{
+		{Name: "name", Key: "name", Kind: "string", Required: true},
+		{Name: "vintage", Key: "year", Kind: "integer"},
+		{Name: "created_at", Key: "created_at", Kind: "datetime"},
+		{Name: "tags", Key: "tags", Kind: "array", Elem: &client.PayloadField{Kind: "string"}},
+		{Name: "winery", Key: "winery", Kind: "object", Fields: []*client.PayloadField{
+			{Name: "name", Key: "name", Kind: "string", Required: true},
+		}},
+	}
+
+	var data map[string]interface{}
+
+	var coerced map[string]interface{}
+	var err error
+
+	BeforeEach(func() {
+		data = map[string]interface{}{
+			"name":       "merlot",
+			"vintage":    "2012",
+			"created_at": time.Date(2016, 1, 2, 15, 4, 5, 0, time.UTC),
+			"tags":       []string{"red", "dry"},
+		}
+	})
+
+	JustBeforeEach(func() {
+		coerced, err = client.CoerceMap(data, fields)
+	})
+
+	It("coerces the values to the field kinds", func() {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(coerced).Should(Equal(map[string]interface{}{
+			"name":       "merlot",
+			"year":       int64(2012),
+			"created_at": "2016-01-02T15:04:05Z",
+			"tags":       []interface{}{"red", "dry"},
+		}))
+	})
+
+	Context("with a missing required field", func() {
+		BeforeEach(func() {
+			delete(data, "name")
+		})
+
+		It("returns an error", func() {
+			Ω(err).Should(MatchError(`missing required field "name"`))
+		})
+	})
+
+	Context("with a missing required nested field", func() {
+		BeforeEach(func() {
+			data["winery"] = map[string]interface{}{}
+		})
+
+		It("returns an error naming the field path", func() {
+			Ω(err).Should(MatchError(`missing required field "winery.name"`))
+		})
+	})
+
+	Context("with a value that cannot be coerced", func() {
+		BeforeEach(func() {
+			data["vintage"] = "old"
+		})
+
+		It("returns an error", func() {
+			Ω(err).Should(MatchError(`invalid value for field "vintage": "old" is not an integer`))
+		})
+	})
+
+	Context("with an unknown field", func() {
+		BeforeEach(func() {
+			data["color"] = "red"
+		})
+
+		It("returns an error", func() {
+			Ω(err).Should(MatchError(`unknown field "color"`))
+		})
+	})
+})
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// NextCursor returns the value of the query string parameter param of the URL of the link with
+// the "next" relation type listed in the Link header of resp (RFC 5988), the empty string if
+// there is no such link or if its URL does not define the parameter. For example NextCursor
+// returns "abc" for param "cursor" and the header:
+//
+//	Link: <https://api.example.com/bottles?cursor=abc>; rel="next"
+func NextCursor(resp *http.Response, param string) string {
+	for _, header := range resp.Header["Link"] {
+		if target := nextLink(header); target != "" {
+			u, err := url.Parse(target)
+			if err != nil {
+				return ""
+			}
+			return u.Query().Get(param)
+		}
+	}
+	return ""
+}
+
+// nextLink returns the target of the link with the "next" relation type in the given Link header
+// value, the empty string if there is none. Link targets are delimited with angle brackets so
+// that the commas separating links are not mistaken for commas in URLs.
+func nextLink(header string) string {
+	for {
+		start := strings.Index(header, "<")
+		end := strings.Index(header, ">")
+		if start < 0 || end < start {
+			return ""
+		}
+		target := header[start+1 : end]
+		params := header[end+1:]
+		header = ""
+		if i := strings.Index(params, "<"); i >= 0 {
+			params, header = params[:i], params[i:]
+		}
+		for _, param := range strings.Split(params, ";") {
+			param = strings.Trim(strings.TrimSpace(param), ",")
+			kv := strings.SplitN(param, "=", 2)
+			if len(kv) != 2 || !strings.EqualFold(strings.TrimSpace(kv[0]), "rel") {
+				continue
+			}
+			for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(kv[1]), `"`)) {
+				if strings.EqualFold(rel, "next") {
+					return target
+				}
+			}
+		}
+	}
+}
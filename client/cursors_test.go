@@ -0,0 +1,74 @@
+package client_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/goadesign/goa/client"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NextCursor", func() {
+	var resp *http.Response
+
+	BeforeEach(func() {
+		resp = &http.Response{Header: make(http.Header)}
+	})
+
+	It("returns the empty string without Link header", func() {
+		Ω(client.NextCursor(resp, "cursor")).Should(Equal(""))
+	})
+
+	It("returns the cursor of the next link", func() {
+		resp.Header.Set("Link", `</bottles?cursor=a&fields=id,name>; rel="prev", </bottles?fields=id,name&cursor=b>; rel="next last"`)
+		Ω(client.NextCursor(resp, "cursor")).Should(Equal("b"))
+	})
+
+	It("returns the empty string if the next link does not define the parameter", func() {
+		resp.Header.Set("Link", `</bottles?page=2>; rel=next`)
+		Ω(client.NextCursor(resp, "cursor")).Should(Equal(""))
+	})
+
+	Context("with a server returning cursor linked pages", func() {
+		var srv *httptest.Server
+
+		BeforeEach(func() {
+			srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Query().Get("cursor") {
+				case "":
+					w.Header().Set("Link", fmt.Sprintf(`<%s/bottles?cursor=page2>; rel="next"`, srv.URL))
+					w.Write([]byte("page1"))
+				case "page2":
+					w.Write([]byte("page2"))
+				default:
+					w.WriteHeader(http.StatusBadRequest)
+				}
+			}))
+		})
+
+		AfterEach(func() {
+			srv.Close()
+		})
+
+		It("follows the cursors until the last page", func() {
+			var pages []string
+			cursor := ""
+			for {
+				resp, err := http.Get(srv.URL + "/bottles?cursor=" + cursor)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(resp.StatusCode).Should(Equal(http.StatusOK))
+				body, err := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				Ω(err).ShouldNot(HaveOccurred())
+				pages = append(pages, string(body))
+				if cursor = client.NextCursor(resp, "cursor"); cursor == "" {
+					break
+				}
+			}
+			Ω(pages).Should(Equal([]string{"page1", "page2"}))
+		})
+	})
+})
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// SnippetSize is the maximum number of bytes of the response body kept in DecodeError.
+const SnippetSize = 256
+
+// DecodeError is the error returned by the generated decode helpers when the response body cannot
+// be decoded. It describes the response so that mismatched responses, e.g. HTML error pages
+// returned by a proxy, are easy to diagnose.
+type DecodeError struct {
+	// Status is the response status code.
+	Status int
+	// ContentType is the value of the response Content-Type header.
+	ContentType string
+	// Snippet contains the first SnippetSize bytes of the response body at most.
+	Snippet []byte
+	// Err is the error returned by the decoder.
+	Err error
+}
+
+// NewDecodeError returns the error describing the failure to decode the body of resp, snippet
+// is the beginning of the body as returned by SnippetReader.
+func NewDecodeError(resp *http.Response, snippet []byte, err error) *DecodeError {
+	return &DecodeError{
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Snippet:     snippet,
+		Err:         err,
+	}
+}
+
+// Error returns the decoder error together with the response status, content type and body
+// snippet.
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode %d response with content type %q: %s, body: %q",
+		e.Status, e.ContentType, e.Err, e.Snippet)
+}
+
+// Unwrap returns the decoder error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// SnippetReader is a reader that keeps the first SnippetSize bytes read from the underlying
+// reader.
+type SnippetReader struct {
+	r       io.Reader
+	snippet []byte
+}
+
+// NewSnippetReader returns a reader of r that keeps the beginning of the data read.
+func NewSnippetReader(r io.Reader) *SnippetReader {
+	return &SnippetReader{r: r}
+}
+
+// Read reads from the underlying reader.
+func (r *SnippetReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if rest := SnippetSize - len(r.snippet); rest > 0 && n > 0 {
+		if rest > n {
+			rest = n
+		}
+		r.snippet = append(r.snippet, p[:rest]...)
+	}
+	return n, err
+}
+
+// Snippet returns the first SnippetSize bytes read at most.
+func (r *SnippetReader) Snippet() []byte {
+	return r.snippet
+}
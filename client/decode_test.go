@@ -0,0 +1,42 @@
+package client_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/goadesign/goa/client"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DecodeError", func() {
+	const page = "<html><body>502 Bad Gateway</body></html>"
+
+	It("describes the response that could not be decoded", func() {
+		resp := &http.Response{
+			StatusCode: http.StatusBadGateway,
+			Header:     http.Header{"Content-Type": {"text/html"}},
+		}
+		snippet := client.NewSnippetReader(strings.NewReader(page))
+		var decoded map[string]interface{}
+		err := json.NewDecoder(snippet).Decode(&decoded)
+		Ω(err).Should(HaveOccurred())
+
+		decodeErr := client.NewDecodeError(resp, snippet.Snippet(), err)
+		Ω(decodeErr.Status).Should(Equal(http.StatusBadGateway))
+		Ω(decodeErr.Err).Should(Equal(err))
+		Ω(decodeErr.Error()).Should(ContainSubstring(`failed to decode 502 response with content type "text/html"`))
+		Ω(decodeErr.Error()).Should(ContainSubstring("502 Bad Gateway"))
+	})
+
+	It("keeps at most SnippetSize bytes of the body", func() {
+		body := strings.Repeat("x", client.SnippetSize+10)
+		snippet := client.NewSnippetReader(strings.NewReader(body))
+		b, err := ioutil.ReadAll(snippet)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(b).Should(HaveLen(len(body)))
+		Ω(snippet.Snippet()).Should(Equal([]byte(body[:client.SnippetSize])))
+	})
+})
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// Fixture is the serialized form of a request written by the clients created with the
+// WithFixtureDir option. Fixtures are encoded in JSON so that they can be checked in as golden
+// files and loaded back with ReadFixture, e.g. by a transport replaying the requests.
+type Fixture struct {
+	// Method is the request HTTP method.
+	Method string `json:"method"`
+	// Path is the request path including the query string if any.
+	Path string `json:"path"`
+	// Header contains the request headers.
+	Header http.Header `json:"header,omitempty"`
+	// Body is the request body.
+	Body []byte `json:"body,omitempty"`
+}
+
+// NewFixture returns the fixture describing req. The body is read from req.GetBody so that req
+// can still be sent, requests with a body but no GetBody function are rejected.
+func NewFixture(req *http.Request) (*Fixture, error) {
+	f := &Fixture{
+		Method: req.Method,
+		Path:   req.URL.RequestURI(),
+		Header: req.Header,
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		return f, nil
+	}
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("cannot read body of %s %s", req.Method, f.Path)
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	if f.Body, err = ioutil.ReadAll(body); err != nil {
+		return nil, fmt.Errorf("failed to read body: %s", err)
+	}
+	return f, nil
+}
+
+// ReadFixture loads the fixture stored in the given file.
+func ReadFixture(file string) (*Fixture, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var f Fixture
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %s", file, err)
+	}
+	return &f, nil
+}
+
+// Write stores the fixture in the given file, overwriting it if it exists.
+func (f *Fixture) Write(file string) error {
+	b, err := json.MarshalIndent(f, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(b, '\n'), 0644)
+}
+
+// Request builds the request described by the fixture, host is the scheme and host of the
+// request URL, e.g. "http://localhost:8080".
+func (f *Fixture) Request(host string) (*http.Request, error) {
+	req, err := http.NewRequest(f.Method, host+f.Path, bytes.NewReader(f.Body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range f.Header {
+		req.Header[name] = append([]string(nil), values...)
+	}
+	return req, nil
+}
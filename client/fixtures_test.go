@@ -0,0 +1,62 @@
+package client_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goadesign/goa/client"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fixture", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "fixtures")
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("round trips requests through fixture files", func() {
+		req, err := http.NewRequest("POST", "http://example.com/bottles?sort=name", strings.NewReader(`{"name":"x"}`))
+		Ω(err).ShouldNot(HaveOccurred())
+		req.Header.Set("Content-Type", "application/json")
+		f, err := client.NewFixture(req)
+		Ω(err).ShouldNot(HaveOccurred())
+		file := filepath.Join(dir, "create.json")
+		Ω(f.Write(file)).Should(Succeed())
+
+		loaded, err := client.ReadFixture(file)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(loaded).Should(Equal(&client.Fixture{
+			Method: "POST",
+			Path:   "/bottles?sort=name",
+			Header: http.Header{"Content-Type": {"application/json"}},
+			Body:   []byte(`{"name":"x"}`),
+		}))
+		replayed, err := loaded.Request("http://localhost:8080")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(replayed.URL.String()).Should(Equal("http://localhost:8080/bottles?sort=name"))
+		body, err := ioutil.ReadAll(replayed.Body)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(string(body)).Should(Equal(`{"name":"x"}`))
+	})
+
+	It("leaves the request body readable", func() {
+		req, err := http.NewRequest("PUT", "http://example.com/bottles/1", strings.NewReader("body"))
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = client.NewFixture(req)
+		Ω(err).ShouldNot(HaveOccurred())
+		body, err := ioutil.ReadAll(req.Body)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(string(body)).Should(Equal("body"))
+	})
+})
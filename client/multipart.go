@@ -0,0 +1,51 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// Part is a part of a multipart response body.
+type Part struct {
+	// Header contains the headers of the part, e.g. its Content-Type.
+	Header textproto.MIMEHeader
+	// Body reads the content of the part.
+	Body io.Reader
+}
+
+// ReadParts reads the parts of the multipart body of resp. The parts are read in memory so that
+// they can be consumed in any order. ReadParts returns an error if the response Content-Type
+// header is not a multipart media type with a boundary. It does not close the response body.
+func ReadParts(resp *http.Response) ([]*Part, error) {
+	ct := resp.Header.Get("Content-Type")
+	mediaType, params, err := mime.ParseMediaType(ct)
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, fmt.Errorf("invalid content type %q, must be a multipart media type", ct)
+	}
+	if params["boundary"] == "" {
+		return nil, fmt.Errorf("missing boundary in content type %q", ct)
+	}
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+	var parts []*Part
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			return parts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		b, err := ioutil.ReadAll(p)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, &Part{Header: p.Header, Body: bytes.NewReader(b)})
+	}
+}
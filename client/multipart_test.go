@@ -0,0 +1,52 @@
+package client_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/goadesign/goa/client"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ReadParts", func() {
+	const body = "--b\r\n" +
+		"Content-Type: application/json\r\n\r\n" +
+		`{"title":"report"}` + "\r\n" +
+		"--b\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"Content-Disposition: attachment; filename=\"notes.txt\"\r\n\r\n" +
+		"notes\r\n" +
+		"--b--\r\n"
+
+	var resp *http.Response
+
+	BeforeEach(func() {
+		resp = &http.Response{
+			Header: http.Header{"Content-Type": {"multipart/mixed; boundary=b"}},
+			Body:   ioutil.NopCloser(strings.NewReader(body)),
+		}
+	})
+
+	It("returns the parts with their headers", func() {
+		parts, err := client.ReadParts(resp)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(parts).Should(HaveLen(2))
+		Ω(parts[0].Header.Get("Content-Type")).Should(Equal("application/json"))
+		Ω(parts[1].Header.Get("Content-Type")).Should(Equal("text/plain"))
+		Ω(parts[1].Header.Get("Content-Disposition")).Should(Equal(`attachment; filename="notes.txt"`))
+		b, err := ioutil.ReadAll(parts[1].Body)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(string(b)).Should(Equal("notes"))
+		b, err = ioutil.ReadAll(parts[0].Body)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(string(b)).Should(Equal(`{"title":"report"}`))
+	})
+
+	It("fails on responses that are not multipart", func() {
+		resp.Header.Set("Content-Type", "application/json")
+		_, err := client.ReadParts(resp)
+		Ω(err).Should(MatchError(`invalid content type "application/json", must be a multipart media type`))
+	})
+})
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// Int64 returns the integer held in v, a value decoded from a JSON number into an interface{}
+// field. v is a json.Number if the response was decoded by a client created with the
+// WithNumberDecoding option, so that integers larger than 2^53 keep their precision, a float64
+// otherwise. Int64 returns an error if v is not a number or is not an integer.
+func Int64(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case json.Number:
+		return n.Int64()
+	case float64:
+		if n != math.Trunc(n) || n < math.MinInt64 || n >= math.MaxInt64 {
+			return 0, fmt.Errorf("%v is not an integer", n)
+		}
+		return int64(n), nil
+	case int:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	}
+	return 0, fmt.Errorf("%v is not a number", v)
+}
+
+// Float64 returns the number held in v, a value decoded from a JSON number into an interface{}
+// field, see Int64. Float64 returns an error if v is not a number.
+func Float64(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case json.Number:
+		return n.Float64()
+	case float64:
+		return n, nil
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	}
+	return 0, fmt.Errorf("%v is not a number", v)
+}
@@ -0,0 +1,68 @@
+package client_test
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/goadesign/goa/client"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Int64", func() {
+	const body = `{"id":9007199254740993}`
+
+	var v interface{}
+	var useNumber bool
+
+	var n int64
+	var err error
+
+	JustBeforeEach(func() {
+		var decoded struct {
+			ID interface{} `json:"id"`
+		}
+		d := json.NewDecoder(strings.NewReader(body))
+		if useNumber {
+			d.UseNumber()
+		}
+		Ω(d.Decode(&decoded)).Should(Succeed())
+		v = decoded.ID
+		n, err = client.Int64(v)
+	})
+
+	Context("with a value decoded into a json.Number", func() {
+		BeforeEach(func() {
+			useNumber = true
+		})
+
+		It("preserves the integer precision", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(n).Should(Equal(int64(9007199254740993)))
+		})
+	})
+
+	Context("with a value decoded into a float64", func() {
+		BeforeEach(func() {
+			useNumber = false
+		})
+
+		It("returns the rounded integer", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(n).Should(Equal(int64(9007199254740992)))
+		})
+	})
+})
+
+var _ = Describe("Float64", func() {
+	It("returns the value of a json.Number", func() {
+		f, err := client.Float64(json.Number("1.5"))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(f).Should(Equal(1.5))
+	})
+
+	It("fails on values that are not numbers", func() {
+		_, err := client.Float64("foo")
+		Ω(err).Should(HaveOccurred())
+	})
+})
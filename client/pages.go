@@ -0,0 +1,47 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// PageHeaders lists the names of the response headers describing pages of results that are
+// decoded into PageMeta.
+var PageHeaders = []string{"X-Total", "X-Total-Count", "X-Total-Pages", "X-Page", "X-Per-Page", "X-Next-Page", "X-Prev-Page"}
+
+// PageMeta holds the pagination metadata read from the headers of a response to a list request.
+// Fields of headers missing from the response are nil.
+type PageMeta struct {
+	// Total is the total number of results, read from the X-Total or X-Total-Count header.
+	Total *int
+	// TotalPages is the total number of pages, read from the X-Total-Pages header.
+	TotalPages *int
+	// Page is the index of the page, read from the X-Page header.
+	Page *int
+	// PerPage is the number of results per page, read from the X-Per-Page header.
+	PerPage *int
+	// NextPage is the index of the next page, read from the X-Next-Page header.
+	NextPage *int
+	// PrevPage is the index of the previous page, read from the X-Prev-Page header.
+	PrevPage *int
+}
+
+// ParsePageMeta reads the pagination metadata from the headers of resp, see PageHeaders. Empty
+// headers are ignored, headers whose value is not an integer cause an error.
+func ParsePageMeta(resp *http.Response) (PageMeta, error) {
+	var meta PageMeta
+	targets := []**int{&meta.Total, &meta.Total, &meta.TotalPages, &meta.Page, &meta.PerPage, &meta.NextPage, &meta.PrevPage}
+	for i, name := range PageHeaders {
+		raw := resp.Header.Get(name)
+		if raw == "" || *targets[i] != nil {
+			continue
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return PageMeta{}, fmt.Errorf("invalid %s header value %q, must be an integer", name, raw)
+		}
+		*targets[i] = &v
+	}
+	return meta, nil
+}
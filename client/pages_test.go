@@ -0,0 +1,41 @@
+package client_test
+
+import (
+	"net/http"
+
+	"github.com/goadesign/goa/client"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParsePageMeta", func() {
+	var resp *http.Response
+
+	BeforeEach(func() {
+		resp = &http.Response{Header: make(http.Header)}
+	})
+
+	It("leaves the metadata of missing headers nil", func() {
+		meta, err := client.ParsePageMeta(resp)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(meta).Should(Equal(client.PageMeta{}))
+	})
+
+	It("decodes the pagination headers", func() {
+		resp.Header.Set("X-Total-Count", "42")
+		resp.Header.Set("X-Total-Pages", "5")
+		resp.Header.Set("X-Page", "2")
+		meta, err := client.ParsePageMeta(resp)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(*meta.Total).Should(Equal(42))
+		Ω(*meta.TotalPages).Should(Equal(5))
+		Ω(*meta.Page).Should(Equal(2))
+		Ω(meta.PerPage).Should(BeNil())
+	})
+
+	It("fails on invalid header values", func() {
+		resp.Header.Set("X-Total", "many")
+		_, err := client.ParsePageMeta(resp)
+		Ω(err).Should(MatchError(`invalid X-Total header value "many", must be an integer`))
+	})
+})
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+)
+
+// ProblemMediaType is the media type of the problem details defined by RFC 7807.
+const ProblemMediaType = "application/problem+json"
+
+// Problem holds the problem details returned by APIs implementing RFC 7807. Problem implements
+// error so that clients may return it as is.
+type Problem struct {
+	// Type is a URI reference that identifies the problem type.
+	Type string
+	// Title is a short summary of the problem type.
+	Title string
+	// Status is the HTTP status code generated by the server for this occurrence of the problem.
+	Status int
+	// Detail is an explanation specific to this occurrence of the problem.
+	Detail string
+	// Instance is a URI reference that identifies the specific occurrence of the problem.
+	Instance string
+	// Extensions holds the members of the problem details other than the standard ones.
+	Extensions map[string]interface{}
+}
+
+// problemFields lists the standard members of problem details.
+var problemFields = []string{"type", "title", "status", "detail", "instance"}
+
+// IsProblem returns true if the media type of the response body is ProblemMediaType.
+func IsProblem(resp *http.Response) bool {
+	mt, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return err == nil && mt == ProblemMediaType
+}
+
+// Error returns the problem title and detail.
+func (p *Problem) Error() string {
+	msg := p.Title
+	if msg == "" {
+		msg = p.Type
+	}
+	if p.Detail != "" {
+		msg = fmt.Sprintf("%s: %s", msg, p.Detail)
+	}
+	return msg
+}
+
+// UnmarshalJSON decodes the standard members of the problem details into the Problem fields and
+// the others into Extensions.
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	targets := []interface{}{&p.Type, &p.Title, &p.Status, &p.Detail, &p.Instance}
+	for i, n := range problemFields {
+		if raw, ok := members[n]; ok {
+			if err := json.Unmarshal(raw, targets[i]); err != nil {
+				return fmt.Errorf("invalid problem %s member: %s", n, err)
+			}
+			delete(members, n)
+		}
+	}
+	p.Extensions = nil
+	for n, raw := range members {
+		var v interface{}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		if p.Extensions == nil {
+			p.Extensions = make(map[string]interface{}, len(members))
+		}
+		p.Extensions[n] = v
+	}
+	return nil
+}
+
+// MarshalJSON encodes the problem details, the extension members are encoded next to the
+// standard ones. Empty standard members are omitted.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]interface{}, len(p.Extensions)+len(problemFields))
+	for n, v := range p.Extensions {
+		members[n] = v
+	}
+	values := []interface{}{p.Type, p.Title, p.Status, p.Detail, p.Instance}
+	for i, n := range problemFields {
+		if values[i] != "" && values[i] != 0 {
+			members[n] = values[i]
+		}
+	}
+	return json.Marshal(members)
+}
@@ -0,0 +1,46 @@
+package client_test
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/goadesign/goa/client"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Problem", func() {
+	const body = `{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.",` +
+		`"status":403,"detail":"Your current balance is 30, but that costs 50.","instance":"/account/12345/msgs/abc",` +
+		`"balance":30}`
+
+	var problem *client.Problem
+
+	BeforeEach(func() {
+		problem = &client.Problem{}
+		Ω(json.Unmarshal([]byte(body), problem)).Should(Succeed())
+	})
+
+	It("decodes the standard members and the extensions", func() {
+		Ω(problem.Type).Should(Equal("https://example.com/probs/out-of-credit"))
+		Ω(problem.Title).Should(Equal("You do not have enough credit."))
+		Ω(problem.Status).Should(Equal(403))
+		Ω(problem.Detail).Should(Equal("Your current balance is 30, but that costs 50."))
+		Ω(problem.Instance).Should(Equal("/account/12345/msgs/abc"))
+		Ω(problem.Extensions).Should(Equal(map[string]interface{}{"balance": float64(30)}))
+		Ω(problem.Error()).Should(Equal("You do not have enough credit.: Your current balance is 30, but that costs 50."))
+	})
+
+	It("encodes the extensions next to the standard members", func() {
+		b, err := json.Marshal(problem)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(b).Should(MatchJSON(body))
+	})
+
+	It("detects problem responses", func() {
+		resp := &http.Response{Header: http.Header{"Content-Type": {"application/problem+json; charset=utf-8"}}}
+		Ω(client.IsProblem(resp)).Should(BeTrue())
+		resp.Header.Set("Content-Type", "application/json")
+		Ω(client.IsProblem(resp)).Should(BeFalse())
+	})
+})
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+type (
+	// Route describes one of the routes of an action: the HTTP verb and path pattern used to
+	// build requests sent to the action endpoint.
+	Route struct {
+		// Verb is the route HTTP verb, e.g. "GET".
+		Verb string
+		// Path is the route full path pattern, e.g. "/accounts/:accountID/bottles/:id".
+		Path string
+	}
+
+	// routeKey is the private type used to store route information in contexts.
+	routeKey int
+)
+
+const (
+	routeIndexKey routeKey = iota + 1
+	requestRouteKey
+)
+
+// WithRouteIndex returns a context that makes the client methods of actions with multiple routes
+// build requests using the route at the given index. Index 0 is the first route which path is computed by
+// the <Action><Resource>Path function, index 1 the route which path is computed by the
+// <Action><Resource>Path2 function etc.
+func WithRouteIndex(ctx context.Context, index int) context.Context {
+	return context.WithValue(ctx, routeIndexKey, index)
+}
+
+// SelectRoute returns the route used to build a request sent to path among the given action
+// routes. The route is the one at the index set in ctx with WithRouteIndex if any, the first
+// route whose path pattern matches path otherwise. SelectRoute returns the first route if none
+// match.
+func SelectRoute(ctx context.Context, routes []Route, path string) Route {
+	if len(routes) == 0 {
+		return Route{}
+	}
+	if index, ok := ctx.Value(routeIndexKey).(int); ok && index >= 0 && index < len(routes) {
+		return routes[index]
+	}
+	for _, r := range routes {
+		if matchRoute(r.Path, path) {
+			return r
+		}
+	}
+	return routes[0]
+}
+
+// WithRequestRoute returns a shallow copy of req recording the route used to build it.
+func WithRequestRoute(req *http.Request, route Route) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), requestRouteKey, route))
+}
+
+// RequestRoute returns the route recorded in req with WithRequestRoute if any.
+func RequestRoute(req *http.Request) (Route, bool) {
+	route, ok := req.Context().Value(requestRouteKey).(Route)
+	return route, ok
+}
+
+// MatchPath matches path against re and returns the unescaped values of the regular expression
+// groups. MatchPath returns false if path does not match or if a value is not properly escaped.
+func MatchPath(re *regexp.Regexp, path string) ([]string, bool) {
+	m := re.FindStringSubmatch(path)
+	if m == nil {
+		return nil, false
+	}
+	values := make([]string, len(m)-1)
+	for i, v := range m[1:] {
+		value, err := url.PathUnescape(v)
+		if err != nil {
+			return nil, false
+		}
+		values[i] = value
+	}
+	return values, true
+}
+
+// matchRoute returns true if path matches the route path pattern. Wildcards (":name") match any
+// non empty segment and catch-all wildcards ("*name") match any remainder.
+func matchRoute(pattern, path string) bool {
+	patterns := strings.Split(strings.Trim(pattern, "/"), "/")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, p := range patterns {
+		if strings.HasPrefix(p, "*") {
+			return true
+		}
+		if i >= len(segments) {
+			return false
+		}
+		if strings.HasPrefix(p, ":") {
+			if segments[i] == "" {
+				return false
+			}
+			continue
+		}
+		if p != segments[i] {
+			return false
+		}
+	}
+	return len(patterns) == len(segments)
+}
@@ -1,10 +1,20 @@
 package client
 
 import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"golang.org/x/net/context"
@@ -39,6 +49,13 @@ type (
 		Key string
 		// Format is the format used to render the key, defaults to "Bearer %s"
 		Format string
+		// Query is the name of the query string parameter that contains the API key. The key
+		// is set as is in the query string instead of the header if not empty.
+		Query string
+		// Expiry is the validity period of the requests whose key is set in the query string.
+		// If not zero an "expires" query string parameter holding the Unix time after which
+		// the request URL expires is added alongside the key.
+		Expiry time.Duration
 	}
 
 	// JWTSigner implements JSON Web Token auth.
@@ -80,8 +97,53 @@ type (
 		// expiresAt specifies when to create a new access token.
 		expiresAt time.Time
 	}
+
+	// AWSV4Signer signs requests with the AWS Signature Version 4 algorithm, e.g. requests made to
+	// APIs fronted by AWS API Gateway with IAM authorization. The signer reads the request body
+	// to compute its hash and replaces it with an in-memory copy.
+	AWSV4Signer struct {
+		// AccessKeyID is the AWS access key ID.
+		AccessKeyID string
+		// SecretAccessKey is the AWS secret access key.
+		SecretAccessKey string
+		// SessionToken is the optional session token of temporary credentials, it is sent in
+		// the "X-Amz-Security-Token" header.
+		SessionToken string
+		// Region is the AWS region of the API, e.g. "us-east-1".
+		Region string
+		// Service is the name of the signed AWS service, defaults to "execute-api".
+		Service string
+		// Now returns the signing time, defaults to time.Now.
+		Now func() time.Time
+	}
+
+	// tokenKey is the private type used to store the token overrides in contexts.
+	tokenKey struct{}
 )
 
+// WithToken returns a context that makes the API key, JWT and OAuth2 signers sign the requests
+// made with it using token instead of their configured key or token. This makes it possible to
+// use a single client on behalf of many users or tenants.
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey{}, token)
+}
+
+// ContextToken returns the token set in ctx with WithToken if any.
+func ContextToken(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(tokenKey{}).(string)
+	return token, ok
+}
+
+// NewNonce returns a random hex encoded 128-bit value suitable as the nonce of requests whose
+// signature must not be replayed.
+func NewNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
 // Sign adds the basic auth header to the request.
 func (s *BasicSigner) Sign(ctx context.Context, req *http.Request) error {
 	if s.Username != "" && s.Password != "" {
@@ -90,14 +152,33 @@ func (s *BasicSigner) Sign(ctx context.Context, req *http.Request) error {
 	return nil
 }
 
+// Configured returns true if the signer holds the username and password used to sign requests.
+func (s *BasicSigner) Configured() bool {
+	return s.Username != "" && s.Password != ""
+}
+
 // RegisterFlags adds the "--user" and "--pass" flags to the client tool.
 func (s *BasicSigner) RegisterFlags(app *cobra.Command) {
 	app.Flags().StringVar(&s.Username, "user", "", "Basic Auth username")
 	app.Flags().StringVar(&s.Password, "pass", "", "Basic Auth password")
 }
 
-// Sign adds the API key header to the request.
+// Sign adds the API key header to the request or the API key query string parameter if Query is
+// set. The key set in ctx with WithToken is used instead of Key if any.
 func (s *APIKeySigner) Sign(ctx context.Context, req *http.Request) error {
+	key := s.Key
+	if token, ok := ContextToken(ctx); ok {
+		key = token
+	}
+	if s.Query != "" {
+		values := req.URL.Query()
+		values.Set(s.Query, key)
+		if s.Expiry > 0 {
+			values.Set("expires", strconv.FormatInt(time.Now().Add(s.Expiry).Unix(), 10))
+		}
+		req.URL.RawQuery = values.Encode()
+		return nil
+	}
 	header := s.Header
 	if header == "" {
 		header = "Authorization"
@@ -106,19 +187,32 @@ func (s *APIKeySigner) Sign(ctx context.Context, req *http.Request) error {
 	if format == "" {
 		format = "Bearer %s"
 	}
-	req.Header.Set(header, fmt.Sprintf(format, s.Key))
+	req.Header.Set(header, fmt.Sprintf(format, key))
 	return nil
 }
 
-// RegisterFlags adds the "--key" and "--key-header" flags to the client tool.
+// Configured returns true if the signer holds the API key used to sign requests.
+func (s *APIKeySigner) Configured() bool {
+	return s.Key != ""
+}
+
+// RegisterFlags adds the "--key", "--header", "--format", "--key-query" and "--key-expiry" flags
+// to the client tool.
 func (s *APIKeySigner) RegisterFlags(app *cobra.Command) {
 	app.Flags().StringVar(&s.Key, "key", "", "API key")
 	app.Flags().StringVar(&s.Header, "header", "Authorization", "API key header name")
 	app.Flags().StringVar(&s.Format, "format", "Bearer %s", "Format used to render header value from key")
+	app.Flags().StringVar(&s.Query, "key-query", s.Query, "API key query string parameter name, overrides --header")
+	app.Flags().DurationVar(&s.Expiry, "key-expiry", s.Expiry, "Validity period of the requests whose API key is set in the query string")
 }
 
-// Sign adds the JWT auth header.
+// Sign adds the JWT auth header. The token set in ctx with WithToken is used instead of Token if
+// any.
 func (s *JWTSigner) Sign(ctx context.Context, req *http.Request) error {
+	jwt := s.Token
+	if token, ok := ContextToken(ctx); ok {
+		jwt = token
+	}
 	header := s.Header
 	if header == "" {
 		header = "Authorization"
@@ -127,10 +221,15 @@ func (s *JWTSigner) Sign(ctx context.Context, req *http.Request) error {
 	if format == "" {
 		format = "Bearer %s"
 	}
-	req.Header.Set(header, fmt.Sprintf(format, s.Token))
+	req.Header.Set(header, fmt.Sprintf(format, jwt))
 	return nil
 }
 
+// Configured returns true if the signer holds the JWT used to sign requests.
+func (s *JWTSigner) Configured() bool {
+	return s.Token != ""
+}
+
 // RegisterFlags adds the "--jwt" flag to the client tool.
 func (s *JWTSigner) RegisterFlags(app *cobra.Command) {
 	app.Flags().StringVar(&s.Token, "jwt", "", "JWT value")
@@ -138,8 +237,13 @@ func (s *JWTSigner) RegisterFlags(app *cobra.Command) {
 	app.Flags().StringVar(&s.Format, "format", "Bearer %s", "Format used to render header value from JWT")
 }
 
-// Sign refreshes the access token if needed and adds the OAuth header.
+// Sign refreshes the access token if needed and adds the OAuth header. The access token set in
+// ctx with WithToken is used as is if any.
 func (s *OAuth2Signer) Sign(ctx context.Context, req *http.Request) error {
+	if token, ok := ContextToken(ctx); ok {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		return nil
+	}
 	if s.expiresAt.Before(time.Now()) {
 		if err := s.Refresh(ctx); err != nil {
 			return fmt.Errorf("failed to refresh OAuth token: %s", err)
@@ -149,12 +253,179 @@ func (s *OAuth2Signer) Sign(ctx context.Context, req *http.Request) error {
 	return nil
 }
 
+// Configured returns true if the signer holds the refresh URL format and the refresh token used
+// to create access tokens.
+func (s *OAuth2Signer) Configured() bool {
+	return s.RefreshURLFormat != "" && s.RefreshToken != ""
+}
+
 // RegisterFlags adds the "--refreshURL" and "--refreshToken" flags to the client tool.
 func (s *OAuth2Signer) RegisterFlags(app *cobra.Command) {
 	app.Flags().StringVar(&s.RefreshURLFormat, "refreshURL", "", "OAuth2 refresh URL format, e.g. https://somewhere.com/token?grant_type=authorization_code&code=%s&client_id=xxx")
 	app.Flags().StringVar(&s.RefreshToken, "refreshToken", "", "OAuth2 refresh token or authorization code")
 }
 
+// Sign adds the "X-Amz-Date", "X-Amz-Security-Token" and "Authorization" headers computed
+// from the request method, URL, host, date and body hash as defined by the AWS Signature
+// Version 4 algorithm.
+func (s *AWSV4Signer) Sign(ctx context.Context, req *http.Request) error {
+	payload, err := awsPayloadHash(req)
+	if err != nil {
+		return fmt.Errorf("failed to hash request body: %s", err)
+	}
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	date := now().UTC().Format("20060102T150405Z")
+	service := s.Service
+	if service == "" {
+		service = "execute-api"
+	}
+	req.Header.Set("X-Amz-Date", date)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for n, vs := range req.Header {
+		n = strings.ToLower(n)
+		if n == "x-amz-date" || n == "x-amz-security-token" || n == "content-type" {
+			headers[n] = strings.TrimSpace(strings.Join(vs, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for n := range headers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	var canonicalHeaders bytes.Buffer
+	for _, n := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", n, headers[n])
+	}
+	signedHeaders := strings.Join(names, ";")
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonical := strings.Join([]string{
+		req.Method,
+		awsEscape(path, false),
+		awsCanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payload,
+	}, "\n")
+	scope := strings.Join([]string{date[:8], s.Region, service, "aws4_request"}, "/")
+	hash := sha256.Sum256([]byte(canonical))
+	toSign := strings.Join([]string{"AWS4-HMAC-SHA256", date, scope, hex.EncodeToString(hash[:])}, "\n")
+	key := []byte("AWS4" + s.SecretAccessKey)
+	for _, v := range []string{date[:8], s.Region, service, "aws4_request"} {
+		key = awsHMAC(key, v)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKeyID, scope, signedHeaders, hex.EncodeToString(awsHMAC(key, toSign))))
+	return nil
+}
+
+// Configured returns true if the signer holds the credentials and the region used to sign
+// requests.
+func (s *AWSV4Signer) Configured() bool {
+	return s.AccessKeyID != "" && s.SecretAccessKey != "" && s.Region != ""
+}
+
+// RegisterFlags adds the "--aws-access-key-id", "--aws-secret-access-key", "--aws-session-token",
+// "--aws-region" and "--aws-service" flags to the client tool.
+func (s *AWSV4Signer) RegisterFlags(app *cobra.Command) {
+	app.Flags().StringVar(&s.AccessKeyID, "aws-access-key-id", "", "AWS access key ID")
+	app.Flags().StringVar(&s.SecretAccessKey, "aws-secret-access-key", "", "AWS secret access key")
+	app.Flags().StringVar(&s.SessionToken, "aws-session-token", "", "AWS session token of temporary credentials")
+	app.Flags().StringVar(&s.Region, "aws-region", s.Region, "AWS region of the API")
+	app.Flags().StringVar(&s.Service, "aws-service", "execute-api", "Name of the signed AWS service")
+}
+
+// awsPayloadHash returns the hex encoded SHA256 hash of the request body. It reads the body from
+// GetBody if set, otherwise it reads it and replaces it with an in-memory copy.
+func awsPayloadHash(req *http.Request) (string, error) {
+	var body []byte
+	switch {
+	case req.Body == nil || req.Body == http.NoBody:
+	case req.GetBody != nil:
+		r, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+		defer r.Close()
+		if body, err = ioutil.ReadAll(r); err != nil {
+			return "", err
+		}
+	default:
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return "", err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	hash := sha256.Sum256(body)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// awsCanonicalQuery returns the query string sorted by escaped key then value with the keys and values
+// escaped as required by the AWS Signature Version 4 algorithm.
+func awsCanonicalQuery(values url.Values) string {
+	var pairs [][2]string
+	for k, vs := range values {
+		for _, v := range vs {
+			pairs = append(pairs, [2]string{awsEscape(k, true), awsEscape(v, true)})
+		}
+	}
+	// Sort the pairs rather than the joined strings so that keys sharing a prefix, e.g. "a" and
+	// "a-b", are ordered by key.
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	joined := make([]string, len(pairs))
+	for i, p := range pairs {
+		joined[i] = p[0] + "=" + p[1]
+	}
+	return strings.Join(joined, "&")
+}
+
+// awsEscape percent-encodes all the bytes of s but the unreserved characters defined by RFC 3986.
+// Slashes are encoded only if encodeSlash is true.
+func awsEscape(s string, encodeSlash bool) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			buf.WriteByte(c)
+		default:
+			fmt.Fprintf(&buf, "%%%02X", c)
+		}
+	}
+	return buf.String()
+}
+
+// awsHMAC returns the HMAC-SHA256 of data computed with key.
+func awsHMAC(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
 // ouath2RefreshResponse is the data structure representing the interesting subset of a OAuth2
 // refresh response.
 type oauth2RefreshResponse struct {
@@ -0,0 +1,194 @@
+package client_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goadesign/goa/client"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+var _ = Describe("APIKeySigner", func() {
+	var signer *client.APIKeySigner
+	var req *http.Request
+	var ctx context.Context
+
+	BeforeEach(func() {
+		signer = &client.APIKeySigner{Key: "secret"}
+		ctx = context.Background()
+		var err error
+		req, err = http.NewRequest("GET", "http://localhost/foo?a=b", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		Ω(signer.Sign(ctx, req)).Should(Succeed())
+	})
+
+	It("sets the key in the header", func() {
+		Ω(req.Header.Get("Authorization")).Should(Equal("Bearer secret"))
+		Ω(req.URL.RawQuery).Should(Equal("a=b"))
+	})
+
+	Context("with a token in the context", func() {
+		BeforeEach(func() {
+			ctx = client.WithToken(ctx, "tenant")
+		})
+
+		It("sets the token in the header", func() {
+			Ω(req.Header.Get("Authorization")).Should(Equal("Bearer tenant"))
+		})
+	})
+
+	Context("with a query string parameter", func() {
+		BeforeEach(func() {
+			signer.Query = "token"
+		})
+
+		It("sets the key in the query string", func() {
+			Ω(req.URL.Query().Get("token")).Should(Equal("secret"))
+			Ω(req.URL.Query().Get("a")).Should(Equal("b"))
+			Ω(req.URL.Query().Get("expires")).Should(BeEmpty())
+			Ω(req.Header.Get("Authorization")).Should(BeEmpty())
+		})
+
+		Context("and an expiry", func() {
+			BeforeEach(func() {
+				signer.Expiry = time.Hour
+			})
+
+			It("sets the expiry time in the query string", func() {
+				expires, err := strconv.ParseInt(req.URL.Query().Get("expires"), 10, 64)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(expires).Should(BeNumerically("~", time.Now().Add(time.Hour).Unix(), 5))
+			})
+		})
+	})
+})
+
+var _ = Describe("JWTSigner", func() {
+	var signer *client.JWTSigner
+	var req *http.Request
+	var ctx context.Context
+
+	BeforeEach(func() {
+		signer = &client.JWTSigner{Token: "default"}
+		ctx = context.Background()
+		var err error
+		req, err = http.NewRequest("GET", "http://localhost/foo", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		Ω(signer.Sign(ctx, req)).Should(Succeed())
+	})
+
+	It("sets the configured token in the header", func() {
+		Ω(req.Header.Get("Authorization")).Should(Equal("Bearer default"))
+	})
+
+	Context("with a token in the context", func() {
+		BeforeEach(func() {
+			ctx = client.WithToken(ctx, "tenant")
+		})
+
+		It("sets the token of the context in the header", func() {
+			Ω(req.Header.Get("Authorization")).Should(Equal("Bearer tenant"))
+		})
+	})
+})
+
+var _ = Describe("NewNonce", func() {
+	It("returns distinct values", func() {
+		first, err := client.NewNonce()
+		Ω(err).ShouldNot(HaveOccurred())
+		second, err := client.NewNonce()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(first).Should(MatchRegexp("^[0-9a-f]{32}$"))
+		Ω(second).ShouldNot(Equal(first))
+	})
+})
+
+var _ = Describe("AWSV4Signer", func() {
+	var signer *client.AWSV4Signer
+	var req *http.Request
+
+	BeforeEach(func() {
+		signer = &client.AWSV4Signer{
+			AccessKeyID:     "AKIDEXAMPLE",
+			SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+			Region:          "us-east-1",
+			Service:         "service",
+			Now: func() time.Time {
+				return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+			},
+		}
+		var err error
+		req, err = http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		Ω(signer.Sign(context.Background(), req)).Should(Succeed())
+	})
+
+	It("signs the request as defined by the AWS test suite", func() {
+		Ω(req.Header.Get("X-Amz-Date")).Should(Equal("20150830T123600Z"))
+		Ω(req.Header.Get("Authorization")).Should(Equal("AWS4-HMAC-SHA256 " +
+			"Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+			"SignedHeaders=host;x-amz-date, " +
+			"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"))
+	})
+
+	Context("with query string parameters", func() {
+		BeforeEach(func() {
+			var err error
+			req, err = http.NewRequest("GET", "https://example.amazonaws.com/?Param2=value2&Param1=value1", nil)
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+
+		It("signs the query string sorted by key as defined by the AWS test suite", func() {
+			Ω(req.Header.Get("Authorization")).Should(HaveSuffix(
+				"Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"))
+		})
+
+		Context("with keys prefixes of other keys", func() {
+			BeforeEach(func() {
+				var err error
+				req, err = http.NewRequest("GET", "https://example.amazonaws.com/?a-b=1&a=2&a=1", nil)
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+
+			It("sorts the parameters by key then value", func() {
+				Ω(req.Header.Get("Authorization")).Should(HaveSuffix(
+					"Signature=c55a4bf05f068bf43585bea6cee5249a0f9f645d92763c513e8e1d19cd97f237"))
+			})
+		})
+	})
+
+	Context("with a body and a session token", func() {
+		BeforeEach(func() {
+			signer.SessionToken = "token"
+			var err error
+			req, err = http.NewRequest("POST", "https://example.amazonaws.com/bottles?b=2&a=1", strings.NewReader(`{"name":"x"}`))
+			Ω(err).ShouldNot(HaveOccurred())
+			req.Header.Set("Content-Type", "application/json")
+			req.GetBody = nil
+		})
+
+		It("signs the body and keeps it readable", func() {
+			Ω(req.Header.Get("X-Amz-Security-Token")).Should(Equal("token"))
+			Ω(req.Header.Get("Authorization")).Should(MatchRegexp(`^AWS4-HMAC-SHA256 ` +
+				`Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, ` +
+				`SignedHeaders=content-type;host;x-amz-date;x-amz-security-token, Signature=[0-9a-f]{64}$`))
+			body, err := ioutil.ReadAll(req.Body)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(body)).Should(Equal(`{"name":"x"}`))
+		})
+	})
+})
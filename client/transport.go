@@ -0,0 +1,78 @@
+package client
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/http2"
+	"golang.org/x/sync/singleflight"
+	"golang.org/x/time/rate"
+)
+
+type (
+	// RateLimiter limits the rate of the requests sent by a client, see NewRateLimiter.
+	RateLimiter struct {
+		limiter *rate.Limiter
+	}
+
+	// CallGroup runs the functions given the same key concurrently only once, the callers all
+	// receive the results of the single run. The zero value is ready to use.
+	CallGroup struct {
+		group singleflight.Group
+	}
+
+	// DialFunc opens the connections used to send requests, see NewHTTP2Transport.
+	DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+)
+
+// NewRateLimiter returns a rate limiter allowing r requests per second with bursts of at most b
+// requests.
+func NewRateLimiter(r float64, b int) *RateLimiter {
+	return &RateLimiter{limiter: rate.NewLimiter(rate.Limit(r), b)}
+}
+
+// Wait blocks until the limiter allows a request. It returns an error if ctx is done first or
+// if the wait would exceed the context deadline.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	return l.limiter.Wait(ctx)
+}
+
+// Do runs fn and returns its results, the callers of Do given the same key while fn runs wait
+// for it and receive the same results. shared is true if the results were given to several
+// callers.
+func (g *CallGroup) Do(key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	return g.group.Do(key, fn)
+}
+
+// NewHTTP2Transport returns a transport sending requests with HTTP/2 using the given TLS
+// configuration. If h2c is true the requests are sent over cleartext connections with prior
+// knowledge instead of being negotiated with TLS ALPN. The connections are opened with dial if
+// not nil, the TLS handshake is made over the connections it returns unless h2c is true.
+func NewHTTP2Transport(cfg *tls.Config, h2c bool, dial DialFunc) http.RoundTripper {
+	t := &http2.Transport{TLSClientConfig: cfg}
+	if h2c {
+		t.AllowHTTP = true
+		if dial == nil {
+			var d net.Dialer
+			dial = d.DialContext
+		}
+	}
+	if dial == nil {
+		return t
+	}
+	t.DialTLS = func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+		conn, err := dial(context.Background(), network, addr)
+		if err != nil || h2c {
+			return conn, err
+		}
+		tlsConn := tls.Client(conn, cfg)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+	return t
+}
@@ -0,0 +1,79 @@
+package client_test
+
+import (
+	"crypto/tls"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/http2"
+
+	"github.com/goadesign/goa/client"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RateLimiter", func() {
+	It("delays the requests exceeding the rate", func() {
+		l := client.NewRateLimiter(10, 1)
+		start := time.Now()
+		Ω(l.Wait(context.Background())).Should(Succeed())
+		Ω(l.Wait(context.Background())).Should(Succeed())
+		Ω(time.Since(start)).Should(BeNumerically(">=", 80*time.Millisecond))
+	})
+
+	It("fails if the context is done first", func() {
+		l := client.NewRateLimiter(1.0/3600, 1)
+		Ω(l.Wait(context.Background())).Should(Succeed())
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		Ω(l.Wait(ctx)).ShouldNot(Succeed())
+	})
+})
+
+var _ = Describe("CallGroup", func() {
+	It("runs concurrent calls with the same key once", func() {
+		var g client.CallGroup
+		var runs int32
+		release := make(chan struct{})
+		var wg sync.WaitGroup
+		results := make([]interface{}, 5)
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i], _, _ = g.Do("key", func() (interface{}, error) {
+					atomic.AddInt32(&runs, 1)
+					<-release
+					return "result", nil
+				})
+			}(i)
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		Ω(atomic.LoadInt32(&runs)).Should(Equal(int32(1)))
+		for _, r := range results {
+			Ω(r).Should(Equal("result"))
+		}
+	})
+})
+
+var _ = Describe("NewHTTP2Transport", func() {
+	It("returns an HTTP/2 transport using the TLS configuration", func() {
+		cfg := &tls.Config{ServerName: "example.com"}
+		rt := client.NewHTTP2Transport(cfg, false, nil)
+		t, ok := rt.(*http2.Transport)
+		Ω(ok).Should(BeTrue())
+		Ω(t.TLSClientConfig).Should(BeIdenticalTo(cfg))
+		Ω(t.AllowHTTP).Should(BeFalse())
+		Ω(t.DialTLS).Should(BeNil())
+	})
+
+	It("allows cleartext connections with h2c", func() {
+		t := client.NewHTTP2Transport(nil, true, nil).(*http2.Transport)
+		Ω(t.AllowHTTP).Should(BeTrue())
+		Ω(t.DialTLS).ShouldNot(BeNil())
+	})
+})
@@ -26,6 +26,33 @@ import (
 //        Metadata("struct:tag:json", "myName,omitempty")
 //        Metadata("struct:tag:xml", "myName,attr")
 //
+// `struct:field:json`: overrides the key used in the json and xml tags of the generated Go struct
+// field, the attribute name by default. Unlike `struct:tag:json` the other tag options such as
+// omitempty are still computed by goagen.
+// Applicable to attributes only.
+//
+//        Metadata("struct:field:json", "my_name")
+//
+// `struct:field:xml`: overrides the name used in the xml tag of the generated Go struct field, the
+// name may be prefixed with a namespace followed by a space. Additional values are added as xml
+// tag options, e.g. "attr" to encode the field as an XML attribute.
+// Applicable to attributes only.
+//
+//        Metadata("struct:field:xml", "http://example.com/ns name")
+//        Metadata("struct:field:xml", "id", "attr")
+//
+// `client:timeout`: sets the time limit of the calls made by the generated client to the action,
+// overriding the client WithTimeout option. The value must be a valid Go duration.
+// Applicable to actions only.
+//
+//        Metadata("client:timeout", "30s")
+//
+// `client:json`: makes the generated client encode the value of a query string or header parameter
+// in JSON. Required for parameters whose type is an object or an array of non primitive elements.
+// Applicable to attributes only.
+//
+//        Metadata("client:json")
+//
 // `swagger:tag:xxx`: sets the Swagger object field tag xxx.
 // Applicable to resources and actions.
 //
@@ -39,6 +66,70 @@ import (
 //
 //        Metadata("swagger:summary", "Short summary of what action does")
 //
+// `operationId`: sets the name of the generated client methods for the action, overriding the
+// default that concatenates the action and resource names.
+// Applicable to actions.
+//
+//        Metadata("operationId", "listBottles")
+//
+// `client:content-type`: sets the MIME type used by the generated client to encode the action
+// payload, the default encoder is used otherwise. The "application/x-www-form-urlencoded" MIME
+// type makes the client send the payload attributes as form values.
+// Applicable to actions.
+//
+//        Metadata("client:content-type", "application/xml")
+//
+// `client:stream`: generates a client method that returns the response body without reading it.
+// Applicable to actions and responses.
+//
+//        Metadata("client:stream")
+//
+// `client:fields`: adds a query string parameter listing the fields of sparse fieldsets to the
+// generated client methods. The parameter is named "fields" unless the metadata value sets another
+// name, it is an array of strings serialized as a comma separated list.
+// Applicable to actions.
+//
+//        Metadata("client:fields")
+//        Metadata("client:fields", "select")
+//
+// `client:cursor`: generates a client method that follows the cursors of the pages of results of
+// the action and returns the elements of all the pages. The first value is the name of the string
+// attribute of the collection elements holding the cursor of the next page, the optional second
+// value the name of the query string parameter carrying the cursor, "cursor" by default. The
+// cursor is also read from the "next" link of the response Link header.
+// Applicable to actions only.
+//
+//        Metadata("client:cursor", "next_cursor")
+//
+// `client:query-order`: lists the names of the query string parameters in the order used to build
+// the query strings of the requests made by clients generated with the --ordered-query flag, e.g.
+// for signature schemes that require a given order. The parameters that are not listed follow in
+// the order of the client method arguments.
+// Applicable to actions only.
+//
+//        Metadata("client:query-order", "sort", "limit", "cursor")
+//
+// `client:aws-sigv4`: lists the names of the security schemes whose requests are signed by the
+// generated client with the AWS Signature Version 4 algorithm, e.g. for APIs fronted by AWS API
+// Gateway with IAM authorization. The client is configured with the With<Scheme>Credentials
+// option.
+// Applicable to API definitions only.
+//
+//        Metadata("client:aws-sigv4", "iam")
+//
+// `client:nonce`: lists the names of the security schemes whose requests get a unique "X-Nonce"
+// header and a "X-Timestamp" header holding the Unix time set by the generated client prior to
+// being signed so that servers may reject replayed requests. The generated client never retries
+// these requests. Applicable to API definitions only.
+//
+//        Metadata("client:nonce", "hmac")
+//
+// `healthcheck`: marks the action as the API health check, the generated client Ping method calls
+// it. The action must not define path wildcards, a payload or required parameters.
+// Applicable to actions.
+//
+//        Metadata("healthcheck")
+//
 // The special key names listed above may be used as follows:
 //
 //        var Account = Type("Account", func() {
@@ -9,6 +9,9 @@ import (
 // Resource, it applies to all Actions, unless overriden by individual actions.  When defined at the
 // API level, it will apply to all resources by default, following the same logic.
 //
+// Security may be called multiple times in the same definition, the requests must then meet all
+// the requirements, e.g. provide both an API key and an OAuth2 token.
+//
 // The scheme refers to previous definitions of either OAuth2Security, BasicAuthSecurity,
 // APIKeySecurity or JWTSecurity.  It can be a string, corresponding to the first parameter of
 // those definitions, or a SecuritySchemeDefinition, returned by those same functions.
@@ -42,19 +45,29 @@ func Security(scheme interface{}, dsl ...func()) {
 	parentDef := dslengine.CurrentDefinition()
 	switch parent := parentDef.(type) {
 	case *design.ActionDefinition:
-		parent.Security = def
+		parent.Security = addSecurity(parent.Security, def)
 	case *design.FileServerDefinition:
-		parent.Security = def
+		parent.Security = addSecurity(parent.Security, def)
 	case *design.ResourceDefinition:
-		parent.Security = def
+		parent.Security = addSecurity(parent.Security, def)
 	case *design.APIDefinition:
-		parent.Security = def
+		parent.Security = addSecurity(parent.Security, def)
 	default:
 		dslengine.IncompatibleDSL()
 		return
 	}
 }
 
+// addSecurity adds the requirement def to the requirements sec defined previously in the same
+// definition if any and returns the result.
+func addSecurity(sec, def *design.SecurityDefinition) *design.SecurityDefinition {
+	if sec == nil || sec.Scheme.Kind == design.NoSecurityKind {
+		return def
+	}
+	sec.Additional = append(sec.Additional, def)
+	return sec
+}
+
 // NoSecurity resets the authentication schemes for an Action or a Resource. It also prevents
 // fallback to Resource or API-defined Security.
 func NoSecurity() {
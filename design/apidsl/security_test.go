@@ -173,6 +173,38 @@ var _ = Describe("Security", func() {
 
 	})
 
+	Context("with multiple requirements", func() {
+		It("should require all of them in order", func() {
+			API("", func() {
+				APIKeySecurity("key", func() {
+					Header("X-API-Key")
+				})
+				JWTSecurity("jwt", func() {
+					TokenURL("/token")
+					Scope("read", "Read")
+				})
+			})
+			Resource("one", func() {
+				Action("first", func() {
+					Routing(GET("/first"))
+					Security("jwt", func() {
+						Scope("read")
+					})
+					Security("key")
+				})
+			})
+
+			dslengine.Run()
+
+			Ω(dslengine.Errors).ShouldNot(HaveOccurred())
+			sec := Design.Resources["one"].Actions["first"].Security
+			Ω(sec.Scheme.SchemeName).Should(Equal("jwt"))
+			Ω(sec.Scopes).Should(Equal([]string{"read"}))
+			Ω(sec.Additional).Should(HaveLen(1))
+			Ω(sec.Additional[0].Scheme.SchemeName).Should(Equal("key"))
+		})
+	})
+
 	Context("with resources and actions", func() {
 		It("should fallback properly to lower-level security", func() {
 			API("", func() {
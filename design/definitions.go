@@ -1492,19 +1492,20 @@ func (a *ActionDefinition) IterateHeaders(it HeaderIterator) error {
 	return iterateHeaders(mergedHeaders, isRequired, it)
 }
 
-// IterateResponses calls the given iterator passing in each response sorted in alphabetical order.
+// IterateResponses calls the given iterator passing in each response sorted by ascending status
+// code, responses with the same status code are sorted by media type identifier and then by name.
 // Iteration stops if an iterator returns an error and in this case IterateResponses returns that
 // error.
 func (a *ActionDefinition) IterateResponses(it ResponseIterator) error {
-	names := make([]string, len(a.Responses))
+	responses := make([]*ResponseDefinition, len(a.Responses))
 	i := 0
-	for n := range a.Responses {
-		names[i] = n
+	for _, r := range a.Responses {
+		responses[i] = r
 		i++
 	}
-	sort.Strings(names)
-	for _, n := range names {
-		if err := it(a.Responses[n]); err != nil {
+	sort.Sort(ByStatus(responses))
+	for _, r := range responses {
+		if err := it(r); err != nil {
 			return err
 		}
 	}
@@ -1541,6 +1542,22 @@ func (b ByFilePath) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
 func (b ByFilePath) Len() int           { return len(b) }
 func (b ByFilePath) Less(i, j int) bool { return b[i].FilePath < b[j].FilePath }
 
+// ByStatus makes ResponseDefinition sortable by status code for code generators. Responses with
+// the same status code are sorted by media type identifier and then by name.
+type ByStatus []*ResponseDefinition
+
+func (b ByStatus) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b ByStatus) Len() int      { return len(b) }
+func (b ByStatus) Less(i, j int) bool {
+	if b[i].Status != b[j].Status {
+		return b[i].Status < b[j].Status
+	}
+	if b[i].MediaType != b[j].MediaType {
+		return b[i].MediaType < b[j].MediaType
+	}
+	return b[i].Name < b[j].Name
+}
+
 // Context returns the generic definition name used in error messages.
 func (l *LinkDefinition) Context() string {
 	var prefix, suffix string
@@ -1,6 +1,8 @@
 package design_test
 
 import (
+	"errors"
+
 	"github.com/goadesign/goa/design"
 	"github.com/goadesign/goa/dslengine"
 	. "github.com/onsi/ginkgo"
@@ -68,3 +70,53 @@ var _ = Describe("IterateHeaders", func() {
 		Ω(names).Should(ConsistOf("a"))
 	})
 })
+
+var _ = Describe("IterateResponses", func() {
+	var (
+		action *design.ActionDefinition
+		it     design.ResponseIterator
+
+		iteratedResponses []string
+	)
+	BeforeEach(func() {
+		action = &design.ActionDefinition{}
+
+		// setup iterator that just accumulates response names into iteratedResponses
+		iteratedResponses = []string{}
+		it = func(r *design.ResponseDefinition) error {
+			iteratedResponses = append(iteratedResponses, r.Name)
+			return nil
+		}
+	})
+	It("works with empty", func() {
+		Ω(action.Responses).Should(BeEmpty())
+		Ω(action.IterateResponses(it)).Should(Succeed())
+		Ω(iteratedResponses).Should(BeEmpty())
+	})
+	Context("with non-empty responses map", func() {
+		BeforeEach(func() {
+			action.Responses = map[string]*design.ResponseDefinition{
+				"NotFound":   {Name: "NotFound", Status: 404},
+				"OK":         {Name: "OK", Status: 200, MediaType: "application/vnd.b+json"},
+				"Created":    {Name: "Created", Status: 201},
+				"OKA":        {Name: "OKA", Status: 200, MediaType: "application/vnd.a+json"},
+				"BadRequest": {Name: "BadRequest", Status: 400},
+			}
+		})
+		It("sorts responses by status code and media type identifier", func() {
+			Ω(action.IterateResponses(it)).Should(Succeed())
+			Ω(iteratedResponses).Should(Equal([]string{"OKA", "OK", "Created", "BadRequest", "NotFound"}))
+		})
+		It("propagates error", func() {
+			errIterator := func(r *design.ResponseDefinition) error {
+				if len(iteratedResponses) > 2 {
+					return errors.New("foo")
+				}
+				iteratedResponses = append(iteratedResponses, r.Name)
+				return nil
+			}
+			Ω(action.IterateResponses(errIterator)).Should(MatchError("foo"))
+			Ω(iteratedResponses).Should(Equal([]string{"OKA", "OK", "Created"}))
+		})
+	})
+})
@@ -53,6 +53,8 @@ func (d *dupper) DupAttribute(att *AttributeDefinition) *AttributeDefinition {
 		NonZeroAttributes: att.NonZeroAttributes,
 		View:              att.View,
 		DSLFunc:           att.DSLFunc,
+		Example:           att.Example,
+		isCustomExample:   att.isCustomExample,
 	}
 	return &dup
 }
@@ -59,6 +59,17 @@ var _ = Describe("Dup", func() {
 		})
 	})
 
+	Context("with an object type with attribute examples", func() {
+		BeforeEach(func() {
+			dt = Object{"name": &AttributeDefinition{Type: String, Example: "bob"}}
+		})
+
+		It("keeps the examples", func() {
+			Ω(dup).Should(Equal(dt))
+			Ω(dup.(Object)["name"].Example).Should(Equal("bob"))
+		})
+	})
+
 	Context("with a user type", func() {
 		const typeName = "foo"
 		var att = &AttributeDefinition{Type: Integer}
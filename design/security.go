@@ -29,6 +29,10 @@ type SecurityDefinition struct {
 
 	// Scopes are scopes required for this action
 	Scopes []string `json:"scopes,omitempty"`
+
+	// Additional lists the other security requirements that the requests must meet together
+	// with this one, e.g. an API key in addition to an OAuth2 token, in order of definition.
+	Additional []*SecurityDefinition `json:"additional,omitempty"`
 }
 
 // Context returns the generic definition name used in error messages.
@@ -72,6 +72,9 @@ type (
 		// User type and media type attributes are traversed once even for recursive
 		// definitions to avoid infinite recursion.
 		Walk(func(*AttributeDefinition))
+		// WalkErr traverses the data structure like Walk. The traversal stops if the given
+		// function returns an error and in this case WalkErr returns that error.
+		WalkErr(func(*AttributeDefinition) error) error
 	}
 
 	// Primitive is the type for null, boolean, integer, number, string, and time.
@@ -882,42 +885,72 @@ func (a *AttributeDefinition) Definition() *AttributeDefinition {
 // Walk traverses the data structure recursively and calls the given function once
 // on each attribute starting with the attribute returned by Definition.
 func (a *AttributeDefinition) Walk(walker func(*AttributeDefinition)) {
-	walk(a, walker, make(map[string]bool))
+	walk(a, walkAll(walker), make(map[string]bool))
+}
+
+// WalkErr traverses the data structure recursively and calls the given function once
+// on each attribute starting with the attribute returned by Definition. The traversal stops
+// if the function returns an error and in this case WalkErr returns that error.
+func (a *AttributeDefinition) WalkErr(walker func(*AttributeDefinition) error) error {
+	return walk(a, walker, make(map[string]bool))
 }
 
 // Walk traverses the data structure recursively and calls the given function once
 // on each attribute starting with the attribute returned by Definition.
 func (u *UserTypeDefinition) Walk(walker func(*AttributeDefinition)) {
-	walk(u.AttributeDefinition, walker, map[string]bool{u.TypeName: true})
+	walk(u.AttributeDefinition, walkAll(walker), map[string]bool{u.TypeName: true})
+}
+
+// WalkErr traverses the data structure recursively and calls the given function once
+// on each attribute starting with the attribute returned by Definition. The traversal stops
+// if the function returns an error and in this case WalkErr returns that error.
+func (u *UserTypeDefinition) WalkErr(walker func(*AttributeDefinition) error) error {
+	return walk(u.AttributeDefinition, walker, map[string]bool{u.TypeName: true})
+}
+
+// walkAll adapts the function given to Walk so that the traversal never stops.
+func walkAll(walker func(*AttributeDefinition)) func(*AttributeDefinition) error {
+	return func(at *AttributeDefinition) error {
+		walker(at)
+		return nil
+	}
 }
 
 // Recursive implementation of the Walk methods. Takes care of avoiding infinite recursions by
-// keeping track of types that have already been walked.
-func walk(at *AttributeDefinition, walker func(*AttributeDefinition), seen map[string]bool) {
-	walker(at)
-	walkUt := func(ut *UserTypeDefinition) {
+// keeping track of types that have already been walked. The traversal stops as soon as walker
+// returns an error.
+func walk(at *AttributeDefinition, walker func(*AttributeDefinition) error, seen map[string]bool) error {
+	if err := walker(at); err != nil {
+		return err
+	}
+	walkUt := func(ut *UserTypeDefinition) error {
 		if _, ok := seen[ut.TypeName]; ok {
-			return
+			return nil
 		}
 		seen[ut.TypeName] = true
-		walk(ut.AttributeDefinition, walker, seen)
+		return walk(ut.AttributeDefinition, walker, seen)
 	}
 	switch actual := at.Type.(type) {
 	case Primitive:
-		return
+		return nil
 	case *Array:
-		walk(actual.ElemType, walker, seen)
+		return walk(actual.ElemType, walker, seen)
 	case *Hash:
-		walk(actual.KeyType, walker, seen)
-		walk(actual.ElemType, walker, seen)
+		if err := walk(actual.KeyType, walker, seen); err != nil {
+			return err
+		}
+		return walk(actual.ElemType, walker, seen)
 	case Object:
 		for _, cat := range actual {
-			walk(cat, walker, seen)
+			if err := walk(cat, walker, seen); err != nil {
+				return err
+			}
 		}
+		return nil
 	case *UserTypeDefinition:
-		walkUt(actual)
+		return walkUt(actual)
 	case *MediaTypeDefinition:
-		walkUt(actual.UserTypeDefinition)
+		return walkUt(actual.UserTypeDefinition)
 	default:
 		panic("unknown attribute type") // bug
 	}
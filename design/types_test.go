@@ -364,3 +364,71 @@ var _ = Describe("Walk", func() {
 		})
 	})
 })
+
+var _ = Describe("WalkErr", func() {
+	var target DataStructure
+	var stopAt string
+	var count int
+	var walkErr error
+
+	errStop := errors.New("stop")
+
+	BeforeEach(func() {
+		stopAt = ""
+		count = 0
+	})
+
+	JustBeforeEach(func() {
+		walkErr = target.WalkErr(func(att *AttributeDefinition) error {
+			count++
+			if u, ok := att.Type.(*UserTypeDefinition); ok && u.TypeName == stopAt {
+				return errStop
+			}
+			return nil
+		})
+	})
+
+	Context("with an object attribute containing recursive user types", func() {
+		const typeName = "foo"
+		BeforeEach(func() {
+			co := Object{}
+			at := &AttributeDefinition{Type: co}
+			ut := &UserTypeDefinition{AttributeDefinition: at, TypeName: typeName}
+			co["recurse"] = &AttributeDefinition{Type: ut}
+			o := Object{"foo": &AttributeDefinition{Type: ut}}
+			target = &AttributeDefinition{Type: o}
+		})
+
+		It("walks it", func() {
+			Ω(walkErr).ShouldNot(HaveOccurred())
+			Ω(count).Should(Equal(4))
+		})
+
+		Context("with a function returning an error", func() {
+			BeforeEach(func() {
+				stopAt = typeName
+			})
+
+			It("stops at the first attribute returning an error", func() {
+				Ω(walkErr).Should(Equal(errStop))
+				Ω(count).Should(Equal(2))
+			})
+		})
+	})
+
+	Context("with a recursive user type", func() {
+		const typeName = "bar"
+		BeforeEach(func() {
+			co := Object{"name": &AttributeDefinition{Type: String}}
+			at := &AttributeDefinition{Type: co}
+			ut := &UserTypeDefinition{AttributeDefinition: at, TypeName: typeName}
+			co["children"] = &AttributeDefinition{Type: &Array{ElemType: &AttributeDefinition{Type: ut}}}
+			target = ut
+		})
+
+		It("walks each attribute once", func() {
+			Ω(walkErr).ShouldNot(HaveOccurred())
+			Ω(count).Should(Equal(4))
+		})
+	})
+})
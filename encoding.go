@@ -76,6 +76,14 @@ func NewJSONEncoder(w io.Writer) Encoder { return json.NewEncoder(w) }
 // NewJSONDecoder is an adapter for the encoding package JSON decoder.
 func NewJSONDecoder(r io.Reader) Decoder { return json.NewDecoder(r) }
 
+// NewStrictJSONDecoder is an adapter for the encoding package JSON decoder that fails to decode
+// objects containing fields that do not exist in the target struct.
+func NewStrictJSONDecoder(r io.Reader) Decoder {
+	d := json.NewDecoder(r)
+	d.DisallowUnknownFields()
+	return d
+}
+
 // NewXMLEncoder is an adapter for the encoding package XML encoder.
 func NewXMLEncoder(w io.Writer) Encoder { return xml.NewEncoder(w) }
 
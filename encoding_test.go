@@ -0,0 +1,37 @@
+package goa_test
+
+import (
+	"strings"
+
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewStrictJSONDecoder", func() {
+	const body = `{"name":"foo","unknown":true}`
+
+	type payload struct {
+		Name string `json:"name"`
+	}
+
+	It("fails to decode unknown fields", func() {
+		var p payload
+		err := goa.NewStrictJSONDecoder(strings.NewReader(body)).Decode(&p)
+		Ω(err).Should(HaveOccurred())
+	})
+
+	It("decodes known fields", func() {
+		var p payload
+		err := goa.NewStrictJSONDecoder(strings.NewReader(`{"name":"foo"}`)).Decode(&p)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(p.Name).Should(Equal("foo"))
+	})
+
+	It("differs from the default decoder", func() {
+		var p payload
+		err := goa.NewJSONDecoder(strings.NewReader(body)).Decode(&p)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(p.Name).Should(Equal("foo"))
+	})
+})
@@ -64,29 +64,42 @@ func init() {
 // private controls whether the field is a pointer or not. All fields in the struct are
 //   pointers for a private struct.
 func GoTypeDef(ds design.DataStructure, tabs int, jsonTags, private bool) string {
+	return goTypeDef(ds, tabs, jsonTags, private, false)
+}
+
+// GoTypeDefOmitEmpty behaves like GoTypeDef but sets the omitempty option in the tags of all the
+// fields that are not required, including the fields that have a default value. Such fields are
+// thus omitted from the encoded data when they hold the zero value which lets the receiving end
+// apply the default.
+func GoTypeDefOmitEmpty(ds design.DataStructure, tabs int, jsonTags, private bool) string {
+	return goTypeDef(ds, tabs, jsonTags, private, true)
+}
+
+// goTypeDef implements GoTypeDef and GoTypeDefOmitEmpty.
+func goTypeDef(ds design.DataStructure, tabs int, jsonTags, private, omitEmpty bool) string {
 	def := ds.Definition()
 	t := def.Type
 	switch actual := t.(type) {
 	case design.Primitive:
 		return GoTypeName(t, nil, tabs, private)
 	case *design.Array:
-		d := GoTypeDef(actual.ElemType, tabs, jsonTags, private)
+		d := goTypeDef(actual.ElemType, tabs, jsonTags, private, omitEmpty)
 		if actual.ElemType.Type.IsObject() {
 			d = "*" + d
 		}
 		return "[]" + d
 	case *design.Hash:
-		keyDef := GoTypeDef(actual.KeyType, tabs, jsonTags, private)
+		keyDef := goTypeDef(actual.KeyType, tabs, jsonTags, private, omitEmpty)
 		if actual.KeyType.Type.IsObject() {
 			keyDef = "*" + keyDef
 		}
-		elemDef := GoTypeDef(actual.ElemType, tabs, jsonTags, private)
+		elemDef := goTypeDef(actual.ElemType, tabs, jsonTags, private, omitEmpty)
 		if actual.ElemType.Type.IsObject() {
 			elemDef = "*" + elemDef
 		}
 		return fmt.Sprintf("map[%s]%s", keyDef, elemDef)
 	case design.Object:
-		return goTypeDefObject(actual, def, tabs, jsonTags, private)
+		return goTypeDefObject(actual, def, tabs, jsonTags, private, omitEmpty)
 	case *design.UserTypeDefinition:
 		return GoTypeName(actual, actual.AllRequired(), tabs, private)
 	case *design.MediaTypeDefinition:
@@ -97,7 +110,7 @@ func GoTypeDef(ds design.DataStructure, tabs int, jsonTags, private bool) string
 }
 
 // goTypeDefObject returns the Go code that defines a Go struct.
-func goTypeDefObject(actual design.Object, def *design.AttributeDefinition, tabs int, jsonTags, private bool) string {
+func goTypeDefObject(actual design.Object, def *design.AttributeDefinition, tabs int, jsonTags, private, omitEmpty bool) string {
 	var buffer bytes.Buffer
 	buffer.WriteString("struct {\n")
 	keys := make([]string, len(actual))
@@ -110,7 +123,7 @@ func goTypeDefObject(actual design.Object, def *design.AttributeDefinition, tabs
 	for _, name := range keys {
 		WriteTabs(&buffer, tabs+1)
 		field := actual[name]
-		typedef := GoTypeDef(field, tabs+1, jsonTags, private)
+		typedef := goTypeDef(field, tabs+1, jsonTags, private, omitEmpty)
 		if (field.Type.IsPrimitive() && private) || field.Type.IsObject() || def.IsPrimitivePointer(name) {
 			typedef = "*" + typedef
 		}
@@ -125,7 +138,7 @@ func goTypeDefObject(actual design.Object, def *design.AttributeDefinition, tabs
 		fname = Goify(fname, true)
 		var tags string
 		if jsonTags {
-			tags = attributeTags(def, field, name, private)
+			tags = attributeTags(def, field, name, private, omitEmpty)
 		}
 		desc := actual[name].Description
 		if desc != "" {
@@ -139,8 +152,9 @@ func goTypeDefObject(actual design.Object, def *design.AttributeDefinition, tabs
 	return buffer.String()
 }
 
-// attributeTags computes the struct field tags.
-func attributeTags(parent, att *design.AttributeDefinition, name string, private bool) string {
+// attributeTags computes the struct field tags. The json and xml tags use the value of the
+// "struct:field:json" metadata as key if any, the attribute name otherwise.
+func attributeTags(parent, att *design.AttributeDefinition, name string, private, omitEmpty bool) string {
 	var elems []string
 	keys := make([]string, len(att.Metadata))
 	i := 0
@@ -162,10 +176,20 @@ func attributeTags(parent, att *design.AttributeDefinition, name string, private
 	}
 	// Default algorithm
 	var omit string
-	if private || (!parent.IsRequired(name) && !parent.HasDefaultValue(name)) {
+	if private || (!parent.IsRequired(name) && (omitEmpty || !parent.HasDefaultValue(name))) {
 		omit = ",omitempty"
 	}
-	return fmt.Sprintf(" `json:\"%s%s\" xml:\"%s%s\"`", name, omit, name, omit)
+	if key, ok := att.Metadata["struct:field:json"]; ok && len(key) > 0 {
+		name = key[0]
+	}
+	xmlName, xmlOpts := name, omit
+	if key, ok := att.Metadata["struct:field:xml"]; ok && len(key) > 0 {
+		xmlName = key[0]
+		if len(key) > 1 {
+			xmlOpts = "," + strings.Join(key[1:], ",") + omit
+		}
+	}
+	return fmt.Sprintf(" `json:\"%s%s\" xml:\"%s%s\"`", name, omit, xmlName, xmlOpts)
 }
 
 // GoTypeRef returns the Go code that refers to the Go type which matches the given data type
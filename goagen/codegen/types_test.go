@@ -220,6 +220,45 @@ var _ = Describe("code generation", func() {
 						Ω(st).Should(Equal(expected))
 					})
 				})
+
+				Context("using struct field json metadata", func() {
+					BeforeEach(func() {
+						object["foo"].Metadata = dslengine.MetadataDefinition{
+							"struct:field:json": []string{"foo_id"},
+						}
+					})
+
+					It("produces the struct tags", func() {
+						expected := "struct {\n" +
+							"	Bar *string `json:\"bar,omitempty\" xml:\"bar,omitempty\"`\n" +
+							"	Baz *time.Time `json:\"baz,omitempty\" xml:\"baz,omitempty\"`\n" +
+							"	Foo *int `json:\"foo_id,omitempty\" xml:\"foo_id,omitempty\"`\n" +
+							"	Qux *uuid.UUID `json:\"qux,omitempty\" xml:\"qux,omitempty\"`\n" +
+							"}"
+						Ω(st).Should(Equal(expected))
+					})
+				})
+
+				Context("using struct field xml metadata", func() {
+					BeforeEach(func() {
+						object["foo"].Metadata = dslengine.MetadataDefinition{
+							"struct:field:xml": []string{"id", "attr"},
+						}
+						object["bar"].Metadata = dslengine.MetadataDefinition{
+							"struct:field:xml": []string{"http://example.com/ns bar"},
+						}
+					})
+
+					It("produces the struct tags", func() {
+						expected := "struct {\n" +
+							"	Bar *string `json:\"bar,omitempty\" xml:\"http://example.com/ns bar,omitempty\"`\n" +
+							"	Baz *time.Time `json:\"baz,omitempty\" xml:\"baz,omitempty\"`\n" +
+							"	Foo *int `json:\"foo,omitempty\" xml:\"id,attr,omitempty\"`\n" +
+							"	Qux *uuid.UUID `json:\"qux,omitempty\" xml:\"qux,omitempty\"`\n" +
+							"}"
+						Ω(st).Should(Equal(expected))
+					})
+				})
 			})
 
 			Context("of hash of primitive types", func() {
@@ -340,6 +379,29 @@ var _ = Describe("code generation", func() {
 				})
 			})
 
+			Context("that have default values", func() {
+				BeforeEach(func() {
+					object = Object{
+						"foo": &AttributeDefinition{Type: Integer, DefaultValue: 42},
+					}
+					required = nil
+				})
+
+				It("produces the struct go code", func() {
+					expected := "struct {\n" +
+						"	Foo int `json:\"foo\" xml:\"foo\"`\n" +
+						"}"
+					Ω(st).Should(Equal(expected))
+				})
+
+				It("omits the empty fields with GoTypeDefOmitEmpty", func() {
+					expected := "struct {\n" +
+						"	Foo int `json:\"foo,omitempty\" xml:\"foo,omitempty\"`\n" +
+						"}"
+					Ω(codegen.GoTypeDefOmitEmpty(att, 0, true, false)).Should(Equal(expected))
+				})
+			})
+
 		})
 
 		Context("given an array", func() {
@@ -27,6 +27,8 @@ type (
 		Path string
 		// gopath is the original GOPATH
 		gopath string
+		// modPath is the path of the Go module rooted at Path if the workspace is a module.
+		modPath string
 	}
 
 	// Package represents a temporary Go package
@@ -93,9 +95,19 @@ func NewWorkspace(prefix string) (*Workspace, error) {
 	return &Workspace{Path: dir, gopath: gopath}, nil
 }
 
-// WorkspaceFor returns the Go workspace for the given Go source file.
+// WorkspaceFor returns the Go workspace for the given Go source file. If the file is inside a Go
+// module, that is if its directory or one of its parents contains a go.mod file, the workspace is
+// rooted at the module directory, see PackagePath. The workspace is the GOPATH entry containing
+// the file otherwise.
 func WorkspaceFor(source string) (*Workspace, error) {
 	gopaths := os.Getenv("GOPATH")
+	if root, modPath, ok := findModule(filepath.Dir(source)); ok {
+		return &Workspace{
+			gopath:  gopaths,
+			Path:    root,
+			modPath: modPath,
+		}, nil
+	}
 	for _, gp := range filepath.SplitList(gopaths) {
 		gopath, err := filepath.Abs(gp)
 		if err != nil {
@@ -156,6 +168,13 @@ func PackageFor(source string) (*Package, error) {
 	if err != nil {
 		return nil, err
 	}
+	if w.modPath != "" {
+		path, err := PackagePath(filepath.Dir(source))
+		if err != nil {
+			return nil, err
+		}
+		return &Package{Workspace: w, Path: path}, nil
+	}
 	path, err := filepath.Rel(filepath.Join(w.Path, "src"), filepath.Dir(source))
 	if err != nil {
 		return nil, err
@@ -165,6 +184,10 @@ func PackageFor(source string) (*Package, error) {
 
 // Abs returns the absolute path to the package source directory
 func (p *Package) Abs() string {
+	if modPath := p.Workspace.modPath; modPath != "" {
+		rel := strings.TrimPrefix(strings.TrimPrefix(p.Path, modPath), "/")
+		return filepath.Join(p.Workspace.Path, filepath.FromSlash(rel))
+	}
 	return filepath.Join(p.Workspace.Path, "src", p.Path)
 }
 
@@ -291,12 +314,24 @@ func (f *SourceFile) ExecuteTemplate(name, source string, funcMap template.FuncM
 }
 
 // PackagePath returns the Go package path for the directory that lives under the given absolute
-// file path.
+// file path. If the directory is inside a Go module, that is if it or one of its parents contains
+// a go.mod file, the package path is computed from the module path. The package path is computed
+// from the GOPATH otherwise.
 func PackagePath(path string) (string, error) {
 	absPath, err := filepath.Abs(path)
 	if err != nil {
 		absPath = path
 	}
+	if root, modPath, ok := findModule(absPath); ok {
+		rel, err := filepath.Rel(root, absPath)
+		if err != nil {
+			return "", err
+		}
+		if rel == "." {
+			return modPath, nil
+		}
+		return modPath + "/" + filepath.ToSlash(rel), nil
+	}
 	gopaths := filepath.SplitList(os.Getenv("GOPATH"))
 	for _, gopath := range gopaths {
 		if gp, err := filepath.Abs(gopath); err == nil {
@@ -311,6 +346,40 @@ func PackagePath(path string) (string, error) {
 	return "", fmt.Errorf("%s does not contain a Go package", absPath)
 }
 
+// findModule walks up from the given absolute directory path looking for a go.mod file. It returns
+// the directory containing the file and the module path it declares if found.
+func findModule(dir string) (root, modPath string, ok bool) {
+	for {
+		if content, err := ioutil.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+			if modPath := parseModulePath(content); modPath != "" {
+				return dir, modPath, true
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", false
+		}
+		dir = parent
+	}
+}
+
+// parseModulePath returns the module path declared in the given go.mod file content, empty
+// string if there is none.
+func parseModulePath(content []byte) string {
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "module") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "module"))
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		return strings.Trim(line, "\"`")
+	}
+	return ""
+}
+
 // PackageSourcePath returns the absolute path to the given package source.
 func PackageSourcePath(pkg string) (string, error) {
 	buildCtx := build.Default
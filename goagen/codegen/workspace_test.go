@@ -0,0 +1,122 @@
+package codegen_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/goadesign/goa/goagen/codegen"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PackagePath", func() {
+	var path string
+	var pkgPath string
+	var err error
+
+	JustBeforeEach(func() {
+		pkgPath, err = codegen.PackagePath(path)
+	})
+
+	Context("with a directory inside a Go module", func() {
+		var root string
+
+		BeforeEach(func() {
+			root, err = ioutil.TempDir("", "codegen")
+			Ω(err).ShouldNot(HaveOccurred())
+			gomod := "// Test module\nmodule example.com/app // comment\n\nrequire github.com/goadesign/goa v1.0.0\n"
+			err = ioutil.WriteFile(filepath.Join(root, "go.mod"), []byte(gomod), 0644)
+			Ω(err).ShouldNot(HaveOccurred())
+			path = filepath.Join(root, "gen", "client")
+			Ω(os.MkdirAll(path, 0755)).ShouldNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(root)
+		})
+
+		It("computes the package path from the module path", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(pkgPath).Should(Equal("example.com/app/gen/client"))
+		})
+
+		Context("at the module root", func() {
+			BeforeEach(func() {
+				path = root
+			})
+
+			It("returns the module path", func() {
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(pkgPath).Should(Equal("example.com/app"))
+			})
+		})
+	})
+
+	Context("with a directory inside the GOPATH", func() {
+		var gopath, oldGopath string
+
+		BeforeEach(func() {
+			oldGopath = os.Getenv("GOPATH")
+			gopath, err = ioutil.TempDir("", "gopath")
+			Ω(err).ShouldNot(HaveOccurred())
+			path = filepath.Join(gopath, "src", "example.com", "app", "client")
+			Ω(os.MkdirAll(path, 0755)).ShouldNot(HaveOccurred())
+			os.Setenv("GOPATH", gopath)
+		})
+
+		AfterEach(func() {
+			os.Setenv("GOPATH", oldGopath)
+			os.RemoveAll(gopath)
+		})
+
+		It("computes the package path from the GOPATH", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(pkgPath).Should(Equal("example.com/app/client"))
+		})
+	})
+})
+
+var _ = Describe("SourceFileFor", func() {
+	var path string
+	var file *codegen.SourceFile
+	var err error
+
+	JustBeforeEach(func() {
+		file, err = codegen.SourceFileFor(path)
+	})
+
+	Context("with a file inside a Go module outside the GOPATH", func() {
+		var root, oldGopath string
+
+		BeforeEach(func() {
+			oldGopath = os.Getenv("GOPATH")
+			os.Setenv("GOPATH", filepath.Join(os.TempDir(), "nonexistent-gopath"))
+			root, err = ioutil.TempDir("", "codegen")
+			Ω(err).ShouldNot(HaveOccurred())
+			err = ioutil.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n"), 0644)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(os.MkdirAll(filepath.Join(root, "client"), 0755)).ShouldNot(HaveOccurred())
+			path = filepath.Join(root, "client", "client.go")
+		})
+
+		AfterEach(func() {
+			os.Setenv("GOPATH", oldGopath)
+			os.RemoveAll(root)
+		})
+
+		It("returns a source file in the module package", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(file.Package.Path).Should(Equal("example.com/app/client"))
+			Ω(file.Abs()).Should(Equal(path))
+		})
+
+		It("writes the file content", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(file.WriteHeader("", "client", nil)).ShouldNot(HaveOccurred())
+			content, err := ioutil.ReadFile(path)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(content)).Should(ContainSubstring("package client"))
+		})
+	})
+})
@@ -13,12 +13,23 @@ import (
 	"github.com/goadesign/goa/goagen/codegen"
 )
 
-func (g *Generator) makeToolDir(apiName string) (toolDir string, err error) {
+func (g *Generator) makeToolDir(apiName, version string) (toolDir string, err error) {
 	g.outDir = filepath.Join(g.outDir, "client")
+	g.pkgName = "client"
+	if g.versioned {
+		if pkg := versionPackage(version); pkg != "" {
+			g.outDir = filepath.Join(g.outDir, pkg)
+			g.pkgName = pkg
+		}
+	}
 	if err = os.RemoveAll(g.outDir); err != nil {
 		return
 	}
 	g.genfiles = append(g.genfiles, g.outDir)
+	if g.noCLI || g.typesOnly {
+		err = os.MkdirAll(g.outDir, 0755)
+		return
+	}
 	apiName = strings.Replace(apiName, " ", "-", -1)
 	toolDir = filepath.Join(g.outDir, fmt.Sprintf("%s-cli", codegen.SnakeCase(apiName)))
 	if err = os.MkdirAll(toolDir, 0755); err != nil {
@@ -28,6 +39,32 @@ func (g *Generator) makeToolDir(apiName string) (toolDir string, err error) {
 	return
 }
 
+// versionPackage returns the name of the package generated for the given API version with the
+// versioned flag: "v" followed by the major version, e.g. "v2" for "2.1" or "v2.1". It returns
+// the empty string if the version is empty.
+func versionPackage(version string) string {
+	major := strings.SplitN(strings.TrimPrefix(strings.ToLower(version), "v"), ".", 2)[0]
+	major = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, major)
+	if major == "" {
+		return ""
+	}
+	return "v" + major
+}
+
+// clientImport returns the import of the generated client package by the CLI tool. The package
+// is imported under the name client so that the CLI code is the same for versioned packages.
+func (g *Generator) clientImport(clientPkg string) *codegen.ImportSpec {
+	if g.pkgName == "client" {
+		return codegen.SimpleImport(clientPkg)
+	}
+	return codegen.NewImport("client", clientPkg)
+}
+
 func (g *Generator) generateMain(mainFile string, clientPkg string, funcs template.FuncMap, api *design.APIDefinition) error {
 	imports := []*codegen.ImportSpec{
 		codegen.SimpleImport("encoding/json"),
@@ -36,13 +73,20 @@ func (g *Generator) generateMain(mainFile string, clientPkg string, funcs templa
 		codegen.SimpleImport("net/http"),
 		codegen.SimpleImport("os"),
 		codegen.SimpleImport("time"),
-		codegen.SimpleImport(clientPkg),
+		g.clientImport(clientPkg),
+		codegen.NewImport("goaclient", "github.com/goadesign/goa/client"),
 		codegen.SimpleImport("github.com/spf13/cobra"),
 	}
 	funcs["defaultRouteParams"] = defaultRouteParams
-	funcs["defaultRouteTemplate"] = defaultRouteTemplate
-	funcs["joinNames"] = joinNames
+	funcs["routePath"] = routePath
+	funcs["callParams"] = g.callParams
+	funcs["cliFieldType"] = cliFieldType
+	funcs["jsonParams"] = jsonParams
+	funcs["hashPayload"] = hashPayload
 	funcs["routes"] = routes
+	funcs["envPrefix"] = func(res *design.ResourceDefinition) string {
+		return strings.ToUpper(api.Name + "_" + res.Name + "_")
+	}
 	file, err := codegen.SourceFileFor(mainFile)
 	if err != nil {
 		return err
@@ -97,10 +141,11 @@ func (g *Generator) generateCommands(commandsFile string, clientPkg string, func
 		codegen.SimpleImport("fmt"),
 		codegen.SimpleImport("log"),
 		codegen.SimpleImport("os"),
+		codegen.SimpleImport("strings"),
 		codegen.SimpleImport("time"),
 		codegen.SimpleImport("github.com/goadesign/goa"),
 		codegen.SimpleImport("github.com/spf13/cobra"),
-		codegen.SimpleImport(clientPkg),
+		g.clientImport(clientPkg),
 		codegen.SimpleImport("golang.org/x/net/context"),
 		codegen.SimpleImport("golang.org/x/net/websocket"),
 	}
@@ -149,23 +194,103 @@ func (g *Generator) generateCommands(commandsFile string, clientPkg string, func
 	return file.FormatCode()
 }
 
-// defaultRouteParams returns the parameters needed to build the first route of the given action.
+// defaultRouteParams returns the parameters needed to build the routes of the given action.
 func defaultRouteParams(a *design.ActionDefinition) *design.AttributeDefinition {
-	r := a.Routes[0]
-	params := r.Params()
-	o := make(design.Object, len(params))
-	nz := make(map[string]bool, len(params))
+	o := make(design.Object)
+	nz := make(map[string]bool)
 	pparams := a.PathParams()
-	for _, p := range params {
-		o[p] = pparams.Type.ToObject()[p]
-		nz[p] = true
+	for _, r := range a.Routes {
+		for _, p := range r.Params() {
+			o[p] = pparams.Type.ToObject()[p]
+			nz[p] = true
+		}
 	}
 	return &design.AttributeDefinition{Type: o, NonZeroAttributes: nz}
 }
 
-// produces a fmt template to render the first route of action.
-func defaultRouteTemplate(a *design.ActionDefinition) string {
-	return design.WildcardRegex.ReplaceAllLiteralString(a.Routes[0].FullPath(), "/%v")
+// routePath produces the code setting the path variable of a command Run method to the path of
+// the given route built from the command path parameter flags. The code returns an error if one
+// of the string path parameter flags is not set.
+func routePath(r *design.RouteDefinition) string {
+	params := r.Params()
+	if len(params) == 0 {
+		return fmt.Sprintf("path = %q", r.FullPath())
+	}
+	pparams := r.Parent.PathParams().Type.ToObject()
+	fields := make([]string, len(params))
+	var checks, flags []string
+	for i, p := range params {
+		fields[i] = "cmd." + codegen.Goify(p, true)
+		if att, ok := pparams[p]; ok && att.Type.Kind() == design.StringKind {
+			checks = append(checks, fields[i]+` == ""`)
+			flags = append(flags, "--"+p)
+		}
+	}
+	var code string
+	if len(checks) > 0 {
+		alt := "as argument"
+		if defaultPath(r.Parent) == "" {
+			alt += ", with --request-path"
+		}
+		noun := "flag"
+		if len(flags) > 1 {
+			noun = "flags"
+		}
+		msg := fmt.Sprintf("missing request path, set it %s or with the %s %s", alt, strings.Join(flags, " and "), noun)
+		code = fmt.Sprintf("if %s {\nreturn fmt.Errorf(%q)\n}\n", strings.Join(checks, " || "), msg)
+	}
+	tmpl := design.WildcardRegex.ReplaceAllLiteralString(r.FullPath(), "/%v")
+	return code + fmt.Sprintf("path = fmt.Sprintf(%q, %s)", tmpl, strings.Join(fields, ", "))
+}
+
+// cliFieldType returns the type of the command data structure field holding the value of the flag
+// of a query parameter or header of the given type. The flags of the parameters encoded in JSON
+// hold the JSON string.
+func cliFieldType(t design.DataType) string {
+	if jsonParam(t) {
+		return "string"
+	}
+	return cmdFieldType(t, false)
+}
+
+// hashPayload returns the hash type of the payload of the given action if the CLI can build the
+// payload from key=value entries, that is if the hash keys and elements are booleans, integers,
+// numbers or strings. hashPayload returns nil otherwise.
+func hashPayload(action *design.ActionDefinition) *design.Hash {
+	if action.Payload == nil {
+		return nil
+	}
+	h := action.Payload.Type.ToHash()
+	if h == nil || !entryType(h.KeyType.Type) || !entryType(h.ElemType.Type) {
+		return nil
+	}
+	return h
+}
+
+// entryType returns true if values of the given type can be parsed from a payload entry flag.
+func entryType(t design.DataType) bool {
+	switch t.Kind() {
+	case design.BooleanKind, design.IntegerKind, design.NumberKind, design.StringKind:
+		return true
+	}
+	return false
+}
+
+// jsonParams returns the query parameters and headers of the given action that are encoded in
+// JSON indexed by name.
+func jsonParams(action *design.ActionDefinition) map[string]*design.AttributeDefinition {
+	params := make(map[string]*design.AttributeDefinition)
+	for _, att := range []*design.AttributeDefinition{action.QueryParams, action.Headers} {
+		if att == nil {
+			continue
+		}
+		for n, p := range att.Type.ToObject() {
+			if jsonParam(p.Type) {
+				params[n] = p
+			}
+		}
+	}
+	return params
 }
 
 // joinNames is a code generation helper function that generates a string built from concatenating
@@ -173,33 +298,63 @@ func defaultRouteTemplate(a *design.ActionDefinition) string {
 func joinNames(atts ...*design.AttributeDefinition) string {
 	var elems []string
 	for _, att := range atts {
-		if att == nil {
-			continue
+		names, fields := cmdFields(att)
+		for _, n := range names {
+			elems = append(elems, fields[n])
 		}
-		obj := att.Type.ToObject()
-		var names []string
-		var optNames []string
-		for n, a := range obj {
-			field := fmt.Sprintf("cmd.%s", codegen.Goify(n, true))
-			if !a.Type.IsArray() && !att.IsRequired(n) && !att.IsNonZero(n) {
-				field = "&" + field
-			}
-			if att.IsRequired(n) {
-				names = append(names, field)
-			} else {
-				optNames = append(optNames, field)
-			}
-		}
-		if len(names) > 0 {
-			sort.Strings(names)
-			elems = append(elems, names...)
+	}
+	return strings.Join(elems, ", ")
+}
+
+// callParams returns the query parameter and header arguments of the client method called by the
+// command of the given action. The query parameters are given as a struct literal when the
+// client is generated with structs holding them.
+func (g *Generator) callParams(action *design.ActionDefinition) string {
+	if !g.queryStructs || action.QueryParams == nil || len(action.QueryParams.Type.ToObject()) == 0 {
+		return joinNames(action.QueryParams, action.Headers)
+	}
+	names, fields := cmdFields(action.QueryParams)
+	elems := make([]string, len(names))
+	for i, n := range names {
+		elems[i] = fmt.Sprintf("%s: %s", codegen.Goify(n, true), fields[n])
+	}
+	query := fmt.Sprintf("client.%s{%s}", queryTypeName(action), strings.Join(elems, ", "))
+	if headers := joinNames(action.Headers); headers != "" {
+		return query + ", " + headers
+	}
+	return query
+}
+
+// cmdFields returns the client variable names of the attributes of the given object in the order
+// of the client method arguments and the command expressions holding their values indexed by
+// variable name.
+func cmdFields(att *design.AttributeDefinition) ([]string, map[string]string) {
+	if att == nil {
+		return nil, nil
+	}
+	obj := att.Type.ToObject()
+	var names []string
+	var optNames []string
+	fields := make(map[string]string, len(obj))
+	for n, a := range obj {
+		// Sort by client variable name to match the order of the client method arguments.
+		varName := codegen.Goify(n, false)
+		field := fmt.Sprintf("cmd.%s", codegen.Goify(n, true))
+		if jsonParam(a.Type) {
+			field = varName + "Param"
+		} else if !a.Type.IsArray() && !att.IsRequired(n) && !att.IsNonZero(n) {
+			field = "&" + field
 		}
-		if len(optNames) > 0 {
-			sort.Strings(optNames)
-			elems = append(elems, optNames...)
+		fields[varName] = field
+		if att.IsRequired(n) {
+			names = append(names, varName)
+		} else {
+			optNames = append(optNames, varName)
 		}
 	}
-	return strings.Join(elems, ", ")
+	sort.Strings(names)
+	sort.Strings(optNames)
+	return append(names, optNames...), fields
 }
 
 // routes create the action command "Use" suffix.
@@ -232,6 +387,10 @@ const mainTmpl = `
 // PrettyPrint is true if the tool output should be formatted for human consumption.
 var PrettyPrint bool
 
+// DryRun is true if the tool should print the curl commands equivalent to the requests instead of
+// sending them.
+var DryRun bool
+
 func main() {
 	// Create command line parser
 	app := &cobra.Command{
@@ -245,6 +404,17 @@ func main() {
 	app.PersistentFlags().DurationVarP(&c.Timeout, "timeout", "t", time.Duration(20) * time.Second, "Set the request timeout")
 	app.PersistentFlags().BoolVar(&c.Dump, "dump", false, "Dump HTTP request and response.")
 	app.PersistentFlags().BoolVar(&PrettyPrint, "pp", false, "Pretty print response body")
+	app.PersistentFlags().BoolVar(&DryRun, "dry-run", false, "Print the equivalent curl command instead of sending the request")
+	app.PersistentPreRun = func(*cobra.Command, []string) {
+		if DryRun {
+			c.Doer = &goaclient.DryRunDoer{
+				Output:   os.Stdout,
+				Redacted: []string{"Authorization"{{ range $security := .API.SecuritySchemes }}{{/*
+				*/}}{{ $signer := signerType $security }}{{ if or (eq $signer "goaclient.APIKeySigner") (eq $signer "goaclient.JWTSigner") }}{{/*
+				*/}}, c.{{ goify $security.SchemeName true }}Signer.Header{{ end }}{{ end }}},
+			}
+		}
+	}
 	RegisterCommands(app, c)
 	if err := app.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "request failed: %s", err)
@@ -256,29 +426,38 @@ func main() {
 const commandTypesTmpl = `{{ $cmdName := goify (printf "%s%s%s" .Name (title .Parent.Name) "Command") true }}	// {{ $cmdName }} is the command line data structure for the {{ .Name }} action of {{ .Parent.Name }}
 	{{ $cmdName }} struct {
 {{ if .Payload }}		Payload string
+{{ end }}{{ if hashPayload . }}		// Entries lists the payload entries given as key=value pairs
+		Entries []string
+{{ end }}{{ if not (defaultPath .) }}		// RequestPath is the request path, required unless set as argument or built from the
+		// path parameter flags
+		RequestPath string
+{{ end }}{{ if gt (len .Routes) 1 }}		// RouteIndex is the index of the action route built from the path parameter flags
+		RouteIndex int
 {{ end }}{{ $params := defaultRouteParams . }}{{ if $params }}{{ range $name, $att := $params.Type.ToObject }}{{ if $att.Description }}		{{ multiComment $att.Description }}
 {{ end }}		{{ goify $name true }} {{ cmdFieldType $att.Type false }}
 {{ end }}{{ end }}{{ $params := .QueryParams }}{{ if $params }}{{ range $name, $att := $params.Type.ToObject }}{{ if $att.Description }}		{{ multiComment $att.Description }}
-{{ end }}		{{ goify $name true }} {{ cmdFieldType $att.Type false}}
+{{ end }}		{{ goify $name true }} {{ cliFieldType $att.Type }}
 {{ end }}{{ end }}{{ $headers := .Headers }}{{ if $headers }}{{ range $name, $att := $headers.Type.ToObject }}{{ if $att.Description }}		{{ multiComment $att.Description }}
-{{ end }}		{{ goify $name true }} {{ cmdFieldType $att.Type false}}
-{{ end }}{{ end }}	}
+{{ end }}		{{ goify $name true }} {{ cliFieldType $att.Type }}
+{{ end }}{{ end }}{{ if hasIdempotencyKey . }}		// IdempotencyKey is the value of the Idempotency-Key request header
+		IdempotencyKey string
+{{ end }}{{ if hasIfMatch . }}		// IfMatch is the value of the If-Match request header
+		IfMatch string
+{{ end }}{{ if viewMediaType . }}		// View is the name of the response media type view requested in the Accept header
+		View string
+{{ end }}	}
 `
 
 const commandsTmplWS = `
 {{ $cmdName := goify (printf "%s%sCommand" .Action.Name (title .Resource.Name)) true }}// Run establishes a websocket connection for the {{ $cmdName }} command.
 func (cmd *{{ $cmdName }}) Run(c *client.Client, args []string) error {
-	var path string
-	if len(args) > 0 {
-		path = args[0]
-	} else {
-{{ $default := defaultPath .Action }}{{ if $default }}	path = "{{ $default }}"
-{{ else }}{{ $pparams := defaultRouteParams .Action }}	path = fmt.Sprintf("{{ defaultRouteTemplate .Action}}", {{ joinNames $pparams }})
-{{ end }}	}
-	logger := goa.NewLogger(log.New(os.Stderr, "", log.LstdFlags))
+` + pathT + decodeJSONParamsT + `	logger := goa.NewLogger(log.New(os.Stderr, "", log.LstdFlags))
 	ctx := goa.WithLogger(context.Background(), logger)
-	ws, err := c.{{ goify (printf "%s%s" .Action.Name (title .Resource.Name)) true }}(ctx, path{{/*
-	*/}}{{ $params := joinNames .Action.QueryParams .Action.Headers }}{{ if $params }}, {{ $params }}{{ end }})
+{{ if gt (len .Action.Routes) 1 }}	if cmd.RouteIndex >= 0 {
+		ctx = goaclient.WithRouteIndex(ctx, cmd.RouteIndex)
+	}
+{{ end }}	ws, err := c.{{ methodName .Action }}(ctx, path{{/*
+	*/}}{{ $params := callParams .Action }}{{ if $params }}, {{ $params }}{{ end }})
 	if err != nil {
 		goa.LogError(ctx, "failed", "err", err)
 		return err
@@ -292,31 +471,90 @@ func (cmd *{{ $cmdName }}) Run(c *client.Client, args []string) error {
 
 const registerTmpl = `{{ $cmdName := goify (printf "%s%sCommand" .Action.Name (title .Resource.Name)) true }}// RegisterFlags registers the command flags with the command line.
 func (cmd *{{ $cmdName }}) RegisterFlags(cc *cobra.Command, c *client.Client) {
-{{ if .Action.Payload }}	cc.Flags().StringVar(&cmd.Payload, "payload", "", "Request JSON body")
+{{ if binaryPayload .Action }}	cc.Flags().StringVar(&cmd.Payload, "payload", "", "Request body")
+{{ else if .Action.Payload }}	cc.Flags().StringVar(&cmd.Payload, "payload", "", "Request JSON body")
+{{ end }}{{ if hashPayload .Action }}	cc.Flags().StringArrayVar(&cmd.Entries, "entry", nil, "Request body entry of the form key=value, may be repeated")
+{{ end }}{{ if not (defaultPath .Action) }}	cc.Flags().StringVar(&cmd.RequestPath, "request-path", "", "Request path, required unless set as argument or built from the path parameter flags")
+{{ end }}{{ if gt (len .Action.Routes) 1 }}	cc.Flags().IntVar(&cmd.RouteIndex, "route-index", -1, "Index of the route built from the path parameter flags {{/*
+*/}}({{ range $i, $r := .Action.Routes }}{{ if $i }}, {{ end }}{{ $i }}: {{ $r.Verb }} {{ $r.FullPath }}{{ end }})")
 {{ end }}{{ $pparams := defaultRouteParams .Action }}{{ if $pparams }}{{ range $pname, $pparam := $pparams.Type.ToObject }}{{ $tmp := goify $pname false }}{{/*
 */}}{{ if not $pparam.DefaultValue }}	var {{ $tmp }} {{ cmdFieldType $pparam.Type false }}
 {{ end }}	cc.Flags().{{ flagType $pparam }}Var(&cmd.{{ goify $pname true }}, "{{ $pname }}", {{/*
 */}}{{ if $pparam.DefaultValue }}{{ printf "%#v" $pparam.DefaultValue }}{{ else }}{{ $tmp }}{{ end }}, ` + "`" + `{{ escapeBackticks $pparam.Description }}` + "`" + `)
 {{ end }}{{ end }}{{ $params := .Action.QueryParams }}{{ if $params }}{{ range $name, $param := $params.Type.ToObject }}{{ $tmp := goify $name false }}{{/*
-*/}}{{ if not $param.DefaultValue }}	var {{ $tmp }} {{ cmdFieldType $param.Type false }}
+*/}}{{ if not $param.DefaultValue }}	var {{ $tmp }} {{ cliFieldType $param.Type }}
 {{ end }}	cc.Flags().{{ flagType $param }}Var(&cmd.{{ goify $name true }}, "{{ $name }}", {{/*
 */}}{{ if $param.DefaultValue }}{{ printf "%#v" $param.DefaultValue }}{{ else }}{{ $tmp }}{{ end }}, ` + "`" + `{{ escapeBackticks $param.Description }}` + "`" + `)
 {{ end }}{{ end }}{{ $headers := .Action.Headers }}{{ if $headers }}{{ range $name, $header := $headers.Type.ToObject }}{{/*
 */}} cc.Flags().StringVar(&cmd.{{ goify $name true }}, "{{ $name }}", {{/*
 */}}{{ if $header.DefaultValue }}{{ printf "%q" $header.DefaultValue }}{{ else }}""{{ end }}, ` + "`" + `{{ escapeBackticks $header.Description }}` + "`" + `)
-{{ end }}{{ end }}{{ if .Action.Security }}   c.{{ goify .Action.Security.Scheme.SchemeName true }}Signer.RegisterFlags(cc){{ end }}}`
+{{ end }}{{ end }}{{ if hasIdempotencyKey .Action }}	cc.Flags().StringVar(&cmd.IdempotencyKey, "idempotency-key", "", "Value of the Idempotency-Key request header")
+{{ end }}{{ if hasIfMatch .Action }}	cc.Flags().StringVar(&cmd.IfMatch, "if-match", "", "Value of the If-Match request header")
+{{ end }}{{ with viewMediaType .Action }}	cc.Flags().StringVar(&cmd.View, "view", "", "Name of the {{ .TypeName }} view to request")
+{{ end }}{{ if .Action.Security }}   c.{{ goify .Action.Security.Scheme.SchemeName true }}Signer.RegisterFlags(cc){{ end }}}`
+
+// pathT computes the request path of a command: the command argument if any, the --request-path
+// flag if the action has no route free of wildcards, the route selected with --route-index if the
+// action has multiple routes and the default path or the first route built from the path
+// parameter flags otherwise.
+const pathT = `	var path string
+	switch {
+	case len(args) > 0:
+		path = args[0]
+{{ if not (defaultPath .Action) }}	case cmd.RequestPath != "":
+		path = cmd.RequestPath
+{{ end }}{{ if gt (len .Action.Routes) 1 }}	case cmd.RouteIndex >= 0:
+		switch cmd.RouteIndex {
+{{ range $i, $r := .Action.Routes }}		case {{ $i }}:
+{{ routePath $r }}
+{{ end }}		default:
+			return fmt.Errorf("invalid route index %d, the action has {{ len .Action.Routes }} routes", cmd.RouteIndex)
+		}
+{{ end }}	default:
+{{ with defaultPath .Action }}		path = "{{ . }}"
+{{ else }}{{ routePath (index .Action.Routes 0) }}
+{{ end }}	}
+`
+
+// entriesT adds the entries given with the --entry flags to the hash payload of a command, it
+// is executed with the payload hash type.
+const entriesT = `	for _, entry := range cmd.Entries {
+		elems := strings.SplitN(entry, "=", 2)
+		if len(elems) != 2 {
+			return fmt.Errorf("invalid payload entry %q, must be of the form key=value", entry)
+		}
+{{ if eq .KeyType.Type.Kind 4 }}		key := elems[0]
+{{ else }}		var key {{ cmdFieldType .KeyType.Type false }}
+		if err := json.Unmarshal([]byte(elems[0]), &key); err != nil {
+			return fmt.Errorf("invalid payload entry key %q: %s", elems[0], err)
+		}
+{{ end }}{{ if eq .ElemType.Type.Kind 4 }}		value := elems[1]
+{{ else }}		var value {{ cmdFieldType .ElemType.Type false }}
+		if err := json.Unmarshal([]byte(elems[1]), &value); err != nil {
+			return fmt.Errorf("invalid payload entry value %q: %s", elems[1], err)
+		}
+{{ end }}		if payload == nil {
+			payload = make(map[{{ cmdFieldType .KeyType.Type false }}]{{ cmdFieldType .ElemType.Type false }})
+		}
+		payload[key] = value
+	}
+`
+
+// decodeJSONParamsT decodes the values of the flags of the query parameters and headers encoded in
+// JSON, see jsonParam.
+const decodeJSONParamsT = `{{ range $name, $att := jsonParams .Action }}{{ $field := goify $name true }}{{/*
+*/}}{{ $var := printf "%sParam" (goify $name false) }}	var {{ $var }} {{ cmdFieldType $att.Type false }}
+	if cmd.{{ $field }} != "" {
+		if err := json.Unmarshal([]byte(cmd.{{ $field }}), &{{ $var }}); err != nil {
+			return fmt.Errorf("failed to deserialize {{ $name }}: %s", err)
+		}
+	}
+{{ end }}`
 
 const commandsTmpl = `
 {{ $cmdName := goify (printf "%s%sCommand" .Action.Name (title .Resource.Name)) true }}// Run makes the HTTP request corresponding to the {{ $cmdName }} command.
 func (cmd *{{ $cmdName }}) Run(c *client.Client, args []string) error {
-	var path string
-	if len(args) > 0 {
-		path = args[0]
-	} else {
-{{ $default := defaultPath .Action }}{{ if $default }}	path = "{{ $default }}"
-{{ else }}{{ $pparams := defaultRouteParams .Action }}	path = fmt.Sprintf("{{ defaultRouteTemplate .Action }}", {{ joinNames $pparams }})
-{{ end }}	}
-{{ if .Action.Payload }}var payload {{ gotyperefext .Action.Payload 2 "client" }}
+` + pathT + decodeJSONParamsT + `{{ if binaryPayload .Action }}{{ else if .Action.Payload }}var payload {{ gotyperefext (payloadType .Action) 2 "client" }}
 	if cmd.Payload != "" {
 		err := json.Unmarshal([]byte(cmd.Payload), &payload)
 		if err != nil {
@@ -324,11 +562,15 @@ func (cmd *{{ $cmdName }}) Run(c *client.Client, args []string) error {
 {{ else }}			return fmt.Errorf("failed to deserialize payload: %s", err)
 {{ end }}		}
 	}
-{{ end }}	logger := goa.NewLogger(log.New(os.Stderr, "", log.LstdFlags))
+{{ with hashPayload .Action }}` + entriesT + `{{ end }}{{ end }}	logger := goa.NewLogger(log.New(os.Stderr, "", log.LstdFlags))
 	ctx := goa.WithLogger(context.Background(), logger)
-	resp, err := c.{{ goify (printf "%s%s" .Action.Name (title .Resource.Name)) true }}(ctx, path{{ if .Action.Payload }}, {{/*
+{{ if gt (len .Action.Routes) 1 }}	if cmd.RouteIndex >= 0 {
+		ctx = goaclient.WithRouteIndex(ctx, cmd.RouteIndex)
+	}
+{{ end }}	resp, err := c.{{ methodName .Action }}(ctx, path{{ if binaryPayload .Action }}, strings.NewReader(cmd.Payload){{ else if .Action.Payload }}, {{/*
 	*/}}{{ if or .Action.Payload.Type.IsObject .Action.Payload.IsPrimitive }}&{{ end }}payload{{ else }}{{ end }}{{/*
-	*/}}{{ $params := joinNames .Action.QueryParams .Action.Headers }}{{ if $params }}, {{ $params }}{{ end }})
+	*/}}{{ $params := callParams .Action }}{{ if $params }}, {{ $params }}{{ end }}{{/*
+	*/}}{{ if hasIdempotencyKey .Action }}, cmd.IdempotencyKey{{ end }}{{ if hasIfMatch .Action }}, cmd.IfMatch{{ end }}{{ if viewMediaType .Action }}, cmd.View{{ end }})
 	if err != nil {
 		goa.LogError(ctx, "failed", "err", err)
 		return err
@@ -341,6 +583,9 @@ func (cmd *{{ $cmdName }}) Run(c *client.Client, args []string) error {
 
 // Takes map[string][]*design.ActionDefinition as input
 const registerCmdsT = `// RegisterCommands all the resource action subcommands to the application command line.
+// The flags that are not set on the command line default to the value of the environment variable
+// <API>_<RESOURCE>_<FLAG> if set, explicit flags take precedence over environment variables which
+// take precedence over the design defaults.
 func RegisterCommands(app *cobra.Command, c *client.Client) {
 {{ if gt (len .) 0 }}	var command, sub *cobra.Command
 {{ end }}{{ range $name, $actions := . }}	command = &cobra.Command{
@@ -352,7 +597,10 @@ func RegisterCommands(app *cobra.Command, c *client.Client) {
 	sub = &cobra.Command{
 		Use:   ` + "`" + `{{ $action.Parent.Name }} {{ routes $action }} or` + "`" + `,
 		Short: ` + "`" + `{{ escapeBackticks $action.Parent.Description }}` + "`" + `,
-		RunE:  func(cmd *cobra.Command, args []string) error { return {{ $tmp }}.Run(c, args) },
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return goaclient.FlagsFromEnv(cmd, "{{ envPrefix $action.Parent }}")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error { return {{ $tmp }}.Run(c, args) },
 	}
 	{{ $tmp }}.RegisterFlags(sub, c)
 	command.AddCommand(sub)
@@ -3,6 +3,7 @@ package genclient_test
 import (
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 
@@ -95,7 +96,7 @@ var _ = Describe("Generate", func() {
 
 		It("generates the correct command flag initialization code", func() {
 			Ω(genErr).Should(BeNil())
-			Ω(files).Should(HaveLen(7))
+			Ω(files).Should(HaveLen(8))
 			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "commands.go"))
 			Ω(err).ShouldNot(HaveOccurred())
 			Ω(len(strings.Split(string(content), "\n"))).Should(BeNumerically(">=", 3))
@@ -116,7 +117,7 @@ var _ = Describe("Generate", func() {
 
 			It("properly escapes the multi-line string used in the short description", func() {
 				Ω(genErr).Should(BeNil())
-				Ω(files).Should(HaveLen(7))
+				Ω(files).Should(HaveLen(8))
 				content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "main.go"))
 				Ω(err).ShouldNot(HaveOccurred())
 				Ω(string(content)).Should(ContainSubstring(multiline))
@@ -133,7 +134,7 @@ var _ = Describe("Generate", func() {
 
 			It("properly escapes the multi-line string used in the short description", func() {
 				Ω(genErr).Should(BeNil())
-				Ω(files).Should(HaveLen(7))
+				Ω(files).Should(HaveLen(8))
 				content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "main.go"))
 				Ω(err).ShouldNot(HaveOccurred())
 				Ω(string(content)).Should(ContainSubstring(pre + "` + \"`\" + `" + post))
@@ -189,10 +190,74 @@ var _ = Describe("Generate", func() {
 
 		It("generates the Signer.RegisterFlags call from Command", func() {
 			Ω(genErr).Should(BeNil())
-			Ω(files).Should(HaveLen(7))
+			Ω(files).Should(HaveLen(8))
 			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "commands.go"))
 			Ω(err).ShouldNot(HaveOccurred())
 			Ω(content).Should(ContainSubstring("c.JWT1Signer.RegisterFlags(cc)"))
 		})
+
+		It("generates the lookup of flag defaults in the environment", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "main.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring(`return goaclient.FlagsFromEnv(cmd, "TESTAPI_FOO_")`))
+		})
+
+		It("generates the dry-run flag redacting the signer header", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "main.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring(`app.PersistentFlags().BoolVar(&DryRun, "dry-run", false,`))
+			Ω(content).Should(ContainSubstring(`Redacted: []string{"Authorization", c.JWT1Signer.Header},`))
+			_, err = gexec.Build(filepath.Join(testgenPackagePath, "client", "testapi-cli"))
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+	})
+
+	Context("with an action with parameters named path and route", func() {
+		BeforeEach(func() {
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name: "show",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: "/files/*path"},
+									{Verb: "GET", Path: "/archives/*path"},
+								},
+								Params: &design.AttributeDefinition{
+									Type: design.Object{
+										"path": &design.AttributeDefinition{Type: design.String},
+									},
+								},
+								QueryParams: &design.AttributeDefinition{
+									Type: design.Object{
+										"route": &design.AttributeDefinition{Type: design.String},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			showAct := fooRes.Actions["show"]
+			showAct.Parent = fooRes
+			showAct.Routes[0].Parent = showAct
+			showAct.Routes[1].Parent = showAct
+		})
+
+		It("generates a CLI that builds and registers distinct flags", func() {
+			Ω(genErr).Should(BeNil())
+			cli, err := gexec.Build(filepath.Join(testgenPackagePath, "client", "testapi-cli"))
+			Ω(err).ShouldNot(HaveOccurred())
+			out, err := exec.Command(cli, "show", "foo", "--help").CombinedOutput()
+			Ω(err).ShouldNot(HaveOccurred(), string(out))
+			Ω(string(out)).Should(ContainSubstring("--request-path"))
+			Ω(string(out)).Should(ContainSubstring("--route-index"))
+		})
 	})
 })
@@ -0,0 +1,339 @@
+package genclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/goadesign/goa/design"
+	"github.com/goadesign/goa/goagen/codegen"
+)
+
+// Filename used to generate the examples of the client methods (without the "_test.go" suffix)
+const examplesFileName = "example"
+
+// exampleData is the data used to render the example calling the client method of an action.
+type exampleData struct {
+	// Name is the name of the action.
+	Name string
+	// ResourceName is the name of the action resource.
+	ResourceName string
+	// MethodName is the name of the client method.
+	MethodName string
+	// Path is the expression calling the path factory function of the first action route.
+	Path string
+	// PathError is true if the path factory function returns an error.
+	PathError bool
+	// Payload is the JSON encoded example of the payload, empty if there is none.
+	Payload string
+	// Vars lists the statements declaring the variables given as arguments.
+	Vars []string
+	// Args lists the expressions of the method arguments following the path.
+	Args []string
+}
+
+// newExampleData returns the data needed to generate the example calling the client method of
+// the given action. params and names are the declarations and the names of the method arguments
+// following the path, the arguments initialized from query parameters or headers are described by
+// attrs. The example values declared in the design initialize the arguments, the arguments
+// without example are set to the zero value of their type.
+func (g *Generator) newExampleData(action *design.ActionDefinition, params, names []string, attrs map[string]*design.AttributeDefinition) *exampleData {
+	data := &exampleData{
+		Name:         action.Name,
+		ResourceName: action.Parent.Name,
+		MethodName:   methodName(action),
+		PathError:    g.validatedPaths,
+	}
+	route := action.Routes[0]
+	var pathArgs []string
+	for _, p := range route.Params() {
+		typ := "string"
+		var lit string
+		if att := pathParam(action, p); att != nil {
+			if att.Type.Kind() == design.UUIDKind {
+				typ = "uuid.UUID"
+			} else {
+				typ = cmdFieldType(att.Type, false)
+			}
+			lit = exampleLiteral(att.Type, typ, att.Example)
+		}
+		if lit == "" {
+			lit = zeroLiteral(typ)
+		}
+		pathArgs = append(pathArgs, lit)
+	}
+	data.Path = fmt.Sprintf("%sPath(%s)", codegen.Goify(action.Name+strings.Title(action.Parent.Name), true), strings.Join(pathArgs, ", "))
+	for i, name := range names {
+		typ := strings.TrimPrefix(params[i], name+" ")
+		if name == "payload" {
+			data.Vars = append(data.Vars, fmt.Sprintf("var payload %s", typ))
+			data.Args = append(data.Args, name)
+			if ex := attributeExample(action.Payload.AttributeDefinition); ex != nil {
+				if b, err := json.Marshal(g.exampleJSON(action.Payload.AttributeDefinition, ex)); err == nil {
+					data.Payload = exampleString(string(b))
+				}
+			}
+			continue
+		}
+		var lit string
+		if att, ok := attrs[name]; ok {
+			lit = exampleLiteral(att.Type, typ, att.Example)
+		}
+		switch {
+		case lit == "":
+			data.Vars = append(data.Vars, fmt.Sprintf("var %s %s", name, typ))
+			data.Args = append(data.Args, name)
+		case strings.HasPrefix(typ, "*"):
+			data.Vars = append(data.Vars, fmt.Sprintf("%s := %s", name, lit))
+			data.Args = append(data.Args, "&"+name)
+		default:
+			data.Args = append(data.Args, lit)
+		}
+	}
+	return data
+}
+
+// pathParam returns the attribute of the path parameter of action with the given name, nil if the
+// action does not define it.
+func pathParam(action *design.ActionDefinition, name string) *design.AttributeDefinition {
+	if action.Params == nil {
+		return nil
+	}
+	return action.Params.Type.ToObject()[name]
+}
+
+// attributeExample returns the example of att. The example of an object attribute that does not
+// declare one is built from the examples of its attributes, nil if none declares an example.
+func attributeExample(att *design.AttributeDefinition) interface{} {
+	if att.Example != nil || !att.Type.IsObject() {
+		return att.Example
+	}
+	ex := make(map[string]interface{})
+	for n, child := range att.Type.ToObject() {
+		if v := attributeExample(child); v != nil {
+			ex[n] = v
+		}
+	}
+	if len(ex) == 0 {
+		return nil
+	}
+	return ex
+}
+
+// exampleJSON returns the value of the example v of att that encodes into the JSON accepted by the
+// generated data structures: object keys are renamed after the json struct tags of the fields.
+func (g *Generator) exampleJSON(att *design.AttributeDefinition, v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if !att.Type.IsObject() {
+			return val
+		}
+		obj := att.Type.ToObject()
+		res := make(map[string]interface{}, len(val))
+		for k, ev := range val {
+			child, ok := obj[k]
+			if !ok {
+				res[k] = ev
+				continue
+			}
+			res[g.jsonKey(child, k)] = g.exampleJSON(child, ev)
+		}
+		return res
+	case []interface{}:
+		if !att.Type.IsArray() {
+			return val
+		}
+		elem := att.Type.ToArray().ElemType
+		res := make([]interface{}, len(val))
+		for i, ev := range val {
+			res[i] = g.exampleJSON(elem, ev)
+		}
+		return res
+	case map[interface{}]interface{}:
+		var elem *design.AttributeDefinition
+		if att.Type.IsHash() {
+			elem = att.Type.ToHash().ElemType
+		}
+		res := make(map[string]interface{}, len(val))
+		for k, ev := range val {
+			if elem != nil {
+				ev = g.exampleJSON(elem, ev)
+			}
+			res[fmt.Sprint(k)] = ev
+		}
+		return res
+	default:
+		return v
+	}
+}
+
+// jsonKey returns the key of the json struct tag of the field generated for the attribute att
+// named name, see codegen.GoTypeDef.
+func (g *Generator) jsonKey(att *design.AttributeDefinition, name string) string {
+	key := name
+	if tag, ok := att.Metadata["struct:tag:json"]; ok && len(tag) > 0 {
+		key = strings.Split(strings.Join(tag, ","), ",")[0]
+	} else if field, ok := att.Metadata["struct:field:json"]; ok && len(field) > 0 {
+		key = field[0]
+	}
+	if rename, ok := jsonNamings[g.jsonNaming]; ok {
+		key = rename(key)
+	}
+	return key
+}
+
+// exampleLiteral returns the Go literal of type typ initialized with the example v of an attribute
+// of type t, the empty string if v is nil or cannot be represented as a literal of type typ. typ
+// may be a pointer type in which case the literal is of the pointed type.
+func exampleLiteral(t design.DataType, typ string, v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	typ = strings.TrimPrefix(typ, "*")
+	if strings.HasPrefix(typ, "[]") {
+		elems, ok := v.([]interface{})
+		if !ok || !t.IsArray() {
+			return ""
+		}
+		elemType := t.ToArray().ElemType.Type
+		lits := make([]string, len(elems))
+		for i, elem := range elems {
+			if lits[i] = exampleLiteral(elemType, typ[2:], elem); lits[i] == "" {
+				return ""
+			}
+		}
+		return fmt.Sprintf("%s{%s}", typ, strings.Join(lits, ", "))
+	}
+	switch typ {
+	case "string":
+		if ts, ok := v.(time.Time); ok {
+			return strconv.Quote(ts.Format(time.RFC3339))
+		}
+		if t.IsPrimitive() {
+			return strconv.Quote(fmt.Sprint(v))
+		}
+	case "uuid.UUID":
+		return fmt.Sprintf("uuid.FromStringOrNil(%q)", fmt.Sprint(v))
+	case "int":
+		switch n := v.(type) {
+		case int:
+			return strconv.Itoa(n)
+		case int64:
+			return strconv.FormatInt(n, 10)
+		case float64:
+			if n == float64(int64(n)) {
+				return strconv.FormatInt(int64(n), 10)
+			}
+		}
+	case "float64":
+		var f float64
+		switch n := v.(type) {
+		case int:
+			f = float64(n)
+		case int64:
+			f = float64(n)
+		case float64:
+			f = n
+		default:
+			return ""
+		}
+		lit := strconv.FormatFloat(f, 'g', -1, 64)
+		if !strings.ContainsAny(lit, ".eEnN") {
+			lit += ".0"
+		}
+		return lit
+	case "bool":
+		if b, ok := v.(bool); ok {
+			return strconv.FormatBool(b)
+		}
+	}
+	return ""
+}
+
+// zeroLiteral returns the literal of the zero value of the given path parameter type.
+func zeroLiteral(typ string) string {
+	switch typ {
+	case "string":
+		return `""`
+	case "int", "float64":
+		return "0"
+	case "bool":
+		return "false"
+	case "uuid.UUID":
+		return "uuid.UUID{}"
+	default:
+		return "nil"
+	}
+}
+
+// exampleString returns the Go string literal of s, a raw string literal unless s contains
+// backquotes.
+func exampleString(s string) string {
+	if strings.Contains(s, "`") {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
+
+// generateExamples generates the example_test.go file containing the runnable examples of the
+// client methods collected while generating the resource clients.
+func (g *Generator) generateExamples() error {
+	if len(g.exampleCalls) == 0 {
+		return nil
+	}
+	sort.Slice(g.exampleCalls, func(i, j int) bool {
+		return g.exampleCalls[i].MethodName < g.exampleCalls[j].MethodName
+	})
+	filename := filepath.Join(g.outDir, examplesFileName+"_test.go")
+	file, err := codegen.SourceFileFor(filename)
+	if err != nil {
+		return err
+	}
+	imports := []*codegen.ImportSpec{
+		codegen.SimpleImport("encoding/json"),
+		codegen.SimpleImport("fmt"),
+		codegen.SimpleImport("io"),
+		codegen.SimpleImport("golang.org/x/net/context"),
+		codegen.NewImport("uuid", "github.com/satori/go.uuid"),
+	}
+	if err := file.WriteHeader("Examples", g.pkgName, imports); err != nil {
+		return err
+	}
+	g.genfiles = append(g.genfiles, filename)
+	tmpl := template.Must(template.New("example").Parse(exampleTmpl))
+	if err := tmpl.Execute(file, g.exampleCalls); err != nil {
+		return err
+	}
+	return file.FormatCode()
+}
+
+const exampleTmpl = `{{ range . }}// This example calls the {{ .Name }} action of the {{ .ResourceName }} resource with the
+// example values of the design.
+func ExampleClient_{{ .MethodName }}() {
+	c := New(nil)
+{{ if .PathError }}	path, err := {{ .Path }}
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+{{ else }}	path := {{ .Path }}
+{{ end }}{{ range .Vars }}	{{ . }}
+{{ end }}{{ if .Payload }}	if err := json.Unmarshal([]byte({{ .Payload }}), &payload); err != nil {
+		fmt.Println(err)
+		return
+	}
+{{ end }}	resp, err := c.{{ .MethodName }}(context.Background(), path{{ range .Args }}, {{ . }}{{ end }})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer resp.Body.Close()
+	fmt.Println(resp.Status)
+}
+
+{{ end }}`
@@ -0,0 +1,97 @@
+package genclient
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/goadesign/goa/goagen/codegen"
+)
+
+// FacadeService describes a generated client package aggregated by a facade.
+type FacadeService struct {
+	// Name is the name of the service, the facade field holding the service client is named
+	// after it, e.g. Bottles for "bottles".
+	Name string
+	// PkgPath is the import path of the client package.
+	PkgPath string
+	// PkgName is the name of the client package, "client" or the version package generated
+	// with the versioned flag, e.g. "v2".
+	PkgName string
+}
+
+// NewFacadeService returns the description of the client package of the service with the given
+// name generated in pkgDir. This is the first file returned by Generate.
+func NewFacadeService(name, pkgDir string) (*FacadeService, error) {
+	pkgPath, err := codegen.PackagePath(pkgDir)
+	if err != nil {
+		return nil, err
+	}
+	return &FacadeService{Name: name, PkgPath: pkgPath, PkgName: filepath.Base(pkgDir)}, nil
+}
+
+// Alias returns the name under which the facade imports the client package: the service name
+// followed by the package name, e.g. "bottlesclient" or "bottlesv2". This makes it possible to
+// aggregate client packages that share the same name.
+func (s *FacadeService) Alias() string {
+	return codegen.Goify(s.Name, false) + s.PkgName
+}
+
+// FieldName returns the name of the facade field holding the service client.
+func (s *FacadeService) FieldName() string {
+	return codegen.Goify(s.Name, true)
+}
+
+// GenerateFacade generates the package named pkgName in outDir that defines the Facade struct
+// exposing the clients of the given services under fields named after them. Each service client
+// lives in its own package so that the names of the types of different services cannot collide.
+func GenerateFacade(outDir, pkgName string, services []*FacadeService) (files []string, err error) {
+	if len(services) == 0 {
+		return nil, fmt.Errorf("no service to aggregate")
+	}
+	seen := make(map[string]string)
+	for _, s := range services {
+		if other, ok := seen[s.FieldName()]; ok {
+			return nil, fmt.Errorf("services %q and %q both map to the facade field %s", other, s.Name, s.FieldName())
+		}
+		seen[s.FieldName()] = s.Name
+	}
+	if err = os.MkdirAll(outDir, 0755); err != nil {
+		return
+	}
+	filename := filepath.Join(outDir, "facade.go")
+	file, err := codegen.SourceFileFor(filename)
+	if err != nil {
+		return
+	}
+	imports := []*codegen.ImportSpec{codegen.SimpleImport("net/http")}
+	for _, s := range services {
+		imports = append(imports, codegen.NewImport(s.Alias(), s.PkgPath))
+	}
+	if err = file.WriteHeader("Facade", pkgName, imports); err != nil {
+		return
+	}
+	files = append(files, filename)
+	tmpl := template.Must(template.New("facade").Parse(facadeTmpl))
+	if err = tmpl.Execute(file, services); err != nil {
+		return
+	}
+	err = file.FormatCode()
+	return
+}
+
+const facadeTmpl = `// Facade aggregates the clients of several services.
+type Facade struct {
+{{ range . }}	// {{ .FieldName }} is the client of the {{ .Name }} service.
+	{{ .FieldName }} *{{ .Alias }}.Client
+{{ end }}}
+
+// NewFacade instantiates the clients of all the services with the given http client. The
+// clients can be configured individually once created, e.g. to set their Host.
+func NewFacade(c *http.Client) *Facade {
+	return &Facade{
+{{ range . }}		{{ .FieldName }}: {{ .Alias }}.New(c),
+{{ end }}	}
+}
+`
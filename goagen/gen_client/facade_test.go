@@ -0,0 +1,96 @@
+package genclient_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/goadesign/goa/design"
+	"github.com/goadesign/goa/goagen/gen_client"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GenerateFacade", func() {
+	const testgenPackagePath = "github.com/goadesign/goa/goagen/gen_client/facade_"
+
+	var outDir string
+	var services []*genclient.FacadeService
+	var files []string
+	var genErr error
+
+	newAPI := func(name, version, resource string) *design.APIDefinition {
+		api := &design.APIDefinition{
+			Name:    name,
+			Version: version,
+			Resources: map[string]*design.ResourceDefinition{
+				resource: {
+					Name: resource,
+					Actions: map[string]*design.ActionDefinition{
+						"list": {
+							Name:   "list",
+							Routes: []*design.RouteDefinition{{Verb: "GET", Path: "/" + resource}},
+						},
+					},
+				},
+			},
+		}
+		res := api.Resources[resource]
+		act := res.Actions["list"]
+		act.Parent = res
+		act.Routes[0].Parent = act
+		return api
+	}
+
+	generateService := func(name string, api *design.APIDefinition, args ...string) {
+		design.Design = api
+		os.Args = append([]string{"goagen", "client", "--out=" + filepath.Join(outDir, name), "--design=foo", "--no-cli"}, args...)
+		files, err := genclient.Generate()
+		Ω(err).ShouldNot(HaveOccurred())
+		service, err := genclient.NewFacadeService(name, files[0])
+		Ω(err).ShouldNot(HaveOccurred())
+		services = append(services, service)
+	}
+
+	BeforeEach(func() {
+		gopath := filepath.SplitList(os.Getenv("GOPATH"))[0]
+		outDir = filepath.Join(gopath, "src", testgenPackagePath)
+		err := os.MkdirAll(outDir, 0777)
+		Ω(err).ShouldNot(HaveOccurred())
+		services = nil
+		generateService("bottles", newAPI("bottles", "2.0", "bottle"), "--versioned")
+		generateService("users", newAPI("users", "", "user"))
+	})
+
+	JustBeforeEach(func() {
+		files, genErr = genclient.GenerateFacade(filepath.Join(outDir, "facade"), "facade", services)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(outDir)
+	})
+
+	It("exposes the client of each service", func() {
+		Ω(genErr).ShouldNot(HaveOccurred())
+		Ω(files).Should(Equal([]string{filepath.Join(outDir, "facade", "facade.go")}))
+		content, err := ioutil.ReadFile(files[0])
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(content).Should(ContainSubstring("package facade"))
+		Ω(content).Should(ContainSubstring(`bottlesv2 "` + testgenPackagePath + `/bottles/client/v2"`))
+		Ω(content).Should(ContainSubstring(`usersclient "` + testgenPackagePath + `/users/client"`))
+		Ω(content).Should(ContainSubstring("Bottles *bottlesv2.Client"))
+		Ω(content).Should(ContainSubstring("Users *usersclient.Client"))
+		Ω(content).Should(ContainSubstring("Bottles: bottlesv2.New(c),"))
+		Ω(content).Should(ContainSubstring("Users:   usersclient.New(c),"))
+	})
+
+	Context("with services mapping to the same field", func() {
+		BeforeEach(func() {
+			services = append(services, &genclient.FacadeService{Name: "Users", PkgPath: "example.com/users", PkgName: "client"})
+		})
+
+		It("returns an error", func() {
+			Ω(genErr).Should(MatchError(`services "users" and "Users" both map to the facade field Users`))
+		})
+	})
+})
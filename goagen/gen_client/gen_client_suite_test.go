@@ -1,6 +1,10 @@
 package genclient_test
 
 import (
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -11,3 +15,15 @@ func TestGenClient(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "GenClient Suite")
 }
+
+// runGeneratedTest adds the test file src to the generated package in dir and runs the package
+// tests. It returns the output of "go test".
+func runGeneratedTest(dir, src string) (string, error) {
+	if err := ioutil.WriteFile(filepath.Join(dir, "runtime_test.go"), []byte(src), 0644); err != nil {
+		return "", err
+	}
+	cmd := exec.Command("go", "test", "-count=1", ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
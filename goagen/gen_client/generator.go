@@ -1,15 +1,25 @@
 package genclient
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
+	"go/parser"
+	"mime"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/goadesign/goa/design"
+	"github.com/goadesign/goa/dslengine"
 	"github.com/goadesign/goa/goagen/codegen"
 	"github.com/goadesign/goa/goagen/gen_app"
 	"github.com/goadesign/goa/goagen/utils"
@@ -18,26 +28,116 @@ import (
 // Filename used to generate all data types (without the ".go" extension)
 const typesFileName = "datatypes"
 
+// Filename used to generate the metric names of the actions (without the ".go" extension)
+const metricsFileName = "metrics"
+
 // Generator is the application code generator.
 type Generator struct {
 	outDir         string // Path to output directory
 	genfiles       []string
 	generatedTypes map[string]bool // Keeps track of names of user types that correspond to action payloads.
+	generatedEnums map[string]bool // Keeps track of names of the types generated for enum attributes.
+	exampleCalls   []*exampleData  // Keeps track of the client methods called by the generated examples.
 	encoders       []*genapp.EncoderTemplateData
 	decoders       []*genapp.EncoderTemplateData
 	encoderImports []string
+	idempotency    bool     // Whether to generate idempotency key arguments for unsafe actions
+	ifMatch        bool     // Whether to generate If-Match arguments for PUT and PATCH actions
+	wsWrappers     bool     // Whether to generate typed wrappers for websocket connections
+	patchPointers  bool     // Whether to generate pointer fields for the payloads of PATCH actions
+	grouped        bool     // Whether to generate resource clients grouping the action methods
+	omitEmpty      bool     // Whether to omit all the non-required fields from encoded data when empty
+	contentMD5     bool     // Whether to set the Content-MD5 header of the requests with a body
+	noCLI          bool     // Whether to skip the generation of the CLI tool
+	chunked        bool     // Whether to generate request builders streaming payloads
+	versioned      bool     // Whether to generate the client package in a directory named after the API version
+	validate       bool     // Whether to validate the decoded response bodies
+	queryStructs   bool     // Whether to generate structs holding the query parameters of the actions
+	orderedQuery   bool     // Whether to build the query strings in the order of the parameters instead of sorting them
+	validatedPaths bool     // Whether the path factories validate their parameters and return an error
+	typesOnly      bool     // Whether to only generate the data structures of the API types
+	nested         bool     // Whether to generate methods taking the path parameters of nested resource actions
+	examples       bool     // Whether to generate the example_test.go file calling the client methods with the design examples
+	jsonNaming     string   // Naming strategy of the JSON keys of the data structures: snake, camel or asis
+	pkgName        string   // Name of the generated client package
+	include        []string // Glob patterns of the resources or actions to generate, all if empty
+	exclude        []string // Glob patterns of the resources or actions not to generate
 }
 
 // Generate is the generator entry point called by the meta generator.
 func Generate() (files []string, err error) {
-	var outDir string
+	var (
+		outDir         string
+		idempotency    bool
+		ifMatch        bool
+		wsWrappers     bool
+		patchPointers  bool
+		grouped        bool
+		omitEmpty      bool
+		contentMD5     bool
+		noCLI          bool
+		chunked        bool
+		versioned      bool
+		validate       bool
+		queryStructs   bool
+		orderedQuery   bool
+		validatedPaths bool
+		typesOnly      bool
+		nested         bool
+		examples       bool
+		jsonNaming     string
+		include        string
+		exclude        string
+	)
 
 	set := flag.NewFlagSet("client", flag.PanicOnError)
 	set.String("design", "", "")
 	set.StringVar(&outDir, "out", "", "")
+	set.BoolVar(&idempotency, "idempotency", false, "")
+	set.BoolVar(&ifMatch, "if-match", false, "")
+	set.BoolVar(&wsWrappers, "ws-wrappers", false, "")
+	set.BoolVar(&patchPointers, "patch-pointers", false, "")
+	set.BoolVar(&grouped, "grouped", false, "")
+	set.BoolVar(&omitEmpty, "omitempty", false, "")
+	set.BoolVar(&contentMD5, "content-md5", false, "")
+	set.BoolVar(&noCLI, "no-cli", false, "")
+	set.BoolVar(&chunked, "streaming-uploads", false, "")
+	set.BoolVar(&versioned, "versioned", false, "")
+	set.BoolVar(&validate, "validate-responses", false, "")
+	set.BoolVar(&queryStructs, "query-structs", false, "")
+	set.BoolVar(&orderedQuery, "ordered-query", false, "")
+	set.BoolVar(&validatedPaths, "validated-paths", false, "")
+	set.BoolVar(&typesOnly, "types-only", false, "")
+	set.BoolVar(&nested, "nested-methods", false, "")
+	set.BoolVar(&examples, "examples", false, "")
+	set.StringVar(&jsonNaming, "json-naming", "asis", "")
+	set.StringVar(&include, "include", "", "")
+	set.StringVar(&exclude, "exclude", "", "")
 	set.Parse(os.Args[2:])
 
-	g := &Generator{outDir: outDir}
+	g := &Generator{
+		outDir:         outDir,
+		idempotency:    idempotency,
+		ifMatch:        ifMatch,
+		wsWrappers:     wsWrappers,
+		patchPointers:  patchPointers,
+		grouped:        grouped,
+		omitEmpty:      omitEmpty,
+		contentMD5:     contentMD5,
+		noCLI:          noCLI,
+		chunked:        chunked,
+		versioned:      versioned,
+		validate:       validate,
+		queryStructs:   queryStructs,
+		orderedQuery:   orderedQuery,
+		validatedPaths: validatedPaths,
+		typesOnly:      typesOnly,
+		nested:         nested,
+		examples:       examples,
+		jsonNaming:     jsonNaming,
+		include:        splitPatterns(include),
+		exclude:        splitPatterns(exclude),
+	}
 
 	return g.Generate(design.Design)
 }
@@ -45,6 +145,7 @@ func Generate() (files []string, err error) {
 // Generate generats the client package and CLI.
 func (g *Generator) Generate(api *design.APIDefinition) (_ []string, err error) {
 	go utils.Catch(nil, func() { g.Cleanup() })
+	g.generatedEnums = make(map[string]bool)
 
 	defer func() {
 		if err != nil {
@@ -52,37 +153,80 @@ func (g *Generator) Generate(api *design.APIDefinition) (_ []string, err error)
 		}
 	}()
 
+	// Sort security schemes so that the generated signers are in a stable order
+	sort.Sort(bySchemeName(api.SecuritySchemes))
+
+	// Only keep the resources and actions selected by the include and exclude patterns
+	api, err = g.filterAPI(api)
+	if err != nil {
+		return
+	}
+	// Report all the parameters that cannot be generated at once
+	var errs dslengine.MultiError
+	errs = appendError(errs, addFieldsParams(api))
+	errs = appendError(errs, checkJSONParams(api))
+	if len(errs) > 0 {
+		err = errs
+		return
+	}
+
 	// Make tool directory
 	var toolDir string
-	toolDir, err = g.makeToolDir(api.Name)
+	toolDir, err = g.makeToolDir(api.Name, api.Version)
 	if err != nil {
 		return
 	}
 
 	// Setup generation
 	funcs := template.FuncMap{
-		"add":             func(a, b int) int { return a + b },
-		"cmdFieldType":    cmdFieldType,
-		"defaultPath":     defaultPath,
-		"escapeBackticks": escapeBackticks,
-		"flagType":        flagType,
-		"goify":           codegen.Goify,
-		"gotypedef":       codegen.GoTypeDef,
-		"gotypedesc":      codegen.GoTypeDesc,
-		"gotyperef":       codegen.GoTypeRef,
-		"gotypename":      codegen.GoTypeName,
-		"gotyperefext":    goTypeRefExt,
-		"join":            join,
-		"joinStrings":     strings.Join,
-		"multiComment":    multiComment,
-		"pathParams":      pathParams,
-		"pathParamNames":  pathParamNames,
-		"pathTemplate":    pathTemplate,
-		"tempvar":         codegen.Tempvar,
-		"title":           strings.Title,
-		"toString":        toString,
-		"typeName":        typeName,
-		"signerType":      signerType,
+		"add":               func(a, b int) int { return a + b },
+		"binaryPayload":     binaryPayload,
+		"cmdFieldType":      cmdFieldType,
+		"decodeHeader":      decodeHeader,
+		"defaultPath":       defaultPath,
+		"escapeBackticks":   escapeBackticks,
+		"flagType":          flagType,
+		"goify":             codegen.Goify,
+		"hasIdempotencyKey": g.hasIdempotencyKey,
+		"hasIfMatch":        g.hasIfMatch,
+		"hasPatchPointers":  g.hasPatchPointers,
+		"withEnums":         g.withEnums,
+		"viewMediaType":     viewMediaType,
+		"gotypedef":         codegen.GoTypeDef,
+		"gotypedesc":        codegen.GoTypeDesc,
+		"gotyperef":         codegen.GoTypeRef,
+		"gotypename":        codegen.GoTypeName,
+		"gotyperefext":      goTypeRefExt,
+		"payloadType":       payloadType,
+		"join":              join,
+		"joinStrings":       strings.Join,
+		"methodName":        methodName,
+		"multiComment":      multiComment,
+		"pathParams":        pathParams,
+		"pathValidation":    pathValidation,
+		"pathParamValues":   pathParamValues,
+		"pathTemplate":      pathTemplate,
+		"pathExpr":          pathExpr,
+		"tempvar":           codegen.Tempvar,
+		"title":             strings.Title,
+		"toString":          toString,
+		"typeName":          typeName,
+		"validation":        g.validation,
+		"signerType":        signerType,
+		"nonceScheme":       nonceScheme,
+		"signerFields":      signerFields,
+		"viewFields":        viewFields,
+	}
+	if g.omitEmpty {
+		funcs["gotypedef"] = codegen.GoTypeDefOmitEmpty
+	}
+	if g.jsonNaming != "" && g.jsonNaming != "asis" {
+		rename, ok := jsonNamings[g.jsonNaming]
+		if !ok {
+			err = fmt.Errorf("invalid JSON naming strategy %q, must be one of snake, camel or asis", g.jsonNaming)
+			return
+		}
+		funcs["gotypedef"] = renameJSONKeys(funcs["gotypedef"].(func(design.DataStructure, int, bool, bool) string), rename)
 	}
 	clientPkg, err := codegen.PackagePath(g.outDir)
 	if err != nil {
@@ -90,14 +234,24 @@ func (g *Generator) Generate(api *design.APIDefinition) (_ []string, err error)
 	}
 	arrayToStringTmpl = template.Must(template.New("client").Funcs(funcs).Parse(arrayToStringT))
 
-	// Generate client/client-cli/main.go
-	if err = g.generateMain(filepath.Join(toolDir, "main.go"), clientPkg, funcs, api); err != nil {
-		return
+	if g.typesOnly {
+		// Generate client/datatypes.go only
+		if err = g.generateTypes(funcs, api); err != nil {
+			return
+		}
+		return g.genfiles, nil
 	}
 
-	// Generate client/client-cli/commands.go
-	if err = g.generateCommands(filepath.Join(toolDir, "commands.go"), clientPkg, funcs, api); err != nil {
-		return
+	if !g.noCLI {
+		// Generate client/client-cli/main.go
+		if err = g.generateMain(filepath.Join(toolDir, "main.go"), clientPkg, funcs, api); err != nil {
+			return
+		}
+
+		// Generate client/client-cli/commands.go
+		if err = g.generateCommands(filepath.Join(toolDir, "commands.go"), clientPkg, funcs, api); err != nil {
+			return
+		}
 	}
 
 	// Generate client/client.go
@@ -113,6 +267,115 @@ func (g *Generator) Generate(api *design.APIDefinition) (_ []string, err error)
 	return g.genfiles, nil
 }
 
+// jsonNamings maps the JSON naming strategies to the functions computing the keys of the fields.
+var jsonNamings = map[string]func(string) string{
+	"snake": snakeJSONKey,
+	"camel": camelJSONKey,
+}
+
+// jsonTagRegex matches the beginning of the json struct tags up to the end of the key.
+var jsonTagRegex = regexp.MustCompile(`json:"[^",]*`)
+
+// renameJSONKeys returns a gotypedef function that rewrites the keys of the json tags of the
+// struct fields defined by def using rename. The keys set with the struct:field:json and
+// struct:tag:json metadata are rewritten as well.
+func renameJSONKeys(def func(design.DataStructure, int, bool, bool) string, rename func(string) string) func(design.DataStructure, int, bool, bool) string {
+	return func(ds design.DataStructure, tabs int, jsonTags, private bool) string {
+		return jsonTagRegex.ReplaceAllStringFunc(def(ds, tabs, jsonTags, private), func(tag string) string {
+			return `json:"` + rename(strings.TrimPrefix(tag, `json:"`))
+		})
+	}
+}
+
+// jsonKeyWords splits the given JSON key into lower case words, words are delimited by
+// underscores, dashes, spaces or changes of case.
+func jsonKeyWords(key string) []string {
+	return strings.FieldsFunc(codegen.SnakeCase(key), func(r rune) bool {
+		return r == '_' || r == '-' || r == ' '
+	})
+}
+
+// snakeJSONKey returns the snake_case version of key, e.g. "first_name" for "firstName".
+func snakeJSONKey(key string) string {
+	return strings.Join(jsonKeyWords(key), "_")
+}
+
+// camelJSONKey returns the camelCase version of key, e.g. "firstName" for "first_name".
+func camelJSONKey(key string) string {
+	words := jsonKeyWords(key)
+	for i := 1; i < len(words); i++ {
+		words[i] = strings.Title(words[i])
+	}
+	return strings.Join(words, "")
+}
+
+// splitPatterns returns the glob patterns listed in the comma separated value of the include or
+// exclude flag.
+func splitPatterns(val string) []string {
+	var patterns []string
+	for _, p := range strings.Split(val, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// filterAPI returns a copy of api that only contains the actions selected by the generator include
+// and exclude patterns. An action is selected if its resource name or its name prefixed with the
+// resource name and a dot (e.g. "bottle.show") matches one of the include patterns, or if there
+// are none, and matches none of the exclude patterns. Resources with no selected action are
+// removed. The user and media types referenced by the selected actions are generated as part of
+// the resource types so they do not need to be filtered.
+func (g *Generator) filterAPI(api *design.APIDefinition) (*design.APIDefinition, error) {
+	if len(g.include) == 0 && len(g.exclude) == 0 {
+		return api, nil
+	}
+	match := func(patterns []string, res, action string) (bool, error) {
+		for _, p := range patterns {
+			for _, name := range []string{res, res + "." + action} {
+				ok, err := path.Match(p, name)
+				if err != nil {
+					return false, fmt.Errorf("invalid pattern %q: %s", p, err)
+				}
+				if ok {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	}
+	filtered := *api
+	filtered.Resources = make(map[string]*design.ResourceDefinition)
+	for n, res := range api.Resources {
+		actions := make(map[string]*design.ActionDefinition)
+		for an, a := range res.Actions {
+			included := len(g.include) == 0
+			if !included {
+				ok, err := match(g.include, res.Name, a.Name)
+				if err != nil {
+					return nil, err
+				}
+				included = ok
+			}
+			excluded, err := match(g.exclude, res.Name, a.Name)
+			if err != nil {
+				return nil, err
+			}
+			if included && !excluded {
+				actions[an] = a
+			}
+		}
+		if len(actions) == 0 {
+			continue
+		}
+		r := *res
+		r.Actions = actions
+		filtered.Resources[n] = &r
+	}
+	return &filtered, nil
+}
+
 // Cleanup removes all the files generated by this generator during the last invokation of Generate.
 func (g *Generator) Cleanup() {
 	for _, f := range g.genfiles {
@@ -137,6 +400,7 @@ func (g *Generator) generateClient(clientFile string, clientPkg string, funcs te
 	if err != nil {
 		return err
 	}
+	g.decoders = decoders
 	im := make(map[string]bool)
 	for _, data := range encoders {
 		im[data.PackagePath] = true
@@ -154,45 +418,237 @@ func (g *Generator) generateClient(clientFile string, clientPkg string, funcs te
 
 	// Setup codegen
 	imports := []*codegen.ImportSpec{
+		codegen.SimpleImport("bufio"),
+		codegen.SimpleImport("bytes"),
+		codegen.SimpleImport("compress/flate"),
+		codegen.SimpleImport("compress/gzip"),
+		codegen.SimpleImport("compress/zlib"),
+		codegen.SimpleImport("crypto/tls"),
+		codegen.SimpleImport("encoding/json"),
+		codegen.SimpleImport("errors"),
+		codegen.SimpleImport("fmt"),
+		codegen.SimpleImport("io"),
+		codegen.SimpleImport("io/ioutil"),
+		codegen.SimpleImport("net"),
 		codegen.SimpleImport("net/http"),
+		codegen.SimpleImport("net/http/httputil"),
+		codegen.SimpleImport("net/url"),
+		codegen.SimpleImport("path/filepath"),
+		codegen.SimpleImport("strconv"),
+		codegen.SimpleImport("strings"),
+		codegen.SimpleImport("sync"),
+		codegen.SimpleImport("time"),
 		codegen.SimpleImport("github.com/goadesign/goa"),
 		codegen.NewImport("goaclient", "github.com/goadesign/goa/client"),
+		codegen.SimpleImport("github.com/goadesign/goa/middleware"),
+		codegen.SimpleImport("golang.org/x/net/context"),
 	}
 	for _, packagePath := range packagePaths {
 		imports = append(imports, codegen.SimpleImport(packagePath))
 	}
-	if err := file.WriteHeader("", "client", imports); err != nil {
+	if err := file.WriteHeader("", g.pkgName, imports); err != nil {
 		return err
 	}
 	g.genfiles = append(g.genfiles, clientFile)
 
 	// Generate
+	version := api.Version
+	if version == "" {
+		version = "0"
+	}
+	scheme := preferredScheme(api.Schemes, false)
 	data := struct {
 		API      *design.APIDefinition
+		Version  string
+		Scheme   string
 		Encoders []*genapp.EncoderTemplateData
 		Decoders []*genapp.EncoderTemplateData
 	}{
 		API:      api,
+		Version:  version,
+		Scheme:   scheme,
 		Encoders: encoders,
 		Decoders: decoders,
 	}
 	if err := clientTmpl.Execute(file, data); err != nil {
 		return err
 	}
+	contentTypesTmpl := template.Must(template.New("contentTypes").Parse(contentTypesTmpl))
+	if err := contentTypesTmpl.Execute(file, contentTypes(encoders, decoders)); err != nil {
+		return err
+	}
+	ping, err := g.newPingData(api)
+	if err != nil {
+		return err
+	}
+	if ping != nil {
+		pingTmpl := template.Must(template.New("ping").Funcs(funcs).Parse(pingTmpl))
+		if err := pingTmpl.Execute(file, ping); err != nil {
+			return err
+		}
+	}
 
 	return file.FormatCode()
 }
 
+// pingData is the data structure holding the information needed to generate the Ping method.
+type pingData struct {
+	Action     *design.ActionDefinition
+	MethodName string
+	Path       string
+	Args       string
+}
+
+// newPingData returns the data needed to generate the Ping method that calls the health check
+// action, that is the first action (in alphabetical order of resources and actions) with the
+// "healthcheck" metadata. newPingData returns nil if there is no such action and an error if the
+// action cannot be called without arguments: it must not be a websocket action, define path
+// wildcards, a payload or required parameters.
+func (g *Generator) newPingData(api *design.APIDefinition) (*pingData, error) {
+	var action *design.ActionDefinition
+	api.IterateResources(func(res *design.ResourceDefinition) error {
+		return res.IterateActions(func(a *design.ActionDefinition) error {
+			if _, ok := a.Metadata["healthcheck"]; ok && action == nil {
+				action = a
+			}
+			return nil
+		})
+	})
+	if action == nil {
+		return nil, nil
+	}
+	invalid := func(reason string) error {
+		return fmt.Errorf("%s action of %s: health check action %s", action.Name, action.Parent.Name, reason)
+	}
+	if action.WebSocket() {
+		return nil, invalid("cannot be a websocket action")
+	}
+	if action.Payload != nil {
+		return nil, invalid("cannot have a payload")
+	}
+	if len(action.Routes) == 0 {
+		return nil, invalid("must have a route")
+	}
+	path := action.Routes[0].FullPath()
+	if len(design.ExtractWildcards(path)) > 0 {
+		return nil, invalid("cannot have path wildcards")
+	}
+	var args []string
+	for _, att := range []*design.AttributeDefinition{action.QueryParams, action.Headers} {
+		if att == nil {
+			continue
+		}
+		for n := range att.Type.ToObject() {
+			if att.IsRequired(n) {
+				return nil, invalid(fmt.Sprintf("cannot have required parameter %s", n))
+			}
+			args = append(args, "nil")
+		}
+	}
+	if g.hasIdempotencyKey(action) {
+		args = append(args, `""`)
+	}
+	if g.hasIfMatch(action) {
+		args = append(args, `""`)
+	}
+	if viewMediaType(action) != nil {
+		args = append(args, `""`)
+	}
+	data := &pingData{
+		Action:     action,
+		MethodName: methodName(action),
+		Path:       path,
+	}
+	if len(args) > 0 {
+		data.Args = ", " + strings.Join(args, ", ")
+	}
+	return data, nil
+}
+
+// generateClientResources generates the client files of the API resources and the datatypes.go
+// and metrics.go files. The files of the resources are independent so that an error generating one of them does not
+// prevent the generation of the others, the errors are returned together once all the files are
+// generated.
 func (g *Generator) generateClientResources(clientPkg string, funcs template.FuncMap, api *design.APIDefinition) error {
-	userTypeTmpl := template.Must(template.New("userType").Funcs(funcs).Parse(userTypeTmpl))
-	typeDecodeTmpl := template.Must(template.New("typeDecode").Funcs(funcs).Parse(typeDecodeTmpl))
+	var errs dslengine.MultiError
+	api.IterateResources(func(res *design.ResourceDefinition) error {
+		errs = appendError(errs, g.generateResourceClient(res, funcs))
+		return nil
+	})
+	errs = appendError(errs, g.generateTypes(funcs, api))
+	errs = appendError(errs, g.generateMetrics(api))
+	if g.examples {
+		errs = appendError(errs, g.generateExamples())
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// appendError appends err to errs unless it is nil, the errors of err are appended if it is a
+// dslengine.MultiError.
+func appendError(errs dslengine.MultiError, err error) dslengine.MultiError {
+	if err == nil {
+		return errs
+	}
+	if merr, ok := err.(dslengine.MultiError); ok {
+		return append(errs, merr...)
+	}
+	return append(errs, &dslengine.Error{GoError: err})
+}
 
-	err := api.IterateResources(func(res *design.ResourceDefinition) error {
-		return g.generateResourceClient(res, funcs)
+// generateMetrics generates the metrics.go file containing the constants holding the names of the
+// actions passed to the client metrics and tracer.
+func (g *Generator) generateMetrics(api *design.APIDefinition) error {
+	var actions []*metricData
+	api.IterateResources(func(res *design.ResourceDefinition) error {
+		return res.IterateActions(func(action *design.ActionDefinition) error {
+			actions = append(actions, &metricData{
+				MethodName:   methodName(action),
+				Name:         action.Name,
+				ResourceName: res.Name,
+			})
+			return nil
+		})
 	})
+	if len(actions) == 0 {
+		return nil
+	}
+	filename := filepath.Join(g.outDir, metricsFileName+".go")
+	file, err := codegen.SourceFileFor(filename)
 	if err != nil {
 		return err
 	}
+	if err := file.WriteHeader("Metrics", g.pkgName, nil); err != nil {
+		return err
+	}
+	g.genfiles = append(g.genfiles, filename)
+	tmpl := template.Must(template.New("metrics").Parse(metricsTmpl))
+	if err := tmpl.Execute(file, actions); err != nil {
+		return err
+	}
+	return file.FormatCode()
+}
+
+// metricData holds the data needed to generate the metric name constant of an action.
+type metricData struct {
+	MethodName   string
+	Name         string
+	ResourceName string
+}
+
+// generateTypes generates the datatypes.go file containing the data structures of the user and
+// media types used by the API actions. The file also contains the helpers decoding the response
+// media types and following their links unless the generator only generates types, in which case
+// it contains the data structures of the action payloads instead as they are otherwise generated
+// together with the resource clients.
+func (g *Generator) generateTypes(funcs template.FuncMap, api *design.APIDefinition) error {
+	userTypeTmpl := template.Must(template.New("userType").Funcs(funcs).Parse(userTypeTmpl))
+	typeDecodeTmpl := template.Must(template.New("typeDecode").Funcs(funcs).Parse(typeDecodeTmpl))
+	tinyJSONTmpl := template.Must(template.New("tinyJSON").Funcs(funcs).Parse(tinyJSONTmpl))
+	followLinksTmpl := template.Must(template.New("followLinks").Funcs(funcs).Parse(followLinksTmpl))
+
 	types := make(map[string]*design.UserTypeDefinition)
 	for _, res := range api.Resources {
 		for n, ut := range res.UserTypes() {
@@ -206,17 +662,35 @@ func (g *Generator) generateClientResources(clientPkg string, funcs template.Fun
 	}
 	imports := []*codegen.ImportSpec{
 		codegen.SimpleImport("github.com/goadesign/goa"),
+		codegen.NewImport("goaclient", "github.com/goadesign/goa/client"),
+		codegen.SimpleImport("encoding/json"),
 		codegen.SimpleImport("fmt"),
 		codegen.SimpleImport("io"),
 		codegen.SimpleImport("net/http"),
 		codegen.SimpleImport("time"),
+		codegen.SimpleImport("unicode/utf8"),
 		codegen.NewImport("uuid", "github.com/satori/go.uuid"),
+		codegen.SimpleImport("golang.org/x/net/context"),
 	}
-	if err := file.WriteHeader("User Types", "client", imports); err != nil {
+	if err := file.WriteHeader("User Types", g.pkgName, imports); err != nil {
 		return err
 	}
 	g.genfiles = append(g.genfiles, filename)
 
+	if g.typesOnly {
+		// Generate the action payloads, the resource files are not generated
+		g.generatedTypes = make(map[string]bool)
+		payloadTmpl := template.Must(template.New("payload").Funcs(funcs).Parse(payloadTmpl))
+		err = api.IterateResources(func(res *design.ResourceDefinition) error {
+			return res.IterateActions(func(action *design.ActionDefinition) error {
+				return g.generatePayload(file, payloadTmpl, action)
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	// Generate user and media types used by action payloads and parameters
 	err = api.IterateUserTypes(func(userType *design.UserTypeDefinition) error {
 		if _, ok := g.generatedTypes[userType.TypeName]; ok {
@@ -233,6 +707,7 @@ func (g *Generator) generateClientResources(clientPkg string, funcs template.Fun
 	}
 
 	// Generate media types used by action responses and their load helpers
+	var viewed []*design.MediaTypeDefinition
 	err = api.IterateResources(func(res *design.ResourceDefinition) error {
 		return res.IterateActions(func(a *design.ActionDefinition) error {
 			return a.IterateResponses(func(r *design.ResponseDefinition) error {
@@ -240,14 +715,16 @@ func (g *Generator) generateClientResources(clientPkg string, funcs template.Fun
 					if _, ok := g.generatedTypes[mt.TypeName]; !ok {
 						g.generatedTypes[mt.TypeName] = true
 						if !mt.IsBuiltIn() {
-							if err := userTypeTmpl.Execute(file, mt); err != nil {
+							if err := g.generateMediaType(file, userTypeTmpl, followLinksTmpl, mt); err != nil {
+								return err
+							}
+							if err := g.generateTinyJSON(file, tinyJSONTmpl, mt); err != nil {
 								return err
 							}
+							viewed = append(viewed, mt)
 						}
-						typeName := mt.TypeName
-						if mt.IsBuiltIn() {
-							elems := strings.Split(typeName, ".")
-							typeName = elems[len(elems)-1]
+						if g.typesOnly {
+							return nil
 						}
 						if err := typeDecodeTmpl.Execute(file, mt); err != nil {
 							return err
@@ -272,7 +749,10 @@ func (g *Generator) generateClientResources(clientPkg string, funcs template.Fun
 		}
 		if _, ok := types[mediaType.TypeName]; ok {
 			g.generatedTypes[mediaType.TypeName] = true
-			return userTypeTmpl.Execute(file, mediaType)
+			if err := g.generateMediaType(file, userTypeTmpl, followLinksTmpl, mediaType); err != nil {
+				return err
+			}
+			return g.generateTinyJSON(file, tinyJSONTmpl, mediaType)
 		}
 		return nil
 	})
@@ -280,16 +760,343 @@ func (g *Generator) generateClientResources(clientPkg string, funcs template.Fun
 		return err
 	}
 
+	// Generate the view projections of the response media types last so that the types they
+	// refer to that are generated above keep their load helpers
+	for _, mt := range viewed {
+		if err := g.generateViews(file, userTypeTmpl, typeDecodeTmpl, mt); err != nil {
+			return err
+		}
+	}
+
 	return file.FormatCode()
 }
 
+// generateViews generates the data structures of the projections of the given media type on its
+// views other than the default view together with the helpers decoding them, e.g. BottleTiny and
+// DecodeBottleTiny for the tiny view of the Bottle media type. The types referred to by the
+// projections that are not generated yet, such as the link views of related media types, are
+// generated as well.
+func (g *Generator) generateViews(file *codegen.SourceFile, userTypeTmpl, typeDecodeTmpl *template.Template, mt *design.MediaTypeDefinition) error {
+	return mt.IterateViews(func(view *design.ViewDefinition) error {
+		if view.Name == "default" {
+			return nil
+		}
+		p, _, err := mt.Project(view.Name)
+		if err != nil {
+			return err
+		}
+		if p == mt || g.generatedTypes[p.TypeName] {
+			return nil
+		}
+		g.generatedTypes[p.TypeName] = true
+		if err := userTypeTmpl.Execute(file, p); err != nil {
+			return err
+		}
+		if err := generateCollection(file, p); err != nil {
+			return err
+		}
+		err = p.WalkErr(func(att *design.AttributeDefinition) error {
+			var ut *design.UserTypeDefinition
+			switch t := att.Type.(type) {
+			case *design.UserTypeDefinition:
+				ut = t
+			case *design.MediaTypeDefinition:
+				if t.IsBuiltIn() {
+					return nil
+				}
+				ut = t.UserTypeDefinition
+			default:
+				return nil
+			}
+			if g.generatedTypes[ut.TypeName] {
+				return nil
+			}
+			g.generatedTypes[ut.TypeName] = true
+			return userTypeTmpl.Execute(file, att.Type)
+		})
+		if err != nil {
+			return err
+		}
+		if g.typesOnly {
+			return nil
+		}
+		return typeDecodeTmpl.Execute(file, p)
+	})
+}
+
+// generateMediaType generates the data structure of a media type. The data structure of a media
+// type that defines links has a Links field, the links data structure is generated together with
+// the link types and methods that follow the links. The data structure of a collection media type
+// is generated together with the helpers converting it to a slice and back.
+func (g *Generator) generateMediaType(file *codegen.SourceFile, userTypeTmpl, followLinksTmpl *template.Template, mt *design.MediaTypeDefinition) error {
+	links, err := mediaTypeLinks(mt)
+	if err != nil {
+		return err
+	}
+	if links == nil {
+		if err := userTypeTmpl.Execute(file, mt); err != nil {
+			return err
+		}
+		return generateCollection(file, mt)
+	}
+	obj := make(design.Object)
+	for n, att := range mt.Type.ToObject() {
+		obj[n] = att
+	}
+	obj["links"] = &design.AttributeDefinition{Type: links, Description: "Links to related resources"}
+	att := *mt.AttributeDefinition
+	att.Type = obj
+	ut := *mt.UserTypeDefinition
+	ut.AttributeDefinition = &att
+	withLinks := *mt
+	withLinks.UserTypeDefinition = &ut
+	if err := userTypeTmpl.Execute(file, &withLinks); err != nil {
+		return err
+	}
+	if g.generatedTypes[links.TypeName] {
+		return nil
+	}
+	g.generatedTypes[links.TypeName] = true
+	if err := userTypeTmpl.Execute(file, links); err != nil {
+		return err
+	}
+	linksObj := links.Type.ToObject()
+	var follows []*followData
+	for _, n := range sortedAttributeNames(linksObj) {
+		link, ok := linksObj[n].Type.(*design.MediaTypeDefinition)
+		if !ok {
+			continue
+		}
+		if !g.generatedTypes[link.TypeName] {
+			g.generatedTypes[link.TypeName] = true
+			if err := userTypeTmpl.Execute(file, link); err != nil {
+				return err
+			}
+		}
+		if href := link.Type.ToObject()["href"]; href == nil || href.Type.Kind() != design.StringKind {
+			continue
+		}
+		follows = append(follows, &followData{
+			Name:    n,
+			Field:   codegen.Goify(n, true),
+			Pointer: link.IsPrimitivePointer("href"),
+		})
+	}
+	if g.typesOnly {
+		return nil
+	}
+	data := struct {
+		Links   *design.UserTypeDefinition
+		Follows []*followData
+	}{
+		Links:   links,
+		Follows: follows,
+	}
+	return followLinksTmpl.Execute(file, data)
+}
+
+// followData is the data structure holding the information needed to generate the method that
+// follows a link.
+type followData struct {
+	Name    string
+	Field   string
+	Pointer bool
+}
+
+// enumsData is the data structure holding a user or media type whose string attributes that
+// enumerate their values use named types, together with the enum types to generate.
+type enumsData struct {
+	Type  design.DataType
+	Enums []*enumData
+}
+
+// enumData is the data structure holding the information needed to generate the named string
+// type and constants of an enum attribute.
+type enumData struct {
+	TypeName  string
+	Attribute string
+	Parent    string
+	Values    []*enumValueData
+}
+
+// enumValueData is the data structure holding the name and value of an enum constant.
+type enumValueData struct {
+	Name  string
+	Value string
+}
+
+// withEnums returns a copy of the given user or media type where the string attributes that
+// enumerate their values use a named string type. The name of the type is the user type name
+// followed by the attribute name, e.g. "BottleColor". The returned data also lists the named
+// types that have not been generated yet.
+func (g *Generator) withEnums(ut design.DataType) *enumsData {
+	data := &enumsData{Type: ut}
+	var def *design.AttributeDefinition
+	var typeName string
+	switch actual := ut.(type) {
+	case *design.UserTypeDefinition:
+		def, typeName = actual.AttributeDefinition, actual.TypeName
+	case *design.MediaTypeDefinition:
+		def, typeName = actual.AttributeDefinition, actual.TypeName
+	default:
+		return data
+	}
+	if !def.Type.IsObject() {
+		return data
+	}
+	obj := def.Type.ToObject()
+	var enumObj design.Object
+	for _, n := range sortedAttributeNames(obj) {
+		att := obj[n]
+		values := enumValues(att)
+		if values == nil {
+			continue
+		}
+		if enumObj == nil {
+			enumObj = make(design.Object, len(obj))
+			for n, att := range obj {
+				enumObj[n] = att
+			}
+		}
+		enum := &enumData{
+			TypeName:  codegen.Goify(typeName, true) + codegen.Goify(n, true),
+			Attribute: n,
+			Parent:    typeName,
+		}
+		names := make(map[string]bool, len(values))
+		for _, v := range values {
+			name := enum.TypeName + codegen.Goify(v, true)
+			if v == "" || codegen.Goify(v, true) == "" {
+				name = enum.TypeName + "Empty"
+			}
+			for i := 2; names[name]; i++ {
+				name = fmt.Sprintf("%s%s%d", enum.TypeName, codegen.Goify(v, true), i)
+			}
+			names[name] = true
+			enum.Values = append(enum.Values, &enumValueData{Name: name, Value: v})
+		}
+		enumAtt := *att
+		enumAtt.Type = &design.UserTypeDefinition{
+			AttributeDefinition: &design.AttributeDefinition{Type: design.String},
+			TypeName:            enum.TypeName,
+		}
+		enumObj[n] = &enumAtt
+		if !g.generatedEnums[enum.TypeName] {
+			g.generatedEnums[enum.TypeName] = true
+			data.Enums = append(data.Enums, enum)
+		}
+	}
+	if enumObj == nil {
+		return data
+	}
+	att := *def
+	att.Type = enumObj
+	switch actual := ut.(type) {
+	case *design.UserTypeDefinition:
+		cp := *actual
+		cp.AttributeDefinition = &att
+		data.Type = &cp
+	case *design.MediaTypeDefinition:
+		u := *actual.UserTypeDefinition
+		u.AttributeDefinition = &att
+		cp := *actual
+		cp.UserTypeDefinition = &u
+		data.Type = &cp
+	}
+	return data
+}
+
+// enumValues returns the values of the given string attribute if it enumerates them, nil otherwise.
+func enumValues(att *design.AttributeDefinition) []string {
+	if att.Type != design.String || att.Validation == nil || len(att.Validation.Values) == 0 {
+		return nil
+	}
+	values := make([]string, len(att.Validation.Values))
+	for i, v := range att.Validation.Values {
+		s, ok := v.(string)
+		if !ok {
+			return nil
+		}
+		values[i] = s
+	}
+	return values
+}
+
+// sortedAttributeNames returns the names of the attributes of obj in alphabetical order.
+func sortedAttributeNames(obj design.Object) []string {
+	names := make([]string, 0, len(obj))
+	for n := range obj {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// mediaTypeLinks returns the data structure holding the links of the given media type, nil if the
+// media type does not define links or none of its views render them.
+func mediaTypeLinks(mt *design.MediaTypeDefinition) (*design.UserTypeDefinition, error) {
+	if len(mt.Links) == 0 || !mt.IsObject() {
+		return nil, nil
+	}
+	var links *design.UserTypeDefinition
+	err := mt.IterateViews(func(view *design.ViewDefinition) error {
+		_, l, err := mt.Project(view.Name)
+		if links == nil {
+			links = l
+		}
+		return err
+	})
+	return links, err
+}
+
+// generateCollection generates the helpers converting the data structure of the given media type
+// to the slice of its elements and back if it is a collection, e.g. NewBottleCollection and
+// BottleCollection.Slice.
+func generateCollection(file *codegen.SourceFile, mt *design.MediaTypeDefinition) error {
+	if !mt.IsArray() {
+		return nil
+	}
+	data := map[string]string{
+		"TypeName":  codegen.GoTypeName(mt, mt.AllRequired(), 0, false),
+		"SliceType": codegen.GoTypeName(mt.Type, nil, 0, false),
+	}
+	tmpl := template.Must(template.New("collection").Parse(collectionTmpl))
+	return tmpl.Execute(file, data)
+}
+
+// generateTinyJSON generates the MarshalTinyJSON method for media types that define a "tiny" view.
+func (g *Generator) generateTinyJSON(file *codegen.SourceFile, tmpl *template.Template, mt *design.MediaTypeDefinition) error {
+	if _, ok := mt.Views["tiny"]; !ok || !mt.IsObject() {
+		return nil
+	}
+	return tmpl.Execute(file, mt)
+}
+
+// generatePayload generates the data structure of the payload of the given action if any.
+func (g *Generator) generatePayload(file *codegen.SourceFile, payloadTmpl *template.Template, action *design.ActionDefinition) error {
+	if action.Payload == nil || binaryPayload(action) || arrayPayload(action) {
+		return nil
+	}
+	if g.hasPatchPointers(action) {
+		action.Payload = optionalPayload(action.Payload)
+	}
+	if err := payloadTmpl.Execute(file, action); err != nil {
+		return err
+	}
+	g.generatedTypes[action.Payload.TypeName] = true
+	return nil
+}
+
 func (g *Generator) generateResourceClient(res *design.ResourceDefinition, funcs template.FuncMap) error {
 	payloadTmpl := template.Must(template.New("payload").Funcs(funcs).Parse(payloadTmpl))
+	basePathTmpl := template.Must(template.New("basePath").Parse(basePathTmpl))
 	pathTmpl := template.Must(template.New("pathTemplate").Funcs(funcs).Parse(pathTmpl))
+	parsePathTmpl := template.Must(template.New("parsePath").Funcs(funcs).Parse(parsePathTmpl))
+	respHeadersTmpl := template.Must(template.New("responseHeaders").Funcs(funcs).Parse(responseHeadersTmpl))
 
 	resFilename := codegen.SnakeCase(res.Name)
-	if resFilename == typesFileName {
-		// Avoid clash with datatypes.go
+	if resFilename == typesFileName || resFilename == metricsFileName {
+		// Avoid clash with datatypes.go and metrics.go
 		resFilename += "_client"
 	}
 	filename := filepath.Join(g.outDir, resFilename+".go")
@@ -298,59 +1105,85 @@ func (g *Generator) generateResourceClient(res *design.ResourceDefinition, funcs
 		return err
 	}
 	imports := []*codegen.ImportSpec{
+		codegen.SimpleImport("bufio"),
 		codegen.SimpleImport("bytes"),
+		codegen.SimpleImport("crypto/md5"),
+		codegen.SimpleImport("encoding/base64"),
 		codegen.SimpleImport("encoding/json"),
 		codegen.SimpleImport("fmt"),
 		codegen.SimpleImport("io"),
+		codegen.SimpleImport("io/ioutil"),
+		codegen.SimpleImport("mime"),
 		codegen.SimpleImport("net/http"),
 		codegen.SimpleImport("net/url"),
+		codegen.SimpleImport("regexp"),
 		codegen.SimpleImport("strconv"),
 		codegen.SimpleImport("strings"),
 		codegen.SimpleImport("time"),
 		codegen.SimpleImport("golang.org/x/net/context"),
 		codegen.SimpleImport("golang.org/x/net/websocket"),
 		codegen.NewImport("uuid", "github.com/satori/go.uuid"),
+		codegen.SimpleImport("github.com/goadesign/goa"),
+		codegen.NewImport("goaclient", "github.com/goadesign/goa/client"),
+		codegen.SimpleImport("github.com/goadesign/goa/middleware"),
 	}
-	if err := file.WriteHeader("", "client", imports); err != nil {
+	if err := file.WriteHeader("", g.pkgName, imports); err != nil {
 		return err
 	}
 	g.genfiles = append(g.genfiles, filename)
 	g.generatedTypes = make(map[string]bool)
-	err = res.IterateActions(func(action *design.ActionDefinition) error {
-		if action.Payload != nil {
-			if err := payloadTmpl.Execute(file, action); err != nil {
-				return err
-			}
-			g.generatedTypes[action.Payload.TypeName] = true
+	if g.grouped {
+		groupTmpl := template.Must(template.New("group").Funcs(funcs).Parse(groupTmpl))
+		if err := groupTmpl.Execute(file, newGroupData(res)); err != nil {
+			return err
 		}
-		if action.Params != nil {
-			params := make(design.Object, len(action.QueryParams.Type.ToObject()))
+	}
+	if base := resourceBasePath(res); base != "" {
+		data := map[string]string{
+			"Name":     basePathName(res),
+			"Resource": res.Name,
+			"Path":     base,
+		}
+		if err := basePathTmpl.Execute(file, data); err != nil {
+			return err
+		}
+	}
+	err = res.IterateActions(func(action *design.ActionDefinition) error {
+		if err := g.generatePayload(file, payloadTmpl, action); err != nil {
+			return err
+		}
+		if action.Params != nil {
+			params := make(design.Object, len(action.QueryParams.Type.ToObject()))
 			for n, param := range action.QueryParams.Type.ToObject() {
 				name := codegen.Goify(n, false)
 				params[name] = param
 			}
 			action.QueryParams.Type = params
 		}
-		if action.Headers != nil {
-			headers := make(design.Object, len(action.Headers.Type.ToObject()))
-			for n, header := range action.Headers.Type.ToObject() {
-				name := codegen.Goify(n, false)
-				headers[name] = header
-			}
-			action.Headers.Type = headers
-		}
 		for i, r := range action.Routes {
 			data := struct {
-				Route *design.RouteDefinition
-				Index int
+				Route     *design.RouteDefinition
+				Index     int
+				Validated bool
 			}{
-				Route: r,
-				Index: i,
+				Route:     r,
+				Index:     i,
+				Validated: g.validatedPaths,
 			}
 			if err := pathTmpl.Execute(file, data); err != nil {
 				return err
 			}
 		}
+		if data := newParsePathData(action); data != nil {
+			if err := parsePathTmpl.Execute(file, data); err != nil {
+				return err
+			}
+		}
+		if data := newResponseHeadersData(action); data != nil {
+			if err := respHeadersTmpl.Execute(file, data); err != nil {
+				return err
+			}
+		}
 		return g.generateActionClient(action, file, funcs)
 	})
 	if err != nil {
@@ -366,13 +1199,34 @@ func (g *Generator) generateActionClient(action *design.ActionDefinition, file *
 		names         []string
 		queryParams   []*paramData
 		headers       []*paramData
-		signer        string
+		signers       []string
+		tokenSigners  = make(map[string]bool)
+		headerSigners = make(map[string]bool)
+		nonce         bool
 		clientsTmpl   = template.Must(template.New("clients").Funcs(funcs).Parse(clientsTmpl))
+		streamTmpl    = template.Must(template.New("stream").Funcs(funcs).Parse(streamTmpl))
 		requestsTmpl  = template.Must(template.New("requests").Funcs(funcs).Parse(requestsTmpl))
 		clientsWSTmpl = template.Must(template.New("clientsws").Funcs(funcs).Parse(clientsWSTmpl))
+		groupedTmpl   = template.Must(template.New("grouped").Funcs(funcs).Parse(groupedTmpl))
+		nestedTmpl    = template.Must(template.New("nested").Funcs(funcs).Parse(nestedTmpl))
+		wsWrapperTmpl = template.Must(template.New("wswrapper").Funcs(funcs).Parse(wsWrapperTmpl))
+		sseTmpl       = template.Must(template.New("sse").Funcs(funcs).Parse(sseTmpl))
+		ndjsonTmpl    = template.Must(template.New("ndjson").Funcs(funcs).Parse(ndjsonTmpl))
+		multipartTmpl = template.Must(template.New("multipart").Parse(multipartTmpl))
+		resultTmpl    = template.Must(template.New("result").Funcs(funcs).Parse(resultTmpl))
+		headTmpl      = template.Must(template.New("head").Parse(headTmpl))
+		cursorTmpl    = template.Must(template.New("cursor").Parse(cursorTmpl))
+		pageTmpl      = template.Must(template.New("page").Parse(pageTmpl))
+		chunkedTmpl   = template.Must(template.New("chunked").Funcs(funcs).Parse(chunkedTmpl))
+		fromMapTmpl   = template.Must(template.New("frommap").Parse(fromMapTmpl))
+		queryTmpl     = template.Must(template.New("query").Funcs(funcs).Parse(queryTmpl))
 	)
-	if action.Payload != nil {
-		params = append(params, "payload "+codegen.GoTypeRef(action.Payload, action.Payload.AllRequired(), 1, false))
+	binary := binaryPayload(action)
+	if binary {
+		params = append(params, "body io.Reader")
+		names = append(names, "body")
+	} else if action.Payload != nil {
+		params = append(params, "payload "+codegen.GoTypeRef(payloadType(action), action.Payload.AllRequired(), 1, false))
 		names = append(names, "payload")
 	}
 	initParams := func(att *design.AttributeDefinition) []*paramData {
@@ -382,8 +1236,6 @@ func (g *Generator) generateActionClient(action *design.ActionDefinition, file *
 		obj := att.Type.ToObject()
 		var pdata []*paramData
 		var optData []*paramData
-		var pnames, pparams []string
-		var optNames, optParams []string
 		for n, q := range obj {
 			varName := codegen.Goify(n, false)
 			param := &paramData{
@@ -394,423 +1246,4006 @@ func (g *Generator) generateActionClient(action *design.ActionDefinition, file *
 			if q.Type.IsPrimitive() {
 				param.MustToString = q.Type.Kind() != design.StringKind
 				if att.IsRequired(n) {
+					param.Required = true
 					param.ValueName = varName
+					param.TypeName = cmdFieldType(q.Type, false)
 					pdata = append(pdata, param)
-					pparams = append(pparams, varName+" "+cmdFieldType(q.Type, false))
-					pnames = append(pnames, varName)
 				} else {
 					param.ValueName = "*" + varName
 					param.CheckNil = true
+					param.TypeName = cmdFieldType(q.Type, true)
 					optData = append(optData, param)
-					optParams = append(optParams, varName+" "+cmdFieldType(q.Type, true))
-					optNames = append(optNames, varName)
 				}
 			} else {
 				param.MustToString = true
 				param.ValueName = varName
 				param.CheckNil = true
+				param.TypeName = cmdFieldType(q.Type, false)
 				if att.IsRequired(n) {
+					param.Required = true
 					pdata = append(pdata, param)
-					pparams = append(params, varName+" "+cmdFieldType(q.Type, false))
-					pnames = append(pnames, varName)
 				} else {
 					optData = append(optData, param)
-					optParams = append(optParams, varName+" "+cmdFieldType(q.Type, false))
-					optNames = append(optNames, varName)
 				}
 			}
 		}
-		sort.Strings(pparams)
-		sort.Strings(optParams)
-		sort.Strings(pnames)
-		sort.Strings(optNames)
-
-		// Update closure
-		names = append(names, pnames...)
-		names = append(names, optNames...)
-		params = append(params, pparams...)
-		params = append(params, optParams...)
-
 		sort.Sort(byParamName(pdata))
 		sort.Sort(byParamName(optData))
-		return append(pdata, optData...)
+		pdata = append(pdata, optData...)
+
+		// Update closure, params and names must be built from the same ordered list so that
+		// they stay aligned.
+		for _, param := range pdata {
+			params = append(params, param.VarName+" "+param.TypeName)
+			names = append(names, param.VarName)
+		}
+		return pdata
 	}
 	queryParams = initParams(action.QueryParams)
+	if g.queryStructs && len(queryParams) > 0 {
+		// Replace the query parameter arguments with a single argument holding them all.
+		n := len(params) - len(queryParams)
+		params = append(params[:n], "query "+queryTypeName(action))
+		names = append(names[:n], "query")
+		for _, param := range queryParams {
+			param.VarName = "query." + codegen.Goify(param.Name, true)
+			if strings.HasPrefix(param.ValueName, "*") {
+				param.ValueName = "*" + param.VarName
+			} else {
+				param.ValueName = param.VarName
+			}
+		}
+		data := &queryData{TypeName: queryTypeName(action), Action: action, Params: queryParams}
+		if err := queryTmpl.Execute(file, data); err != nil {
+			return err
+		}
+	}
 	headers = initParams(action.Headers)
+	idempotencyKey := g.hasIdempotencyKey(action)
+	if idempotencyKey {
+		params = append(params, "idempotencyKey string")
+		names = append(names, "idempotencyKey")
+	}
+	ifMatch := g.hasIfMatch(action)
+	if ifMatch {
+		params = append(params, "ifMatch string")
+		names = append(names, "ifMatch")
+	}
+	var viewIdentifier string
+	if mt := viewMediaType(action); mt != nil {
+		viewIdentifier = mt.Identifier
+		params = append(params, "view string")
+		names = append(names, "view")
+	}
 	if action.Security != nil {
-		signer = codegen.Goify(action.Security.Scheme.SchemeName, true)
+		schemes := []*design.SecuritySchemeDefinition{action.Security.Scheme}
+		for _, sec := range action.Security.Additional {
+			schemes = append(schemes, sec.Scheme)
+		}
+		for _, scheme := range schemes {
+			name := codegen.Goify(scheme.SchemeName, true)
+			signers = append(signers, name)
+			if scheme.Kind != design.BasicAuthSecurityKind && !awsV4Scheme(scheme) {
+				tokenSigners[name] = true
+			}
+			if scheme.Kind != design.APIKeySecurityKind || scheme.In != "query" {
+				headerSigners[name] = true
+			}
+			if nonceScheme(scheme) {
+				nonce = true
+			}
+		}
+	}
+	formFields, err := initFormFields(action)
+	if err != nil {
+		return err
+	}
+	timeout, err := timeoutLiteral(action)
+	if err != nil {
+		return err
+	}
+	sseMT := sseMediaType(action)
+	ndjsonMT := ndjsonMediaType(action)
+	multipartType := multipartMediaType(action)
+	cursor, err := newCursorData(action, queryParams)
+	if err != nil {
+		return err
+	}
+	if g.orderedQuery {
+		queryParams = orderQueryParams(action, queryParams)
 	}
 	data := struct {
-		Name            string
-		ResourceName    string
-		Description     string
-		Routes          []*design.RouteDefinition
-		HasPayload      bool
-		Params          string
-		ParamNames      string
-		CanonicalScheme string
-		Signer          string
-		QueryParams     []*paramData
-		Headers         []*paramData
+		Name           string
+		MethodName     string
+		ResourceName   string
+		Description    string
+		Routes         []*design.RouteDefinition
+		RoutesVar      string
+		HasPayload     bool
+		Params         string
+		ParamNames     string
+		DefaultScheme  string
+		Signers        []string
+		TokenSigners   map[string]bool
+		HeaderSigners  map[string]bool
+		Nonce          bool
+		QueryParams    []*paramData
+		OrderedQuery   bool
+		Headers        []*paramData
+		IdempotencyKey bool
+		IfMatch        bool
+		ViewIdentifier string
+		EventStream    bool
+		JSONLines      bool
+		Multipart      string
+		ContentMD5     bool
+		OptionalBody   bool
+		Binary         bool
+		ContentType    string
+		FormFields     []*paramData
+		Raw            bool
+		RawParams      string
+		RawParamNames  string
+		Timeout        string
+		Accept         string
+		PayloadType    string
+		Shared         bool
 	}{
-		Name:            action.Name,
-		ResourceName:    action.Parent.Name,
-		Description:     action.Description,
-		Routes:          action.Routes,
-		HasPayload:      action.Payload != nil,
-		Params:          strings.Join(params, ", "),
-		ParamNames:      strings.Join(names, ", "),
-		CanonicalScheme: action.CanonicalScheme(),
-		Signer:          signer,
-		QueryParams:     queryParams,
-		Headers:         headers,
+		Name:           action.Name,
+		MethodName:     methodName(action),
+		ResourceName:   action.Parent.Name,
+		Description:    action.Description,
+		Routes:         action.Routes,
+		RoutesVar:      routesVar(action),
+		HasPayload:     action.Payload != nil,
+		Params:         strings.Join(params, ", "),
+		ParamNames:     strings.Join(names, ", "),
+		DefaultScheme:  preferredScheme(action.EffectiveSchemes(), action.WebSocket()),
+		Signers:        signers,
+		TokenSigners:   tokenSigners,
+		HeaderSigners:  headerSigners,
+		Nonce:          nonce,
+		QueryParams:    queryParams,
+		OrderedQuery:   g.orderedQuery,
+		Headers:        headers,
+		IdempotencyKey: idempotencyKey,
+		IfMatch:        ifMatch,
+		ViewIdentifier: viewIdentifier,
+		EventStream:    sseMT != nil,
+		JSONLines:      ndjsonMT != nil,
+		Multipart:      multipartType,
+		ContentMD5:     g.contentMD5 && action.Payload != nil && !binary,
+		OptionalBody:   action.Payload != nil && !binary && formFields == nil && !action.Payload.IsPrimitive() && hasBodylessRoute(action),
+		Binary:         binary,
+		ContentType:    requestContentType(action),
+		FormFields:     formFields,
+		Timeout:        timeout,
+		Shared:         sseMT == nil && ndjsonMT == nil && !streamsResponse(action),
+	}
+	if sseMT == nil && ndjsonMT == nil && multipartType == "" && !streamsResponse(action) {
+		data.Accept = acceptHeader(g.decoders)
+	}
+	if binary && data.ContentType == "*/*" {
+		data.ContentType = "application/octet-stream"
+	}
+	if action.Payload != nil {
+		data.PayloadType = codegen.GoTypeRef(payloadType(action), action.Payload.AllRequired(), 1, false)
+		data.RawParams = strings.Join(params[1:], ", ")
+		data.RawParamNames = strings.Join(names[1:], ", ")
+	}
+	if g.grouped {
+		groupedData := struct {
+			Action     interface{}
+			Name       string
+			MethodName string
+			GroupType  string
+			WebSocket  bool
+		}{
+			Action:     data,
+			Name:       action.Name,
+			MethodName: codegen.Goify(action.Name, true),
+			GroupType:  newGroupData(action.Parent).TypeName,
+			WebSocket:  action.WebSocket(),
+		}
+		if err := groupedTmpl.Execute(file, groupedData); err != nil {
+			return err
+		}
+	}
+	if g.nested {
+		if nestedData := newNestedData(action, data, names); nestedData != nil {
+			nestedData.Validated = g.validatedPaths
+			if err := nestedTmpl.Execute(file, nestedData); err != nil {
+				return err
+			}
+		}
 	}
 	if action.WebSocket() {
-		return clientsWSTmpl.Execute(file, data)
+		codegen.TempCount = 0
+		if err := clientsWSTmpl.Execute(file, data); err != nil {
+			return err
+		}
+		if !g.wsWrappers {
+			return nil
+		}
+		mt := wsMediaType(action)
+		if mt == nil {
+			return nil
+		}
+		wsData := struct {
+			Action    interface{}
+			MediaType *design.MediaTypeDefinition
+		}{
+			Action:    data,
+			MediaType: mt,
+		}
+		return wsWrapperTmpl.Execute(file, wsData)
 	}
 	if err := clientsTmpl.Execute(file, data); err != nil {
 		return err
 	}
-	return requestsTmpl.Execute(file, data)
-}
-
-// join is a code generation helper function that generates a function signature built from
-// concatenating the properties (name type) of the given attribute type (assuming it's an object).
-// join accepts an optional slice of strings which indicates the order in which the parameters
-// should appear in the signature. If pos is specified then it must list all the parameters. If
-// it's not specified then parameters are sorted alphabetically.
-func join(att *design.AttributeDefinition, usePointers bool, pos ...[]string) string {
-	if att == nil {
-		return ""
+	if g.examples {
+		attrs := make(map[string]*design.AttributeDefinition)
+		for _, param := range append(queryParams, headers...) {
+			attrs[param.VarName] = param.Attribute
+		}
+		g.exampleCalls = append(g.exampleCalls, g.newExampleData(action, params, names, attrs))
 	}
-	obj := att.Type.ToObject()
-	elems := make([]string, len(obj))
-	var keys []string
-	if len(pos) > 0 {
-		keys = pos[0]
-		if len(keys) != len(obj) {
-			panic("invalid position slice, lenght does not match attribute field count") // bug
+	if headAction(action) {
+		if headers := newResponseHeadersData(action); headers != nil {
+			headData := struct {
+				Action interface{}
+			}{
+				Action: data,
+			}
+			if err := headTmpl.Execute(file, headData); err != nil {
+				return err
+			}
 		}
-	} else {
-		keys = make([]string, len(obj))
-		i := 0
-		for n := range obj {
-			keys[i] = n
-			i++
+	} else if results := resultResponses(action); len(results) > 0 && sseMT == nil && ndjsonMT == nil && !streamsResponse(action) {
+		resultData := struct {
+			Action    interface{}
+			Responses []*resultResponseData
+		}{
+			Action:    data,
+			Responses: results,
+		}
+		if err := resultTmpl.Execute(file, resultData); err != nil {
+			return err
 		}
-		sort.Strings(keys)
 	}
-	for i, n := range keys {
-		a := obj[n]
-		elems[i] = fmt.Sprintf("%s %s", codegen.Goify(n, false), cmdFieldType(a.Type, usePointers && !a.IsRequired(n)))
+	if cursor != nil {
+		cursor.Action = data
+		if err := cursorTmpl.Execute(file, cursor); err != nil {
+			return err
+		}
 	}
-	return strings.Join(elems, ", ")
+	if page := newPageData(action); page != nil {
+		page.Action = data
+		if err := pageTmpl.Execute(file, page); err != nil {
+			return err
+		}
+	}
+	if streamsResponse(action) {
+		if err := streamTmpl.Execute(file, data); err != nil {
+			return err
+		}
+	}
+	if sseMT != nil {
+		sseData := struct {
+			Action    interface{}
+			MediaType *design.MediaTypeDefinition
+		}{
+			Action:    data,
+			MediaType: sseMT,
+		}
+		if err := sseTmpl.Execute(file, sseData); err != nil {
+			return err
+		}
+	}
+	if ndjsonMT != nil {
+		ndjsonData := struct {
+			Action    interface{}
+			MediaType *design.MediaTypeDefinition
+		}{
+			Action:    data,
+			MediaType: ndjsonMT,
+		}
+		if err := ndjsonTmpl.Execute(file, ndjsonData); err != nil {
+			return err
+		}
+	}
+	if multipartType != "" {
+		if err := multipartTmpl.Execute(file, data); err != nil {
+			return err
+		}
+	}
+	// Reset the temporary variable counter before generating each function so that the names
+	// of the temporary variables do not depend on the functions generated before.
+	codegen.TempCount = 0
+	if err := requestsTmpl.Execute(file, data); err != nil {
+		return err
+	}
+	if action.Payload == nil || binary {
+		return nil
+	}
+	data.Raw = true
+	codegen.TempCount = 0
+	if err := requestsTmpl.Execute(file, data); err != nil {
+		return err
+	}
+	if action.Payload.Type.IsObject() {
+		mapData := struct {
+			Action    interface{}
+			FieldsVar string
+			Fields    string
+		}{
+			Action:    data,
+			FieldsVar: codegen.Goify(methodName(action), false) + "Fields",
+			Fields:    g.payloadFields(action.Payload.AttributeDefinition, make(map[string]bool)),
+		}
+		if err := fromMapTmpl.Execute(file, mapData); err != nil {
+			return err
+		}
+	}
+	if !g.chunked {
+		return nil
+	}
+	return chunkedTmpl.Execute(file, data)
 }
 
-// escapeBackticks is a code generation helper that escapes backticks in a string.
-func escapeBackticks(text string) string {
-	return strings.Replace(text, "`", "`+\"`\"+`", -1)
+// groupData is the data structure holding the information needed to generate the client grouping
+// the methods sending requests to the actions of a resource.
+type groupData struct {
+	Resource *design.ResourceDefinition
+	TypeName string
+	Accessor string
 }
 
-// multiComment produces a Go comment containing the given string taking into account newlines.
-func multiComment(text string) string {
-	lines := strings.Split(text, "\n")
-	nl := make([]string, len(lines))
-	for i, l := range lines {
-		nl[i] = "// " + strings.TrimSpace(l)
+// newGroupData returns the data needed to generate the client of the given resource. The client
+// type is the resource name followed by "Client", the Client method returning it is the plural of
+// the resource name, e.g. BottleClient and Bottles.
+func newGroupData(res *design.ResourceDefinition) *groupData {
+	name := codegen.Goify(res.Name, true)
+	return &groupData{
+		Resource: res,
+		TypeName: name + "Client",
+		Accessor: plural(name),
 	}
-	return strings.Join(nl, "\n")
 }
 
-// gotTypeRefExt computes the type reference for a type in a different package.
-func goTypeRefExt(t design.DataType, tabs int, pkg string) string {
-	ref := codegen.GoTypeRef(t, nil, tabs, false)
-	if strings.HasPrefix(ref, "*") {
-		return fmt.Sprintf("%s.%s", pkg, ref[1:])
+// plural returns the plural form of the given resource name using the regular english rules.
+func plural(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return name + "es"
+	case len(lower) > 1 && strings.HasSuffix(lower, "y") && !strings.ContainsAny(lower[len(lower)-2:len(lower)-1], "aeiou"):
+		return name[:len(name)-1] + "ies"
 	}
-	return fmt.Sprintf("%s.%s", pkg, ref)
+	return name + "s"
 }
 
-// cmdFieldType computes the Go type name used to store command flags of the given design type.
-func cmdFieldType(t design.DataType, point bool) string {
-	var pointer, suffix string
-	if point && !t.IsArray() {
-		pointer = "*"
-	}
-	if t.Kind() == design.DateTimeKind || t.Kind() == design.UUIDKind {
-		suffix = "string"
-	} else {
-		suffix = codegen.GoNativeType(t)
+// methodName returns the name of the client method that sends requests to the given action. It is
+// the value of the "operationId" action metadata if any, the action name followed by the resource
+// name otherwise. The names of the other functions generated for the action derive from it.
+func methodName(action *design.ActionDefinition) string {
+	if id, ok := action.Metadata["operationId"]; ok && len(id) > 0 {
+		return codegen.Goify(id[0], true)
 	}
-	return pointer + suffix
+	return codegen.Goify(action.Name+strings.Title(action.Parent.Name), true)
 }
 
-// template used to produce code that serializes arrays of simple values into comma separated
-// strings.
-var arrayToStringTmpl *template.Template
+// wsMediaType returns the media type of the messages sent by the server over the websocket
+// connection established with the given action. This is the media type of the first action
+// response (in status code order) that defines one, nil if there is none.
+func wsMediaType(action *design.ActionDefinition) *design.MediaTypeDefinition {
+	var mt *design.MediaTypeDefinition
+	action.IterateResponses(func(r *design.ResponseDefinition) error {
+		if mt == nil {
+			mt = design.Design.MediaTypeWithIdentifier(r.MediaType)
+		}
+		return nil
+	})
+	return mt
+}
 
-// toString generates Go code that converts the given simple type attribute into a string.
-func toString(name, target string, att *design.AttributeDefinition) string {
-	switch actual := att.Type.(type) {
-	case design.Primitive:
-		switch actual.Kind() {
-		case design.IntegerKind:
-			return fmt.Sprintf("%s := strconv.Itoa(%s)", target, name)
-		case design.BooleanKind:
-			return fmt.Sprintf("%s := strconv.FormatBool(%s)", target, name)
-		case design.NumberKind:
-			return fmt.Sprintf("%s := strconv.FormatFloat(%s, 'f', -1, 64)", target, name)
-		case design.StringKind, design.DateTimeKind, design.UUIDKind:
-			return fmt.Sprintf("%s := %s", target, name)
-		case design.AnyKind:
-			return fmt.Sprintf("%s := fmt.Sprintf(\"%%v\", %s)", target, name)
-		default:
-			panic("unknown primitive type")
+// sseMediaType returns the media type of the server-sent events streamed by the given action. This
+// is the media type of the first action response (in status code order) whose identifier is
+// "text/event-stream", nil if there is none or if the action is a websocket action. The data of
+// each event is decoded into the media type.
+func sseMediaType(action *design.ActionDefinition) *design.MediaTypeDefinition {
+	return streamedMediaType(action, "text/event-stream")
+}
+
+// ndjsonMediaType returns the media type of the newline-delimited JSON values streamed by the
+// given action. This is the media type of the first action response (in status code order) whose
+// identifier is "application/x-ndjson", nil if there is none or if the action is a websocket
+// action. Each line of the stream is decoded into the media type.
+func ndjsonMediaType(action *design.ActionDefinition) *design.MediaTypeDefinition {
+	return streamedMediaType(action, "application/x-ndjson")
+}
+
+// multipartMediaType returns the identifier of the first response of the given action (in status
+// code order) that uses a multipart media type, e.g. "multipart/mixed", empty if there is none or
+// if the action is a websocket action. The parts of the response body are read with
+// goaclient.ReadParts.
+func multipartMediaType(action *design.ActionDefinition) string {
+	if action.WebSocket() {
+		return ""
+	}
+	var identifier string
+	action.IterateResponses(func(r *design.ResponseDefinition) error {
+		if identifier != "" {
+			return nil
 		}
-	case *design.Array:
-		data := map[string]interface{}{
-			"Name":     name,
-			"Target":   target,
-			"ElemType": actual.ElemType,
+		if base, _, err := mime.ParseMediaType(r.MediaType); err == nil && strings.HasPrefix(base, "multipart/") {
+			identifier = r.MediaType
 		}
-		return codegen.RunTemplate(arrayToStringTmpl, data)
-	default:
-		panic("cannot convert non simple type " + att.Type.Name() + " to string") // bug
-	}
+		return nil
+	})
+	return identifier
 }
 
-// flagType returns the flag type for the given (basic type) attribute definition.
-func flagType(att *design.AttributeDefinition) string {
-	switch att.Type.Kind() {
-	case design.IntegerKind:
-		return "Int"
-	case design.NumberKind:
-		return "Float64"
-	case design.BooleanKind:
-		return "Bool"
-	case design.StringKind:
-		return "String"
-	case design.DateTimeKind:
-		return "String"
-	case design.UUIDKind:
-		return "String"
-	case design.AnyKind:
-		return "String"
-	case design.ArrayKind:
-		return flagType(att.Type.(*design.Array).ElemType) + "Slice"
-	case design.UserTypeKind:
-		return flagType(att.Type.(*design.UserTypeDefinition).AttributeDefinition)
-	case design.MediaTypeKind:
-		return flagType(att.Type.(*design.MediaTypeDefinition).AttributeDefinition)
-	default:
-		panic("invalid flag attribute type " + att.Type.Name())
+// streamedMediaType returns the media type of the first response of the given action (in status
+// code order) whose identifier, stripped of its parameters, is base. It returns nil if there is
+// none or if the action is a websocket action.
+func streamedMediaType(action *design.ActionDefinition, base string) *design.MediaTypeDefinition {
+	if action.WebSocket() {
+		return nil
 	}
+	var mt *design.MediaTypeDefinition
+	action.IterateResponses(func(r *design.ResponseDefinition) error {
+		if mt != nil {
+			return nil
+		}
+		if b, _, err := mime.ParseMediaType(r.MediaType); err != nil || b != base {
+			return nil
+		}
+		mt = design.Design.MediaTypeWithIdentifier(r.MediaType)
+		return nil
+	})
+	return mt
 }
 
-// defaultPath returns the first route path for the given action that does not take any wildcard,
-// empty string if none.
-func defaultPath(action *design.ActionDefinition) string {
-	for _, r := range action.Routes {
-		candidate := r.FullPath()
-		if !strings.ContainsRune(candidate, ':') {
-			return candidate
-		}
+// viewMediaType returns the media type of the first action response (in status code order) that
+// defines more than one view, nil if there is none or if the action is a websocket action. The
+// request builders of actions with such a response accept the name of the view to request in the
+// Accept header.
+func viewMediaType(action *design.ActionDefinition) *design.MediaTypeDefinition {
+	if action.WebSocket() {
+		return nil
 	}
-	return ""
+	var mt *design.MediaTypeDefinition
+	action.IterateResponses(func(r *design.ResponseDefinition) error {
+		if mt != nil {
+			return nil
+		}
+		if m := design.Design.MediaTypeWithIdentifier(r.MediaType); m != nil && len(m.Views) > 1 {
+			mt = m
+		}
+		return nil
+	})
+	return mt
 }
 
-// signerType returns the name of the client signer used for the defined security model on the Action
-func signerType(scheme *design.SecuritySchemeDefinition) string {
-	switch scheme.Kind {
-	case design.JWTSecurityKind:
-		return "goaclient.JWTSigner" // goa client package imported under goaclient
-	case design.OAuth2SecurityKind:
-		return "goaclient.OAuth2Signer"
-	case design.APIKeySecurityKind:
-		return "goaclient.APIKeySigner"
-	case design.BasicAuthSecurityKind:
-		return "goaclient.BasicSigner"
-	}
-	return ""
+// resultResponseData is the data structure holding the information needed to decode the body of
+// an action response in the <Action>AndDecode methods.
+type resultResponseData struct {
+	FieldName string
+	Status    int
+	TypeRef   string
+	Decoder   string
 }
 
-// pathTemplate returns a fmt format suitable to build a request path to the reoute.
-func pathTemplate(r *design.RouteDefinition) string {
-	return design.WildcardRegex.ReplaceAllLiteralString(r.FullPath(), "/%v")
+// resultResponses returns the data used to decode the bodies of the action responses that define
+// a media type, in status code order. Only the first response is kept for a given status code.
+func resultResponses(action *design.ActionDefinition) []*resultResponseData {
+	var results []*resultResponseData
+	seen := make(map[int]bool)
+	action.IterateResponses(func(r *design.ResponseDefinition) error {
+		mt := design.Design.MediaTypeWithIdentifier(r.MediaType)
+		if mt == nil || seen[r.Status] {
+			return nil
+		}
+		seen[r.Status] = true
+		results = append(results, &resultResponseData{
+			FieldName: codegen.Goify(r.Name, true),
+			Status:    r.Status,
+			TypeRef:   codegen.GoTypeRef(mt, mt.AllRequired(), 0, false),
+			Decoder:   "Decode" + typeName(mt),
+		})
+		return nil
+	})
+	return results
 }
 
-// pathParams return the function signature of the path factory function for the given route.
-func pathParams(r *design.RouteDefinition) string {
-	pnames := r.Params()
-	params := make(design.Object, len(pnames))
-	for _, p := range pnames {
-		params[p] = r.Parent.Params.Type.ToObject()[p]
-	}
-	return join(&design.AttributeDefinition{Type: params}, false, pnames)
+// cursorData is the data structure holding the information needed to generate the method that
+// follows the cursors of the pages of results of an action.
+type cursorData struct {
+	Action interface{}
+	// TypeRef is the Go type of the collection media type of the pages.
+	TypeRef string
+	// Decoder is the name of the client method decoding a page.
+	Decoder string
+	// Param is the name of the query string parameter carrying the cursor.
+	Param string
+	// VarName is the expression of the method argument holding the cursor parameter value.
+	VarName string
+	// Field is the name of the field of the page elements holding the next cursor, empty if
+	// the cursor is only read from the Link header.
+	Field string
+	// FieldPointer is true if the field holding the next cursor is a pointer.
+	FieldPointer bool
 }
 
-// pathParamNames return the names of the parameters of the path factory function for the given route.
-func pathParamNames(r *design.RouteDefinition) string {
-	params := r.Params()
-	goified := make([]string, len(params))
-	for i, p := range params {
-		goified[i] = codegen.Goify(p, false)
+// newCursorData returns the data needed to generate the method following the cursors of the
+// pages of results of the action with the "client:cursor" metadata, nil if the action does not
+// have the metadata. The metadata values are the name of the attribute of the collection elements
+// holding the cursor of the next page and the name of the query string parameter carrying the
+// cursor, "cursor" by default. The action must declare the parameter as an optional string and
+// its 200 response must use a collection media type.
+func newCursorData(action *design.ActionDefinition, queryParams []*paramData) (*cursorData, error) {
+	md, ok := action.Metadata["client:cursor"]
+	if !ok {
+		return nil, nil
+	}
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%s action of %s: invalid client:cursor metadata, "+format,
+			append([]interface{}{action.Name, action.Parent.Name}, args...)...)
+	}
+	var attName string
+	param := "cursor"
+	if len(md) > 0 {
+		attName = md[0]
+	}
+	if len(md) > 1 && md[1] != "" {
+		param = md[1]
+	}
+	data := &cursorData{Param: param}
+	for _, p := range queryParams {
+		if p.Name == param {
+			if p.Required || p.Attribute.Type.Kind() != design.StringKind {
+				return nil, invalid("query parameter %s must be an optional string", param)
+			}
+			data.VarName = p.VarName
+		}
+	}
+	if data.VarName == "" {
+		return nil, invalid("missing query parameter %s", param)
+	}
+	if action.WebSocket() || sseMediaType(action) != nil || ndjsonMediaType(action) != nil || streamsResponse(action) {
+		return nil, invalid("the action responses must be decoded")
+	}
+	var mt *design.MediaTypeDefinition
+	if r, ok := action.Responses["OK"]; ok {
+		mt = design.Design.MediaTypeWithIdentifier(r.MediaType)
+	}
+	if mt == nil || !mt.IsArray() {
+		return nil, invalid("the OK response must use a collection media type")
+	}
+	data.TypeRef = codegen.GoTypeRef(mt, mt.AllRequired(), 0, false)
+	data.Decoder = "Decode" + typeName(mt)
+	if attName == "" {
+		return data, nil
+	}
+	elem := mt.ToArray().ElemType
+	if ds, ok := elem.Type.(design.DataStructure); ok {
+		elem = ds.Definition()
+	}
+	att, ok := elem.Type.ToObject()[attName]
+	if !ok || att.Type.Kind() != design.StringKind {
+		return nil, invalid("the collection elements must have a %s string attribute", attName)
 	}
-	return strings.Join(goified, ", ")
+	fieldName := attName
+	if tname, ok := att.Metadata["struct:field:name"]; ok && len(tname) > 0 {
+		fieldName = tname[0]
+	}
+	data.Field = codegen.Goify(fieldName, true)
+	data.FieldPointer = !elem.IsRequired(attName)
+	return data, nil
 }
 
-func typeName(mt *design.MediaTypeDefinition) string {
-	name := codegen.GoTypeName(mt, mt.AllRequired(), 1, false)
-	if mt.IsBuiltIn() {
-		return strings.Split(name, ".")[1]
-	}
-	return name
+// pageTotalHeaders lists the response headers following the pagination convention decoded by
+// goaclient.ParsePageMeta that identify list actions.
+var pageTotalHeaders = []string{"X-Total", "X-Total-Count", "X-Total-Pages"}
+
+// pageData is the data used to render the method decoding a page of results together with the
+// pagination headers of the response.
+type pageData struct {
+	Action interface{}
+	// TypeRef is the Go type of the collection media type of the page.
+	TypeRef string
+	// Decoder is the name of the client method decoding the page.
+	Decoder string
+}
+
+// newPageData returns the data needed to generate the method decoding the pages of results of
+// the given action, nil if the OK response of the action does not declare one of the
+// pageTotalHeaders or does not use a collection media type.
+func newPageData(action *design.ActionDefinition) *pageData {
+	r, ok := action.Responses["OK"]
+	if !ok || r.Headers == nil || action.WebSocket() || sseMediaType(action) != nil || ndjsonMediaType(action) != nil || streamsResponse(action) {
+		return nil
+	}
+	found := false
+	for n := range r.Headers.Type.ToObject() {
+		for _, h := range pageTotalHeaders {
+			if strings.EqualFold(n, h) {
+				found = true
+			}
+		}
+	}
+	if !found {
+		return nil
+	}
+	mt := design.Design.MediaTypeWithIdentifier(r.MediaType)
+	if mt == nil || !mt.IsArray() {
+		return nil
+	}
+	return &pageData{
+		TypeRef: codegen.GoTypeRef(mt, mt.AllRequired(), 0, false),
+		Decoder: "Decode" + typeName(mt),
+	}
+}
+
+// preferredScheme returns the scheme used by default to send requests given the schemes declared
+// in the design, in order of preference https, wss, http then ws. The selected scheme is mapped to
+// the corresponding websocket scheme if websocket is true or HTTP scheme otherwise, so that
+// declaring either https or wss makes the client use TLS.
+func preferredScheme(schemes []string, websocket bool) string {
+	secure := false
+	for _, s := range schemes {
+		if s == "https" || s == "wss" {
+			secure = true
+		}
+	}
+	switch {
+	case websocket && secure:
+		return "wss"
+	case websocket:
+		return "ws"
+	case secure:
+		return "https"
+	default:
+		return "http"
+	}
+}
+
+// headAction returns true if all the routes of the given action use the HEAD verb. The responses
+// of such actions have no body so the client only decodes their headers.
+func headAction(action *design.ActionDefinition) bool {
+	if len(action.Routes) == 0 {
+		return false
+	}
+	for _, r := range action.Routes {
+		if r.Verb != "HEAD" {
+			return false
+		}
+	}
+	return true
+}
+
+// requestContentType returns the MIME type used to encode the action payload. It is the value of
+// the "client:content-type" action metadata if any, "*/*" (which selects the default encoder)
+// otherwise.
+func requestContentType(action *design.ActionDefinition) string {
+	if ct, ok := action.Metadata["client:content-type"]; ok && len(ct) > 0 {
+		return ct[0]
+	}
+	return "*/*"
+}
+
+// acceptHeader returns the value of the Accept header listing the MIME types of the given decoders
+// in order of preference: the MIME types of the default decoder first then the others in order.
+func acceptHeader(decoders []*genapp.EncoderTemplateData) string {
+	var mimeTypes []string
+	seen := make(map[string]bool)
+	add := func(dec *genapp.EncoderTemplateData) {
+		for _, m := range dec.MIMETypes {
+			if !seen[m] {
+				seen[m] = true
+				mimeTypes = append(mimeTypes, m)
+			}
+		}
+	}
+	for _, dec := range decoders {
+		if dec.Default {
+			add(dec)
+		}
+	}
+	for _, dec := range decoders {
+		add(dec)
+	}
+	return strings.Join(mimeTypes, ", ")
+}
+
+// contentTypes returns the sorted list of the MIME types registered with the given encoders and
+// decoders.
+func contentTypes(encoders, decoders []*genapp.EncoderTemplateData) []string {
+	var mimeTypes []string
+	seen := make(map[string]bool)
+	for _, encs := range [][]*genapp.EncoderTemplateData{encoders, decoders} {
+		for _, enc := range encs {
+			for _, m := range enc.MIMETypes {
+				if !seen[m] {
+					seen[m] = true
+					mimeTypes = append(mimeTypes, m)
+				}
+			}
+		}
+	}
+	sort.Strings(mimeTypes)
+	return mimeTypes
+}
+
+// timeoutLiteral returns the Go expression of the duration set in the client:timeout metadata of
+// the given action, e.g. "30*time.Second", or an empty string if there is none.
+func timeoutLiteral(action *design.ActionDefinition) (string, error) {
+	t, ok := action.Metadata["client:timeout"]
+	if !ok || len(t) == 0 {
+		return "", nil
+	}
+	d, err := time.ParseDuration(t[0])
+	if err != nil || d <= 0 {
+		return "", fmt.Errorf("%s action of %s: invalid client:timeout metadata %q", action.Name, action.Parent.Name, t[0])
+	}
+	units := []struct {
+		unit time.Duration
+		name string
+	}{
+		{time.Hour, "time.Hour"},
+		{time.Minute, "time.Minute"},
+		{time.Second, "time.Second"},
+		{time.Millisecond, "time.Millisecond"},
+		{time.Microsecond, "time.Microsecond"},
+	}
+	for _, u := range units {
+		if d%u.unit == 0 {
+			return fmt.Sprintf("%d*%s", d/u.unit, u.name), nil
+		}
+	}
+	return fmt.Sprintf("%d*time.Nanosecond", d), nil
+}
+
+// binaryPayload returns true if the payload of the given action is sent as is in the request
+// body rather than encoded, that is if its type is Any or if the action uses the
+// "application/octet-stream" content type.
+func binaryPayload(action *design.ActionDefinition) bool {
+	if action.Payload == nil {
+		return false
+	}
+	return action.Payload.Type.Kind() == design.AnyKind ||
+		requestContentType(action) == "application/octet-stream"
+}
+
+// arrayPayload returns true if the payload of the given action is an array. The generated methods
+// accept a slice of the element type which is encoded as an array in the request body, no payload
+// type is generated for such actions.
+func arrayPayload(action *design.ActionDefinition) bool {
+	return action.Payload != nil && action.Payload.IsArray() && !binaryPayload(action)
+}
+
+// payloadType returns the type of the payload argument of the generated methods of the given
+// action, see arrayPayload.
+func payloadType(action *design.ActionDefinition) design.DataType {
+	if arrayPayload(action) {
+		return action.Payload.Type
+	}
+	return action.Payload
+}
+
+// payloadFields returns the code of the goaclient.PayloadField slice literal describing the
+// attributes of the object att, see goaclient.CoerceMap. seen holds the names of the user types
+// being described so that the attributes of recursive types are described with the "any" kind.
+func (g *Generator) payloadFields(att *design.AttributeDefinition, seen map[string]bool) string {
+	if ds, ok := att.Type.(design.DataStructure); ok {
+		att = ds.Definition()
+	}
+	obj := att.Type.ToObject()
+	names := make([]string, 0, len(obj))
+	for n := range obj {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	elems := make([]string, len(names))
+	for i, n := range names {
+		elems[i] = "{" + g.payloadField(n, obj[n], att.IsRequired(n), seen) + "},"
+	}
+	return "[]*goaclient.PayloadField{\n" + strings.Join(elems, "\n") + "\n}"
+}
+
+// payloadField returns the fields of the goaclient.PayloadField struct literal describing the
+// attribute att named name, name is empty for the elements of arrays and hashes.
+func (g *Generator) payloadField(name string, att *design.AttributeDefinition, required bool, seen map[string]bool) string {
+	var fields []string
+	if name != "" {
+		fields = append(fields, fmt.Sprintf("Name: %q", name), fmt.Sprintf("Key: %q", g.jsonKey(att, name)))
+	}
+	kind := payloadFieldKind(att.Type)
+	var typeName string
+	if ut, ok := att.Type.(*design.UserTypeDefinition); ok {
+		typeName = ut.TypeName
+	} else if mt, ok := att.Type.(*design.MediaTypeDefinition); ok {
+		typeName = mt.TypeName
+	}
+	if typeName != "" && seen[typeName] {
+		kind = "any"
+	}
+	fields = append(fields, fmt.Sprintf("Kind: %q", kind))
+	if required {
+		fields = append(fields, "Required: true")
+	}
+	switch kind {
+	case "object":
+		if typeName != "" {
+			seen[typeName] = true
+			defer delete(seen, typeName)
+		}
+		fields = append(fields, "Fields: "+g.payloadFields(att, seen))
+	case "array":
+		fields = append(fields, "Elem: &goaclient.PayloadField{"+g.payloadField("", att.Type.ToArray().ElemType, false, seen)+"}")
+	case "hash":
+		fields = append(fields, "Elem: &goaclient.PayloadField{"+g.payloadField("", att.Type.ToHash().ElemType, false, seen)+"}")
+	}
+	return strings.Join(fields, ", ")
+}
+
+// payloadFieldKind returns the goaclient.PayloadField kind of the values of the given type, the
+// kind of the underlying type for user and media types.
+func payloadFieldKind(t design.DataType) string {
+	if ds, ok := t.(design.DataStructure); ok {
+		t = ds.Definition().Type
+	}
+	switch t.Kind() {
+	case design.BooleanKind:
+		return "boolean"
+	case design.IntegerKind:
+		return "integer"
+	case design.NumberKind:
+		return "number"
+	case design.StringKind:
+		return "string"
+	case design.DateTimeKind:
+		return "datetime"
+	case design.UUIDKind:
+		return "uuid"
+	case design.ArrayKind:
+		return "array"
+	case design.HashKind:
+		return "hash"
+	case design.ObjectKind:
+		return "object"
+	}
+	return "any"
+}
+
+// initFormFields returns the fields of the action payload sent in a form encoded request body, nil
+// if the action does not use the "application/x-www-form-urlencoded" content type. Form encoded
+// payloads must be objects whose attributes are primitives or arrays.
+func initFormFields(action *design.ActionDefinition) ([]*paramData, error) {
+	if action.Payload == nil || requestContentType(action) != "application/x-www-form-urlencoded" {
+		return nil, nil
+	}
+	if !action.Payload.Type.IsObject() {
+		return nil, fmt.Errorf("%s action of %s: form encoded payload must be an object", action.Name, action.Parent.Name)
+	}
+	obj := action.Payload.Type.ToObject()
+	names := make([]string, 0, len(obj))
+	for n := range obj {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	fields := make([]*paramData, len(names))
+	for i, n := range names {
+		att := obj[n]
+		if !att.Type.IsPrimitive() && !att.Type.IsArray() {
+			return nil, fmt.Errorf("%s action of %s: cannot form encode payload attribute %s of type %s",
+				action.Name, action.Parent.Name, n, att.Type.Name())
+		}
+		varName := "payload." + codegen.Goify(n, true)
+		field := &paramData{
+			Name:         n,
+			VarName:      varName,
+			ValueName:    varName,
+			Attribute:    att,
+			MustToString: att.Type.Kind() != design.StringKind,
+			CheckNil:     att.Type.IsArray(),
+		}
+		if action.Payload.IsPrimitivePointer(n) {
+			field.ValueName = "*" + varName
+			field.CheckNil = true
+		}
+		fields[i] = field
+	}
+	return fields, nil
+}
+
+// hasBodylessRoute returns true if one of the routes of the given action uses a verb whose
+// requests usually have no body: DELETE, GET, HEAD or OPTIONS. The requests made to such actions
+// have no body when the payload is nil as some servers reject them otherwise.
+func hasBodylessRoute(action *design.ActionDefinition) bool {
+	for _, r := range action.Routes {
+		switch r.Verb {
+		case "DELETE", "GET", "HEAD", "OPTIONS":
+			return true
+		}
+	}
+	return false
+}
+
+// streamsResponse returns true if the client for the given action should include a method that
+// returns the response body without reading it. This is the case if one of the action responses
+// uses a binary media type or if the action or one of its responses has the "client:stream"
+// metadata.
+func streamsResponse(action *design.ActionDefinition) bool {
+	if _, ok := action.Metadata["client:stream"]; ok {
+		return true
+	}
+	for _, r := range action.Responses {
+		if _, ok := r.Metadata["client:stream"]; ok {
+			return true
+		}
+		if isBinaryMediaType(r.MediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBinaryMediaType returns true if the given media type identifier describes opaque content
+// that should not be decoded by the client, e.g. "application/octet-stream" or "image/png".
+func isBinaryMediaType(identifier string) bool {
+	if identifier == "" {
+		return false
+	}
+	base, _, err := mime.ParseMediaType(identifier)
+	if err != nil {
+		return false
+	}
+	switch base {
+	case "application/octet-stream", "application/pdf", "application/zip", "application/gzip":
+		return true
+	}
+	for _, prefix := range []string{"image/", "audio/", "video/"} {
+		if strings.HasPrefix(base, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasIdempotencyKey returns true if the generated request builder for the given action accepts an
+// idempotency key, that is if idempotency keys are enabled and the action uses an unsafe verb
+// (POST, PUT or PATCH).
+func (g *Generator) hasIdempotencyKey(action *design.ActionDefinition) bool {
+	if !g.idempotency || len(action.Routes) == 0 {
+		return false
+	}
+	switch action.Routes[0].Verb {
+	case "POST", "PUT", "PATCH":
+		return true
+	}
+	return false
+}
+
+// orderQueryParams returns the query parameters of the given action in the order listed by its
+// client:query-order metadata, the parameters that are not listed follow in the order of the
+// method arguments. The design does not retain the order in which the parameters are declared.
+func orderQueryParams(action *design.ActionDefinition, params []*paramData) []*paramData {
+	order := action.Metadata["client:query-order"]
+	rank := func(p *paramData) int {
+		for i, n := range order {
+			if n == p.Name {
+				return i
+			}
+		}
+		return len(order)
+	}
+	ordered := append([]*paramData(nil), params...)
+	sort.SliceStable(ordered, func(i, j int) bool { return rank(ordered[i]) < rank(ordered[j]) })
+	return ordered
+}
+
+// hasIfMatch returns true if the generated request builder for the given action accepts an If-Match
+// header argument, that is if If-Match arguments are enabled and the action uses the PUT or PATCH
+// verb. The argument holds the ETag of the representation the update applies to so that conflicting
+// updates are rejected.
+func (g *Generator) hasIfMatch(action *design.ActionDefinition) bool {
+	if !g.ifMatch || len(action.Routes) == 0 {
+		return false
+	}
+	switch action.Routes[0].Verb {
+	case "PUT", "PATCH":
+		return true
+	}
+	return false
+}
+
+// hasPatchPointers returns true if the fields of the payload of the given action are all generated
+// as optional, that is if pointer fields are enabled for PATCH actions and the action uses the
+// PATCH verb. Fields that are not set are then omitted from the request body.
+func (g *Generator) hasPatchPointers(action *design.ActionDefinition) bool {
+	if !g.patchPointers || action.Payload == nil || len(action.Routes) == 0 {
+		return false
+	}
+	return action.Routes[0].Verb == "PATCH"
+}
+
+// optionalPayload returns a copy of the given payload type where none of the attributes are
+// required so that the generated struct fields are all pointers (or nil-able) and omitted from
+// the encoded body when not set.
+func optionalPayload(payload *design.UserTypeDefinition) *design.UserTypeDefinition {
+	if payload.Validation == nil || len(payload.Validation.Required) == 0 {
+		return payload
+	}
+	validation := *payload.Validation
+	validation.Required = nil
+	att := *payload.AttributeDefinition
+	att.Validation = &validation
+	ut := *payload
+	ut.AttributeDefinition = &att
+	return &ut
+}
+
+// join is a code generation helper function that generates a function signature built from
+// concatenating the properties (name type) of the given attribute type (assuming it's an object).
+// join accepts an optional slice of strings which indicates the order in which the parameters
+// should appear in the signature. If pos is specified then it must list all the parameters. If
+// it's not specified then parameters are sorted alphabetically.
+func join(att *design.AttributeDefinition, usePointers bool, pos ...[]string) string {
+	if att == nil {
+		return ""
+	}
+	obj := att.Type.ToObject()
+	elems := make([]string, len(obj))
+	var keys []string
+	if len(pos) > 0 {
+		keys = pos[0]
+		if len(keys) != len(obj) {
+			panic("invalid position slice, lenght does not match attribute field count") // bug
+		}
+	} else {
+		keys = make([]string, len(obj))
+		i := 0
+		for n := range obj {
+			keys[i] = n
+			i++
+		}
+		sort.Strings(keys)
+	}
+	for i, n := range keys {
+		a := obj[n]
+		elems[i] = fmt.Sprintf("%s %s", codegen.Goify(n, false), cmdFieldType(a.Type, usePointers && !a.IsRequired(n)))
+	}
+	return strings.Join(elems, ", ")
+}
+
+// escapeBackticks is a code generation helper that escapes backticks in a string so that it can
+// be rendered inside a raw string literal. It also removes the carriage returns, the control
+// characters other than newlines and tabs and the invalid UTF-8 sequences that either change the
+// string value or are not valid Go source.
+func escapeBackticks(text string) (string, error) {
+	escaped := strings.Replace(sanitize(text, "\n\t"), "`", "`+\"`\"+`", -1)
+	if err := checkRawString(escaped); err != nil {
+		return "", err
+	}
+	return escaped, nil
+}
+
+// checkRawString returns an error if the given escaped text does not produce a valid Go
+// expression once wrapped in backticks.
+func checkRawString(escaped string) error {
+	if _, err := parser.ParseExpr("`" + escaped + "`"); err != nil {
+		return fmt.Errorf("cannot render %q in a raw string literal: %s", escaped, err)
+	}
+	return nil
+}
+
+// sanitize removes the control characters not listed in keep and the invalid UTF-8 sequences from
+// text.
+func sanitize(text, keep string) string {
+	return strings.Map(func(r rune) rune {
+		if r == utf8.RuneError || r == '\uFEFF' || (unicode.IsControl(r) && !strings.ContainsRune(keep, r)) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// multiComment produces a Go comment containing the given string taking into account newlines.
+// Control characters other than tabs, including carriage returns, are removed from the comment.
+func multiComment(text string) string {
+	lines := strings.Split(sanitize(text, "\n\t"), "\n")
+	nl := make([]string, len(lines))
+	for i, l := range lines {
+		nl[i] = "// " + strings.TrimSpace(l)
+	}
+	return strings.Join(nl, "\n")
+}
+
+// goTypeRefExt computes the type reference for a type in a different package, without the pointer
+// to the type if any. The names of the user types, including the element types of arrays and
+// hashes, are qualified with pkg.
+func goTypeRefExt(t design.DataType, tabs int, pkg string) string {
+	return strings.TrimPrefix(qualifiedTypeRef(t, tabs, pkg), "*")
+}
+
+// qualifiedTypeRef computes the type reference for a type in a different package.
+func qualifiedTypeRef(t design.DataType, tabs int, pkg string) string {
+	switch actual := t.(type) {
+	case *design.UserTypeDefinition, *design.MediaTypeDefinition:
+		ref := codegen.GoTypeRef(t, nil, tabs, false)
+		if strings.HasPrefix(ref, "*") {
+			return fmt.Sprintf("*%s.%s", pkg, ref[1:])
+		}
+		return fmt.Sprintf("%s.%s", pkg, ref)
+	case *design.Array:
+		return "[]" + qualifiedTypeRef(actual.ElemType.Type, tabs, pkg)
+	case *design.Hash:
+		return fmt.Sprintf("map[%s]%s", qualifiedTypeRef(actual.KeyType.Type, tabs, pkg),
+			qualifiedTypeRef(actual.ElemType.Type, tabs, pkg))
+	default:
+		return codegen.GoTypeRef(t, nil, tabs, false)
+	}
+}
+
+// validation returns the code validating the instances of the given user or media type if
+// response validation is enabled and the type defines validations, the empty string otherwise.
+func (g *Generator) validation(t design.DataType) string {
+	if !g.validate {
+		return ""
+	}
+	ds, ok := t.(design.DataStructure)
+	if !ok {
+		return ""
+	}
+	if mt, ok := t.(*design.MediaTypeDefinition); ok && mt.IsBuiltIn() {
+		return ""
+	}
+	return codegen.RecursiveChecker(ds.Definition(), false, false, false, "ut", "response", 1, false)
+}
+
+// cmdFieldType computes the Go type name used to store command flags of the given design type.
+func cmdFieldType(t design.DataType, point bool) string {
+	var pointer, suffix string
+	if point && !t.IsArray() {
+		pointer = "*"
+	}
+	if t.Kind() == design.DateTimeKind || t.Kind() == design.UUIDKind {
+		suffix = "string"
+	} else {
+		suffix = codegen.GoNativeType(t)
+	}
+	return pointer + suffix
+}
+
+// jsonParam returns true if values of the given query parameter or header type cannot be
+// serialized into a string as is, that is if the type is not a primitive type or an array of
+// primitive types. The client encodes such values in JSON.
+func jsonParam(t design.DataType) bool {
+	if t.IsPrimitive() {
+		return false
+	}
+	if a, ok := t.(*design.Array); ok {
+		return !a.ElemType.Type.IsPrimitive()
+	}
+	return true
+}
+
+// addFieldsParams adds the query parameter listing the fields of sparse fieldsets to the actions
+// of the api that have the "client:fields" metadata. The metadata value is the name of the
+// parameter, "fields" by default. The parameter is an array of strings serialized as a comma
+// separated list, actions that declare it with a different type cause an error.
+func addFieldsParams(api *design.APIDefinition) error {
+	fieldsType := &design.Array{ElemType: &design.AttributeDefinition{Type: design.String}}
+	var errs dslengine.MultiError
+	api.IterateResources(func(res *design.ResourceDefinition) error {
+		return res.IterateActions(func(action *design.ActionDefinition) error {
+			md, ok := action.Metadata["client:fields"]
+			if !ok {
+				return nil
+			}
+			name := "fields"
+			if len(md) > 0 && md[0] != "" {
+				name = md[0]
+			}
+			for _, att := range []**design.AttributeDefinition{&action.Params, &action.QueryParams} {
+				if *att == nil {
+					*att = &design.AttributeDefinition{Type: design.Object{}}
+				}
+				obj := (*att).Type.ToObject()
+				if obj == nil {
+					errs = appendError(errs, fmt.Errorf("%s action of %s: parameters are not an object", action.Name, res.Name))
+					return nil
+				}
+				p, ok := obj[name]
+				if !ok {
+					obj[name] = &design.AttributeDefinition{
+						Type:        fieldsType,
+						Description: "Names of the fields included in the response",
+					}
+					continue
+				}
+				if a, ok := p.Type.(*design.Array); !ok || a.ElemType.Type.Kind() != design.StringKind {
+					errs = appendError(errs, fmt.Errorf("%s action of %s: parameter %s of the client:fields "+
+						"convention must be an array of strings", action.Name, res.Name, name))
+					return nil
+				}
+			}
+			return nil
+		})
+	})
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// checkJSONParams returns an error for each query parameter or header of the api actions that must
+// be encoded in JSON and does not have the "client:json" metadata. The metadata makes it explicit
+// that the server must decode the JSON value.
+func checkJSONParams(api *design.APIDefinition) error {
+	var errs dslengine.MultiError
+	api.IterateResources(func(res *design.ResourceDefinition) error {
+		return res.IterateActions(func(action *design.ActionDefinition) error {
+			for _, att := range []*design.AttributeDefinition{action.QueryParams, action.Headers} {
+				if att == nil {
+					continue
+				}
+				for n, p := range att.Type.ToObject() {
+					if !jsonParam(p.Type) {
+						continue
+					}
+					if _, ok := p.Metadata["client:json"]; !ok {
+						errs = appendError(errs, fmt.Errorf("%s action of %s: parameter %s of type %s cannot be serialized, "+
+							"use the client:json metadata to encode it in JSON", action.Name, res.Name, n, p.Type.Name()))
+					}
+				}
+			}
+			return nil
+		})
+	})
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// template used to produce code that serializes arrays of simple values into comma separated
+// strings.
+var arrayToStringTmpl *template.Template
+
+// toString generates Go code that converts the given simple type attribute into a string.
+// Attributes of other types are encoded in JSON, see jsonParam. toString returns an error if the
+// attribute type cannot be converted.
+func toString(name, target string, att *design.AttributeDefinition) (string, error) {
+	if jsonParam(att.Type) {
+		tmp := codegen.Tempvar()
+		return fmt.Sprintf("%s, err := json.Marshal(%s)\n\tif err != nil {\n\t\treturn nil, err\n\t}\n\t%s := string(%s)", tmp, name, target, tmp), nil
+	}
+	switch actual := att.Type.(type) {
+	case design.Primitive:
+		switch actual.Kind() {
+		case design.IntegerKind:
+			return fmt.Sprintf("%s := strconv.Itoa(%s)", target, name), nil
+		case design.BooleanKind:
+			return fmt.Sprintf("%s := strconv.FormatBool(%s)", target, name), nil
+		case design.NumberKind:
+			return fmt.Sprintf("%s := strconv.FormatFloat(%s, 'f', -1, 64)", target, name), nil
+		case design.StringKind, design.DateTimeKind, design.UUIDKind:
+			return fmt.Sprintf("%s := %s", target, name), nil
+		case design.AnyKind:
+			return fmt.Sprintf("%s := fmt.Sprintf(\"%%v\", %s)", target, name), nil
+		default:
+			return "", fmt.Errorf("cannot convert %s of unknown primitive type %s to string", name, actual.Name())
+		}
+	case *design.Array:
+		data := map[string]interface{}{
+			"Name":     name,
+			"Target":   target,
+			"ElemType": actual.ElemType,
+		}
+		var b bytes.Buffer
+		if err := arrayToStringTmpl.Execute(&b, data); err != nil {
+			return "", err
+		}
+		return b.String(), nil
+	default:
+		return "", fmt.Errorf("cannot convert %s of non simple type %s to string", name, att.Type.Name())
+	}
+}
+
+// decodeHeader returns the code that parses the raw value of the given response header and
+// stores it in the target pointer field. The code returns an error if the value is invalid.
+func decodeHeader(header, raw, target string, att *design.AttributeDefinition) string {
+	var parse string
+	switch att.Type.Kind() {
+	case design.IntegerKind:
+		parse = fmt.Sprintf("strconv.Atoi(%s)", raw)
+	case design.NumberKind:
+		parse = fmt.Sprintf("strconv.ParseFloat(%s, 64)", raw)
+	case design.BooleanKind:
+		parse = fmt.Sprintf("strconv.ParseBool(%s)", raw)
+	case design.DateTimeKind:
+		parse = fmt.Sprintf("time.Parse(time.RFC3339, %s)", raw)
+	case design.UUIDKind:
+		parse = fmt.Sprintf("uuid.FromString(%s)", raw)
+	default:
+		return fmt.Sprintf("%s = &%s", target, raw)
+	}
+	return fmt.Sprintf(`v, err := %s
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s header value %%q: %%s", %s, err)
+		}
+		%s = &v`, parse, header, raw, target)
+}
+
+// headerFieldType returns the Go type of the field holding the value of a response header with
+// the given attribute. Headers of non primitive types are kept as raw strings.
+func headerFieldType(att *design.AttributeDefinition) string {
+	switch att.Type.Kind() {
+	case design.IntegerKind, design.NumberKind, design.BooleanKind, design.DateTimeKind, design.UUIDKind:
+		return "*" + codegen.GoNativeType(att.Type)
+	}
+	return "*string"
+}
+
+// flagType returns the flag type for the given (basic type) attribute definition, an error if
+// there is no flag type for the attribute type.
+func flagType(att *design.AttributeDefinition) (string, error) {
+	if jsonParam(att.Type) {
+		return "String", nil
+	}
+	switch att.Type.Kind() {
+	case design.IntegerKind:
+		return "Int", nil
+	case design.NumberKind:
+		return "Float64", nil
+	case design.BooleanKind:
+		return "Bool", nil
+	case design.StringKind:
+		return "String", nil
+	case design.DateTimeKind:
+		return "String", nil
+	case design.UUIDKind:
+		return "String", nil
+	case design.AnyKind:
+		return "String", nil
+	case design.ArrayKind:
+		elem, err := flagType(att.Type.(*design.Array).ElemType)
+		if err != nil {
+			return "", err
+		}
+		return elem + "Slice", nil
+	case design.UserTypeKind:
+		return flagType(att.Type.(*design.UserTypeDefinition).AttributeDefinition)
+	case design.MediaTypeKind:
+		return flagType(att.Type.(*design.MediaTypeDefinition).AttributeDefinition)
+	default:
+		return "", fmt.Errorf("invalid flag attribute type %s", att.Type.Name())
+	}
+}
+
+// defaultPath returns the first route path for the given action that does not take any wildcard,
+// empty string if none.
+func defaultPath(action *design.ActionDefinition) string {
+	for _, r := range action.Routes {
+		candidate := r.FullPath()
+		if len(design.ExtractWildcards(candidate)) == 0 {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// signerType returns the name of the client signer used for the defined security model on the Action
+func signerType(scheme *design.SecuritySchemeDefinition) string {
+	if awsV4Scheme(scheme) {
+		return "goaclient.AWSV4Signer"
+	}
+	switch scheme.Kind {
+	case design.JWTSecurityKind:
+		return "goaclient.JWTSigner" // goa client package imported under goaclient
+	case design.OAuth2SecurityKind:
+		return "goaclient.OAuth2Signer"
+	case design.APIKeySecurityKind:
+		return "goaclient.APIKeySigner"
+	case design.BasicAuthSecurityKind:
+		return "goaclient.BasicSigner"
+	}
+	return ""
+}
+
+// awsV4Scheme returns true if the API client:aws-sigv4 metadata lists the given security scheme,
+// the requests of the actions secured with it are signed with the AWS Signature Version 4
+// algorithm regardless of the scheme kind.
+func awsV4Scheme(scheme *design.SecuritySchemeDefinition) bool {
+	return schemeMetadata(scheme, "client:aws-sigv4")
+}
+
+// nonceScheme returns true if the API client:nonce metadata lists the given security scheme, the
+// requests of the actions secured with it get unique nonce and timestamp headers prior to being
+// signed.
+func nonceScheme(scheme *design.SecuritySchemeDefinition) bool {
+	return schemeMetadata(scheme, "client:nonce")
+}
+
+// schemeMetadata returns true if the values of the API metadata with the given key include the
+// name of the given security scheme.
+func schemeMetadata(scheme *design.SecuritySchemeDefinition, key string) bool {
+	if design.Design == nil {
+		return false
+	}
+	for _, name := range design.Design.Metadata[key] {
+		if name == scheme.SchemeName {
+			return true
+		}
+	}
+	return false
+}
+
+// signerFields returns the fields initializing the signer of the given security scheme as
+// defined in the design, e.g. the name of the query string parameter holding API keys.
+func signerFields(scheme *design.SecuritySchemeDefinition) string {
+	if scheme.Kind == design.APIKeySecurityKind && scheme.In == "query" {
+		return fmt.Sprintf("Query: %q", scheme.Name)
+	}
+	return ""
+}
+
+// pathTemplate returns a fmt format suitable to build a request path to the reoute.
+func pathTemplate(r *design.RouteDefinition) string {
+	return design.WildcardRegex.ReplaceAllLiteralString(r.FullPath(), "/%s")
+}
+
+// resourceBasePath returns the path prefix shared by the routes of the resource actions, that
+// is the full path of the resource. It returns the empty string if the path is the root path
+// or contains wildcards as it cannot be used as is to build request paths in that case.
+func resourceBasePath(res *design.ResourceDefinition) string {
+	p := res.FullPath()
+	if p == "" || p == "/" || len(design.ExtractWildcards(p)) > 0 {
+		return ""
+	}
+	return p
+}
+
+// basePathName returns the name of the constant holding the base path of the resource.
+func basePathName(res *design.ResourceDefinition) string {
+	return codegen.Goify(res.Name, true) + "BasePath"
+}
+
+// pathExpr returns the Go expression for the given path or path format of the route. The
+// expression builds on the resource base path constant when the path starts with it.
+func pathExpr(r *design.RouteDefinition, p string) string {
+	if r.Parent != nil && r.Parent.Parent != nil && !r.IsAbsolute() {
+		res := r.Parent.Parent
+		if base := resourceBasePath(res); base != "" && strings.HasPrefix(p, base) {
+			rest := p[len(base):]
+			if rest == "" {
+				return basePathName(res)
+			}
+			if rest[0] == '/' {
+				return basePathName(res) + " + " + strconv.Quote(rest)
+			}
+		}
+	}
+	return strconv.Quote(p)
+}
+
+// pathParams return the function signature of the path factory function for the given route.
+// UUID parameters are given as uuid.UUID values.
+func pathParams(r *design.RouteDefinition) string {
+	pnames := r.Params()
+	elems := make([]string, len(pnames))
+	for i, p := range pnames {
+		typ := "string"
+		if att := r.Parent.Params.Type.ToObject()[p]; att != nil {
+			if att.Type.Kind() == design.UUIDKind {
+				typ = "uuid.UUID"
+			} else {
+				typ = cmdFieldType(att.Type, false)
+			}
+		}
+		elems[i] = fmt.Sprintf("%s %s", codegen.Goify(p, false), typ)
+	}
+	return strings.Join(elems, ", ")
+}
+
+// pathValidation returns the code validating the parameters of the path factory function for the
+// given route against the validations declared in the design, the empty string if there are none.
+// The code sets the err variable. UUID parameters are not validated as they are given as uuid.UUID
+// values.
+func pathValidation(r *design.RouteDefinition) string {
+	var checks []string
+	for _, p := range r.Params() {
+		att := r.Parent.Params.Type.ToObject()[p]
+		if att == nil || att.Validation == nil || att.Type.Kind() == design.UUIDKind {
+			continue
+		}
+		if check := codegen.ValidationChecker(att, true, true, false, codegen.Goify(p, false), p, 1, false); check != "" {
+			checks = append(checks, check)
+		}
+	}
+	return strings.Join(checks, "\n")
+}
+
+// pathParamValues returns the expressions that compute the escaped values of the wildcards of the
+// given route from the parameters of the path factory function. Catch-all wildcards values may
+// contain slashes which are kept as is.
+func pathParamValues(r *design.RouteDefinition) string {
+	params := r.Params()
+	fullPath := r.FullPath()
+	values := make([]string, len(params))
+	for i, p := range params {
+		value := codegen.Goify(p, false)
+		if att := r.Parent.Params.Type.ToObject()[p]; att != nil && att.Type.Kind() == design.UUIDKind {
+			value = fmt.Sprintf("%s.String()", value)
+		} else if att == nil || att.Type.Kind() != design.StringKind {
+			value = fmt.Sprintf("fmt.Sprintf(\"%%v\", %s)", value)
+		}
+		value = fmt.Sprintf("url.PathEscape(%s)", value)
+		if strings.Contains(fullPath, "/*"+p) {
+			value = fmt.Sprintf("strings.Replace(%s, \"%%2F\", \"/\", -1)", value)
+		}
+		values[i] = value
+	}
+	return strings.Join(values, ", ")
+}
+
+// responseHeadersData is the data structure holding the information needed to generate the
+// struct and function that decode the headers of an action responses.
+type responseHeadersData struct {
+	Action     *design.ActionDefinition
+	MethodName string
+	Headers    []*paramData
+}
+
+// newResponseHeadersData returns the data needed to generate the decoding of the headers declared
+// by the action responses, nil if none of the responses declare headers.
+func newResponseHeadersData(action *design.ActionDefinition) *responseHeadersData {
+	atts := make(map[string]*design.AttributeDefinition)
+	action.IterateResponses(func(r *design.ResponseDefinition) error {
+		if r.Headers != nil {
+			for n, att := range r.Headers.Type.ToObject() {
+				if _, ok := atts[n]; !ok {
+					atts[n] = att
+				}
+			}
+		}
+		return nil
+	})
+	if len(atts) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(atts))
+	for n := range atts {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	data := &responseHeadersData{Action: action, MethodName: methodName(action)}
+	for _, n := range names {
+		data.Headers = append(data.Headers, &paramData{
+			Name:      n,
+			VarName:   codegen.Goify(n, true),
+			TypeName:  headerFieldType(atts[n]),
+			Attribute: atts[n],
+		})
+	}
+	return data
+}
+
+// routesVar returns the name of the variable listing the routes of an action with multiple routes,
+// the empty string if the action has a single route.
+func routesVar(action *design.ActionDefinition) string {
+	if len(action.Routes) < 2 {
+		return ""
+	}
+	return codegen.Goify(action.Name+strings.Title(action.Parent.Name), false) + "Routes"
+}
+
+// parsePathData is the data structure holding the information needed to generate the function
+// that extracts the wildcard values of the request paths of an action.
+type parsePathData struct {
+	Action    *design.ActionDefinition
+	FuncName  string
+	VarName   string
+	Wildcards string
+	Results   []string
+	Routes    []*parseRouteData
+}
+
+// parseRouteData holds the regular expression matching the request paths of a route and the
+// expressions returned by the parse function when a path matches.
+type parseRouteData struct {
+	Regexp  string
+	Values  string
+	Returns string
+}
+
+// newParsePathData returns the data needed to generate the parse function of the action request
+// paths, nil if none of the action routes have wildcards. The function results are the wildcards
+// of all the routes in order of appearance.
+func newParsePathData(action *design.ActionDefinition) *parsePathData {
+	var names []string
+	seen := make(map[string]bool)
+	for _, r := range action.Routes {
+		for _, p := range r.Params() {
+			if !seen[p] {
+				seen[p] = true
+				names = append(names, p)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	prefix := codegen.Goify(action.Name+strings.Title(action.Parent.Name), false)
+	data := &parsePathData{
+		Action:    action,
+		FuncName:  "Parse" + codegen.Goify(prefix, true) + "Path",
+		VarName:   prefix + "PathRegexps",
+		Wildcards: strings.Join(names, ", "),
+		Results:   make([]string, len(names)),
+	}
+	for i, n := range names {
+		data.Results[i] = codegen.Goify(n, false)
+	}
+	for _, r := range action.Routes {
+		params := r.Params()
+		returns := make([]string, len(names))
+		for i, n := range names {
+			returns[i] = `""`
+			for j, p := range params {
+				if p == n {
+					returns[i] = fmt.Sprintf("values[%d]", j)
+					break
+				}
+			}
+		}
+		values := "values"
+		if len(params) == 0 {
+			values = "_"
+		}
+		data.Routes = append(data.Routes, &parseRouteData{
+			Regexp:  pathRegexp(r),
+			Values:  values,
+			Returns: strings.Join(returns, ", "),
+		})
+	}
+	return data
+}
+
+// pathRegexp returns the regular expression matching the request paths of the given route. Each
+// wildcard captures a path segment, catch-all wildcards capture the remainder of the path.
+func pathRegexp(r *design.RouteDefinition) string {
+	fullPath := r.FullPath()
+	var re bytes.Buffer
+	re.WriteString("^")
+	last := 0
+	for _, m := range design.WildcardRegex.FindAllStringIndex(fullPath, -1) {
+		re.WriteString(regexp.QuoteMeta(fullPath[last:m[0]]))
+		if fullPath[m[0]+1] == '*' {
+			re.WriteString("/(.*)")
+		} else {
+			re.WriteString("/([^/]+)")
+		}
+		last = m[1]
+	}
+	re.WriteString(regexp.QuoteMeta(fullPath[last:]))
+	re.WriteString("$")
+	return re.String()
+}
+
+// viewFields returns the Go literal listing the names of the attributes rendered by the given view
+// of the media type.
+func viewFields(mt *design.MediaTypeDefinition, view string) (string, error) {
+	p, _, err := mt.Project(view)
+	if err != nil {
+		return "", err
+	}
+	obj := p.Type.ToObject()
+	names := make([]string, 0, len(obj))
+	for n := range obj {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return fmt.Sprintf("%#v", names), nil
+}
+
+func typeName(mt *design.MediaTypeDefinition) string {
+	name := codegen.GoTypeName(mt, mt.AllRequired(), 1, false)
+	if mt.IsBuiltIn() {
+		return strings.Split(name, ".")[1]
+	}
+	return name
 }
 
 // paramData is the data structure holding the information needed to generate query params and
-// headers handling code.
+// headers handling code. The generated code compares VarName to nil before reading ValueName when
+// CheckNil is true, CheckNil must therefore be true whenever ValueName dereferences VarName.
 type paramData struct {
 	Name         string
 	VarName      string
+	TypeName     string
 	ValueName    string
 	Attribute    *design.AttributeDefinition
 	MustToString bool
 	CheckNil     bool
+	Required     bool
+}
+
+// nestedData is the data used to render the method making requests to an action of a nested
+// resource given the path parameters of its first route.
+type nestedData struct {
+	Action         interface{}
+	Name           string
+	ResourceName   string
+	ParentName     string
+	MethodName     string
+	PathFunc       string
+	PathParams     string
+	PathParamNames string
+	WebSocket      bool
+	Validated      bool
+}
+
+// newNestedData returns the data used to render the method making requests to the given action
+// of a nested resource, nil if the resource has no parent, if the first route of the action has
+// no wildcard or if the names of the path parameters clash with the names of the other method
+// arguments.
+func newNestedData(action *design.ActionDefinition, data interface{}, names []string) *nestedData {
+	res := action.Parent
+	if res.ParentName == "" || len(action.Routes) == 0 {
+		return nil
+	}
+	route := action.Routes[0]
+	params := route.Params()
+	if len(params) == 0 {
+		return nil
+	}
+	taken := make(map[string]bool, len(names))
+	for _, n := range names {
+		taken[n] = true
+	}
+	pnames := make([]string, len(params))
+	for i, p := range params {
+		pnames[i] = codegen.Goify(p, false)
+		if taken[pnames[i]] {
+			return nil
+		}
+	}
+	return &nestedData{
+		Action:         data,
+		Name:           action.Name,
+		ResourceName:   res.Name,
+		ParentName:     res.ParentName,
+		MethodName:     methodName(action) + "In" + codegen.Goify(res.ParentName, true),
+		PathFunc:       codegen.Goify(action.Name+strings.Title(res.Name), true) + "Path",
+		PathParams:     pathParams(route),
+		PathParamNames: strings.Join(pnames, ", "),
+		WebSocket:      action.WebSocket(),
+	}
+}
+
+// queryData is the data used to render the struct holding the query parameters of an action.
+type queryData struct {
+	TypeName string
+	Action   *design.ActionDefinition
+	Params   []*paramData
+}
+
+// queryTypeName returns the name of the struct holding the query parameters of the given action.
+func queryTypeName(action *design.ActionDefinition) string {
+	return methodName(action) + "Query"
+}
+
+// byParamName sorts params by Go variable name, this is the order of the corresponding generated
+// function arguments.
+type byParamName []*paramData
+
+func (b byParamName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byParamName) Less(i, j int) bool { return b[i].VarName < b[j].VarName }
+func (b byParamName) Len() int           { return len(b) }
+
+// bySchemeName sorts security schemes by name.
+type bySchemeName []*design.SecuritySchemeDefinition
+
+func (b bySchemeName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b bySchemeName) Less(i, j int) bool { return b[i].SchemeName < b[j].SchemeName }
+func (b bySchemeName) Len() int           { return len(b) }
+
+const arrayToStringT = `	{{ $tmp := tempvar }}{{ $tmp }} := make([]string, len({{ .Name }}))
+	for i, e := range {{ .Name }} {
+		{{ $tmp2 := tempvar }}{{ toString "e" $tmp2 .ElemType }}
+		{{ $tmp }}[i] = {{ $tmp2 }}
+	}
+	{{ .Target }} := strings.Join({{ $tmp }}, ",")`
+
+const payloadTmpl = `// {{ gotypename .Payload nil 0 false }} is the {{ .Parent.Name }} {{ .Name }} action payload.{{ if hasPatchPointers . }}
+// Fields that are not set are omitted from the request body.{{ end }}
+type {{ gotypename .Payload nil 1 false }} {{ gotypedef .Payload 0 true false }}
+`
+
+const userTypeTmpl = `{{ $data := withEnums . }}{{ range $data.Enums }}{{/*
+*/}}// {{ .TypeName }} enumerates the values of the {{ .Attribute }} attribute of {{ .Parent }}.
+type {{ .TypeName }} string
+
+// {{ .TypeName }} values.
+const (
+{{ $typeName := .TypeName }}{{ range .Values }}	{{ .Name }} {{ $typeName }} = {{ printf "%q" .Value }}
+{{ end }})
+
+{{ end }}// {{ gotypedesc $data.Type true }}
+type {{ gotypename $data.Type $data.Type.AllRequired 1 false }} {{ gotypedef $data.Type 0 true false }}
+{{ $validation := validation . }}{{ if $validation }}
+// Validate validates the {{ gotypename $data.Type $data.Type.AllRequired 0 false }} type instance.
+func (ut {{ gotyperef $data.Type $data.Type.AllRequired 0 false }}) Validate() (err error) {
+{{ $validation }}
+	return
+}
+{{ end }}`
+
+const collectionTmpl = `
+// New{{ .TypeName }} returns the {{ .TypeName }} holding the elements of s.
+func New{{ .TypeName }}(s {{ .SliceType }}) {{ .TypeName }} {
+	return {{ .TypeName }}(s)
+}
+
+// Slice returns the elements of the collection as a plain slice.
+func (c {{ .TypeName }}) Slice() {{ .SliceType }} {
+	return {{ .SliceType }}(c)
+}
+`
+
+const typeDecodeTmpl = `{{ $typeName := typeName . }}{{ $funcName := printf "Decode%s" $typeName }}// {{ $funcName }} decodes the {{ $typeName }} instance encoded in resp body.
+func (c *Client) {{ $funcName }}(resp *http.Response) ({{ gotyperef . .AllRequired 0 false }}, error) {
+	body, err := c.responseBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	snippet := goaclient.NewSnippetReader(c.limitBody(body))
+	var decoded {{ gotypename . .AllRequired 0 false }}
+	if err := c.Decoder.Decode(&decoded, snippet, resp.Header.Get("Content-Type")); err != nil {
+		if err == ErrResponseTooLarge {
+			return nil, err
+		}
+		return nil, goaclient.NewDecodeError(resp, snippet.Snippet(), err)
+	}{{ if validation . }}
+	err = decoded.Validate(){{ end }}
+	return {{ if .IsObject }}&{{ end }}decoded, err
+}
+`
+
+const followLinksTmpl = `{{ $typeName := gotypename .Links .Links.AllRequired 0 false }}{{ range .Follows }}{{/*
+*/}}// Follow{{ .Field }} sends a GET request to the href of the {{ .Name }} link using c. The request
+// is not signed, use WithRequestMutator to set credentials if needed.
+func (l *{{ $typeName }}) Follow{{ .Field }}(ctx context.Context, c *Client) (*http.Response, error) {
+	if l == nil || l.{{ .Field }} == nil{{ if .Pointer }} || l.{{ .Field }}.Href == nil{{ end }} {
+		return nil, fmt.Errorf("missing {{ .Name }} link")
+	}
+	return c.followLink(ctx, {{ if .Pointer }}*{{ end }}l.{{ .Field }}.Href)
+}
+
+{{ end }}`
+
+const tinyJSONTmpl = `{{ $typeName := typeName . }}// MarshalTinyJSON encodes the tiny view of the {{ $typeName }} instance as JSON.
+func (mt {{ gotyperef . .AllRequired 0 false }}) MarshalTinyJSON() ([]byte, error) {
+	b, err := json.Marshal(mt)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	tiny := make(map[string]json.RawMessage)
+	for _, n := range {{ viewFields . "tiny" }} {
+		if v, ok := fields[n]; ok {
+			tiny[n] = v
+		}
+	}
+	return json.Marshal(tiny)
+}
+`
+
+const pathTmpl = `{{ $funcName := printf "%sPath%s" (goify (printf "%s%s" .Route.Parent.Name (title .Route.Parent.Parent.Name)) true) ((or (and .Index (add .Index 1)) "") | printf "%v") }}{{/*
+*/}}{{ with .Route }}// {{ $funcName }} computes a request path to the {{ .Parent.Name }} action of {{ .Parent.Parent.Name }}.
+{{ if $.Validated }}// It returns an error if a parameter does not satisfy its validations.
+func {{ $funcName }}({{ pathParams . }}) (string, error) {
+{{ with pathValidation . }}	var err error
+{{ . }}
+	if err != nil {
+		return "", err
+	}
+{{ end }}{{ if .Params }}	return fmt.Sprintf({{ pathExpr . (pathTemplate .) }}, {{ pathParamValues . }}), nil
+{{ else }}	return {{ pathExpr . .FullPath }}, nil
+{{ end }}}
+{{ else }}func {{ $funcName }}({{ pathParams . }}) string {
+{{ if .Params }}	return fmt.Sprintf({{ pathExpr . (pathTemplate .) }}, {{ pathParamValues . }})
+{{ else }}	return {{ pathExpr . .FullPath }}
+{{ end }}}
+{{ end }}{{ end }}`
+
+const basePathTmpl = `// {{ .Name }} is the path prefix shared by the routes of the {{ .Resource }} resource actions.
+const {{ .Name }} = {{ printf "%q" .Path }}
+`
+
+const parsePathTmpl = `{{ $results := .Results }}// {{ .VarName }} hold the regular expressions matching the request paths of the action routes.
+var {{ .VarName }} = []*regexp.Regexp{
+{{ range .Routes }}	regexp.MustCompile(` + "`" + `{{ .Regexp }}` + "`" + `),
+{{ end }}}
+
+// {{ .FuncName }} extracts the values of the {{ .Wildcards }} wildcards of a request path to the
+// {{ .Action.Name }} action of {{ .Action.Parent.Name }} in that order, the last result is false if
+// the path does not match any of the action routes.
+func {{ .FuncName }}(path string) ({{ range .Results }}string, {{ end }}bool) {
+{{ range $i, $r := .Routes }}	if {{ .Values }}, match := goaclient.MatchPath({{ $.VarName }}[{{ $i }}], path); match {
+		return {{ .Returns }}, true
+	}
+{{ end }}	return {{ range .Results }}"", {{ end }}false
+}
+`
+
+const queryTmpl = `// {{ .TypeName }} holds the query string parameters of the requests made to the {{ .Action.Name }}
+// action of {{ .Action.Parent.Name }}. Parameters whose field is nil are not sent.
+type {{ .TypeName }} struct {
+{{ range .Params }}{{ $field := goify .Name true }}{{ if .Attribute.Description }}	{{ multiComment .Attribute.Description }}
+{{ else }}	// {{ $field }} is the value of the {{ .Name }} parameter.
+{{ end }}	{{ $field }} {{ .TypeName }}
+{{ end }}}
+
+`
+
+const responseHeadersTmpl = `{{ $typeName := printf "%sResponseHeaders" .MethodName }}{{/*
+*/}}// {{ $typeName }} holds the headers of the responses to the {{ .Action.Name }} action of
+// {{ .Action.Parent.Name }}. Fields of headers missing from a response are nil.
+type {{ $typeName }} struct {
+{{ range .Headers }}	// {{ .VarName }} is the value of the {{ .Name }} header.
+	{{ .VarName }} {{ .TypeName }}
+{{ end }}}
+
+// Decode{{ .MethodName }}Headers decodes the headers of a response to the {{ .Action.Name }} action
+// of {{ .Action.Parent.Name }}.
+func Decode{{ .MethodName }}Headers(resp *http.Response) (*{{ $typeName }}, error) {
+	headers := &{{ $typeName }}{}
+{{ range .Headers }}	if raw := resp.Header.Get("{{ .Name }}"); raw != "" {
+		{{ decodeHeader .Name "raw" (printf "headers.%s" .VarName) .Attribute }}
+	}
+{{ end }}	return headers, nil
+}
+`
+
+const clientsTmpl = `{{ $funcName := .MethodName }}{{ $desc := .Description }}{{/*
+*/}}{{ if $desc }}{{ multiComment $desc }}{{ else }}{{/*
+*/}}// {{ $funcName }} makes a request to the {{ .Name }} action endpoint of the {{ .ResourceName }} resource{{ end }}
+//
+{{ range .Routes }}// {{ .Verb }} {{ or .FullPath "/" }}
+{{ end }}func (c *Client) {{ $funcName }}(ctx context.Context, path string{{ if .Params}},  {{ .Params }}{{ end }}) (*http.Response, error) {
+{{ if .RoutesVar }}	actionRoute := goaclient.SelectRoute(ctx, {{ .RoutesVar }}, path)
+	req, err := c.new{{ $funcName }}Request(ctx, actionRoute, path{{ if .ParamNames }}, {{ .ParamNames }}{{ end }})
+{{ else }}	req, err := c.New{{ $funcName }}Request(ctx, path{{ if .ParamNames }}, {{ .ParamNames }}{{ end }})
+{{ end }}	if err != nil {
+		return nil, err
+	}
+{{ if .Timeout }}	tctx, cancel := context.WithTimeout(req.Context(), {{ .Timeout }})
+{{ else }}	tctx, cancel := c.withTimeout(req.Context())
+{{ end }}	req = req.WithContext(tctx)
+	if resp := c.cachedResponse(req); resp != nil {
+		return cancelOnClose(resp, nil, cancel)
+	}
+	if err := c.writeFixture(Metric{{ $funcName }}, req); err != nil {
+		return cancelOnClose(nil, err, cancel)
+	}
+	span := c.startSpan(ctx, Metric{{ $funcName }}, req)
+	start := time.Now()
+	resp, err := c.{{ if .Shared }}sendShared{{ else }}send{{ end }}(tctx, req)
+	c.observe(Metric{{ $funcName }}, start, resp)
+	finishSpan(span, resp, err)
+	c.record(req, resp)
+	if err == nil {
+		resp, err = c.cacheResponse(req, resp)
+	}
+	return cancelOnClose(resp, err, cancel)
+}
+`
+
+const metricsTmpl = `// Names of the actions passed to the Metrics and the Tracer of the client, formatted as
+// "<resource>.<action>".
+const (
+{{ range . }}	// Metric{{ .MethodName }} is the name of the {{ .Name }} action of the {{ .ResourceName }} resource.
+	Metric{{ .MethodName }} = "{{ .ResourceName }}.{{ .Name }}"
+{{ end }})
+`
+
+const resultTmpl = `{{ $typeName := printf "%sResult" .Action.MethodName }}{{/*
+*/}}{{ $funcName := printf "%sAndDecode" .Action.MethodName }}{{/*
+*/}}// {{ $typeName }} is the result of a call to the {{ .Action.Name }} action of the {{ .Action.ResourceName }} resource made with
+// {{ $funcName }}. Only the field matching the response status code is set.
+type {{ $typeName }} struct {
+	// StatusCode is the response status code.
+	StatusCode int
+{{ range .Responses }}	// {{ .FieldName }} is the decoded body of the {{ .Status }} response.
+	{{ .FieldName }} {{ .TypeRef }}
+{{ end }}	// Problem is the decoded body of the responses with the application/problem+json content
+	// type.
+	Problem *goaclient.Problem
+	// Raw is the body of the other responses whose status code does not match a response
+	// defined in the design with a media type.
+	Raw []byte
+}
+
+// {{ $funcName }} calls {{ .Action.MethodName }} and decodes the response body according to the response
+// status code or as problem details if the response content type is application/problem+json, see
+// {{ $typeName }}. The response body is closed.
+func (c *Client) {{ $funcName }}(ctx context.Context, path string{{ if .Action.Params }}, {{ .Action.Params }}{{ end }}) (*{{ $typeName }}, error) {
+	resp, err := c.{{ .Action.MethodName }}(ctx, path{{ if .Action.ParamNames }}, {{ .Action.ParamNames }}{{ end }})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	result := &{{ $typeName }}{StatusCode: resp.StatusCode}
+	switch {
+	case goaclient.IsProblem(resp):
+		result.Problem, err = c.DecodeProblem(resp)
+{{ range .Responses }}	case resp.StatusCode == {{ .Status }}:
+		result.{{ .FieldName }}, err = c.{{ .Decoder }}(resp)
+{{ end }}	default:
+		result.Raw, err = ioutil.ReadAll(resp.Body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+`
+
+const cursorTmpl = `{{ $funcName := printf "%sAll" .Action.MethodName }}{{/*
+*/}}// {{ $funcName }} calls {{ .Action.MethodName }} for each page of results and returns the elements of
+// all the pages. The cursor of the next page is the value of the {{ .Param }} query string
+// parameter of the "next" link of the response Link header{{ if .Field }} or else the {{ .Field }}
+// field of the last element of the page{{ end }}. {{ $funcName }} stops when there is no next
+// cursor.
+func (c *Client) {{ $funcName }}(ctx context.Context, path string{{ if .Action.Params }}, {{ .Action.Params }}{{ end }}) ({{ .TypeRef }}, error) {
+	var all {{ .TypeRef }}
+	for {
+		resp, err := c.{{ .Action.MethodName }}(ctx, path{{ if .Action.ParamNames }}, {{ .Action.ParamNames }}{{ end }})
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("{{ .Action.ResourceName }}.{{ .Action.Name }}: unexpected response status %d", resp.StatusCode)
+		}
+		page, err := c.{{ .Decoder }}(resp)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		next := goaclient.NextCursor(resp, "{{ .Param }}"){{ if .Field }}
+		if next == "" && len(page) > 0 {{ if .FieldPointer }}&& page[len(page)-1].{{ .Field }} != nil {{ end }}{
+			next = {{ if .FieldPointer }}*{{ end }}page[len(page)-1].{{ .Field }}
+		}{{ end }}
+		if next == "" || ({{ .VarName }} != nil && *{{ .VarName }} == next) {
+			return all, nil
+		}
+		{{ .VarName }} = &next
+	}
+}
+`
+
+const pageTmpl = `{{ $funcName := printf "%sPage" .Action.MethodName }}{{/*
+*/}}// {{ $funcName }} calls {{ .Action.MethodName }} and decodes the page of results together with the
+// pagination headers of the response, see goaclient.PageMeta.
+func (c *Client) {{ $funcName }}(ctx context.Context, path string{{ if .Action.Params }}, {{ .Action.Params }}{{ end }}) ({{ .TypeRef }}, goaclient.PageMeta, error) {
+	resp, err := c.{{ .Action.MethodName }}(ctx, path{{ if .Action.ParamNames }}, {{ .Action.ParamNames }}{{ end }})
+	if err != nil {
+		return nil, goaclient.PageMeta{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, goaclient.PageMeta{}, fmt.Errorf("{{ .Action.ResourceName }}.{{ .Action.Name }}: unexpected response status %d", resp.StatusCode)
+	}
+	meta, err := goaclient.ParsePageMeta(resp)
+	if err != nil {
+		return nil, goaclient.PageMeta{}, err
+	}
+	items, err := c.{{ .Decoder }}(resp)
+	if err != nil {
+		return nil, goaclient.PageMeta{}, err
+	}
+	return items, meta, nil
+}
+`
+
+const headTmpl = `{{ $funcName := printf "%sAndDecodeHeaders" .Action.MethodName }}{{/*
+*/}}// {{ $funcName }} calls {{ .Action.MethodName }} and decodes the response headers, see
+// Decode{{ .Action.MethodName }}Headers. Responses to HEAD requests have no body, the response body
+// is closed without being read.
+func (c *Client) {{ $funcName }}(ctx context.Context, path string{{ if .Action.Params }}, {{ .Action.Params }}{{ end }}) (*http.Response, *{{ .Action.MethodName }}ResponseHeaders, error) {
+	resp, err := c.{{ .Action.MethodName }}(ctx, path{{ if .Action.ParamNames }}, {{ .Action.ParamNames }}{{ end }})
+	if err != nil {
+		return nil, nil, err
+	}
+	resp.Body.Close()
+	headers, err := Decode{{ .Action.MethodName }}Headers(resp)
+	if err != nil {
+		return resp, nil, err
+	}
+	return resp, headers, nil
+}
+`
+
+const groupTmpl = `// {{ .TypeName }} groups the methods sending requests to the actions of the {{ .Resource.Name }} resource.
+type {{ .TypeName }} struct {
+	client *Client
+}
+
+// {{ .Accessor }} returns the client sending requests to the actions of the {{ .Resource.Name }} resource.
+func (c *Client) {{ .Accessor }}() *{{ .TypeName }} {
+	return &{{ .TypeName }}{client: c}
+}
+`
+
+const groupedTmpl = `// {{ .MethodName }} {{ if .WebSocket }}establishes a websocket connection to{{ else }}makes a request to{{ end }} the {{ .Name }} action endpoint of the {{ .Action.ResourceName }} resource,
+// see {{ .Action.MethodName }}.
+func (r *{{ .GroupType }}) {{ .MethodName }}(ctx context.Context, path string{{ if .Action.Params }}, {{ .Action.Params }}{{ end }}) ({{ if .WebSocket }}*websocket.Conn{{ else }}*http.Response{{ end }}, error) {
+	return r.client.{{ .Action.MethodName }}(ctx, path{{ if .Action.ParamNames }}, {{ .Action.ParamNames }}{{ end }})
+}
+`
+
+const nestedTmpl = `// {{ .MethodName }} {{ if .WebSocket }}establishes a websocket connection to{{ else }}makes a request to{{ end }} the {{ .Name }} action endpoint of the {{ .ResourceName }} resource of
+// a {{ .ParentName }} using the path computed by {{ .PathFunc }}, see {{ .Action.MethodName }}.
+func (c *Client) {{ .MethodName }}(ctx context.Context, {{ .PathParams }}{{ if .Action.Params }}, {{ .Action.Params }}{{ end }}) ({{ if .WebSocket }}*websocket.Conn{{ else }}*http.Response{{ end }}, error) {
+{{ if .Validated }}	path, err := {{ .PathFunc }}({{ .PathParamNames }})
+	if err != nil {
+		return nil, err
+	}
+	return c.{{ .Action.MethodName }}(ctx, path{{ if .Action.ParamNames }}, {{ .Action.ParamNames }}{{ end }})
+{{ else }}	return c.{{ .Action.MethodName }}(ctx, {{ .PathFunc }}({{ .PathParamNames }}){{ if .Action.ParamNames }}, {{ .Action.ParamNames }}{{ end }})
+{{ end }}}
+`
+
+const streamTmpl = `{{ $funcName := printf "%sStream" .MethodName }}{{/*
+*/}}// {{ $funcName }} makes a request to the {{ .Name }} action endpoint of the {{ .ResourceName }} resource
+// and returns the response body without reading it. The caller must close the body.
+func (c *Client) {{ $funcName }}(ctx context.Context, path string{{ if .Params }}, {{ .Params }}{{ end }}) (io.ReadCloser, *http.Response, error) {
+	resp, err := c.{{ .MethodName }}(ctx, path{{ if .ParamNames }}, {{ .ParamNames }}{{ end }})
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Body, resp, nil
+}
+`
+
+const sseTmpl = `{{ $funcName := printf "%sEvents" .Action.MethodName }}{{/*
+*/}}{{ $eventType := gotyperef .MediaType .MediaType.AllRequired 0 false }}{{/*
+*/}}// {{ $funcName }} makes a request to the {{ .Action.Name }} action endpoint of the {{ .Action.ResourceName }} resource
+// and decodes the data of the server-sent events streamed in the response body. The events channel
+// is closed when the stream ends, the error channel then receives the error that ended it if any.
+func (c *Client) {{ $funcName }}(ctx context.Context, path string{{ if .Action.Params }}, {{ .Action.Params }}{{ end }}) (<-chan {{ $eventType }}, <-chan error) {
+	events := make(chan {{ $eventType }})
+	errc := make(chan error, 1)
+	go func() {
+		defer close(errc)
+		defer close(events)
+		resp, err := c.{{ .Action.MethodName }}(ctx, path{{ if .Action.ParamNames }}, {{ .Action.ParamNames }}{{ end }})
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			errc <- fmt.Errorf("unexpected response status %d", resp.StatusCode)
+			return
+		}
+		var data bytes.Buffer
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line != "" {
+				if strings.HasPrefix(line, "data:") {
+					if data.Len() > 0 {
+						data.WriteByte('\n')
+					}
+					data.WriteString(strings.TrimPrefix(line[5:], " "))
+				}
+				continue
+			}
+			if data.Len() == 0 {
+				continue
+			}
+			var decoded {{ gotypename .MediaType .MediaType.AllRequired 0 false }}
+			if err := c.Decoder.Decode(&decoded, &data, ""); err != nil {
+				errc <- err
+				return
+			}
+			data.Reset()
+			select {
+			case events <- {{ if .MediaType.IsObject }}&{{ end }}decoded:
+			case <-ctx.Done():
+				errc <- ctx.Err()
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			errc <- err
+		}
+	}()
+	return events, errc
+}
+`
+
+const ndjsonTmpl = `{{ $funcName := printf "%sLines" .Action.MethodName }}{{/*
+*/}}{{ $lineType := gotyperef .MediaType .MediaType.AllRequired 0 false }}{{/*
+*/}}// {{ $funcName }} makes a request to the {{ .Action.Name }} action endpoint of the {{ .Action.ResourceName }} resource
+// and decodes the newline-delimited JSON values streamed in the response body. The values channel
+// is closed when the stream ends, the error channel then receives the error that ended it if any.
+func (c *Client) {{ $funcName }}(ctx context.Context, path string{{ if .Action.Params }}, {{ .Action.Params }}{{ end }}) (<-chan {{ $lineType }}, <-chan error) {
+	values := make(chan {{ $lineType }})
+	errc := make(chan error, 1)
+	go func() {
+		defer close(errc)
+		defer close(values)
+		resp, err := c.{{ .Action.MethodName }}(ctx, path{{ if .Action.ParamNames }}, {{ .Action.ParamNames }}{{ end }})
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			errc <- fmt.Errorf("unexpected response status %d", resp.StatusCode)
+			return
+		}
+		decoder := json.NewDecoder(resp.Body)
+		for {
+			var decoded {{ gotypename .MediaType .MediaType.AllRequired 0 false }}
+			if err := decoder.Decode(&decoded); err != nil {
+				if ctx.Err() != nil {
+					errc <- ctx.Err()
+				} else if err != io.EOF {
+					errc <- err
+				}
+				return
+			}
+			select {
+			case values <- {{ if .MediaType.IsObject }}&{{ end }}decoded:
+			case <-ctx.Done():
+				errc <- ctx.Err()
+				return
+			}
+		}
+	}()
+	return values, errc
+}
+`
+
+const multipartTmpl = `{{ $funcName := printf "%sParts" .MethodName }}{{/*
+*/}}// {{ $funcName }} makes a request to the {{ .Name }} action endpoint of the {{ .ResourceName }} resource
+// and reads the parts of the multipart response body, see goaclient.ReadParts.
+func (c *Client) {{ $funcName }}(ctx context.Context, path string{{ if .Params }}, {{ .Params }}{{ end }}) ([]*goaclient.Part, *http.Response, error) {
+	resp, err := c.{{ .MethodName }}(ctx, path{{ if .ParamNames }}, {{ .ParamNames }}{{ end }})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, resp, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	parts, err := goaclient.ReadParts(resp)
+	return parts, resp, err
+}
+`
+
+const clientsWSTmpl = `{{ $funcName := .MethodName }}{{ $desc := .Description }}{{/*
+*/}}{{ if $desc }}{{ multiComment $desc }}{{ else }}// {{ $funcName }} establishes a websocket connection to the {{ .Name }} action endpoint of the {{ .ResourceName }} resource{{ end }}
+func (c *Client) {{ $funcName }}(ctx context.Context, path string{{ if .Params }}, {{ .Params }}{{ end }}) (*websocket.Conn, error) {
+	scheme := c.Scheme
+	if scheme == "" {
+		scheme = "{{ .DefaultScheme }}"
+	}
+	u := url.URL{Host: c.Host, Scheme: scheme, Path: path}
+{{ if .QueryParams }}	values := u.Query()
+{{ range .QueryParams }}{{ if .CheckNil }}	if {{ .VarName }} != nil {
+	{{ end }}{{ if .MustToString}}{{ $tmp := tempvar }}	{{ toString .ValueName $tmp .Attribute }}
+	values.Set("{{ .Name }}", {{ $tmp }})
+{{ else }}	values.Set("{{ .Name }}", {{ .ValueName }})
+{{ end }}{{ if .CheckNil }}	}
+{{ end }}{{ end }}	u.RawQuery = values.Encode()
+{{ end }}	return websocket.Dial(u.String(), "", u.String())
+}
+`
+
+const wsWrapperTmpl = `{{ $typeName := goify (printf "%s%sStream" .Action.ResourceName (title .Action.Name)) true }}{{/*
+*/}}{{ $funcName := printf "%sStream" .Action.MethodName }}{{ $connFunc := .Action.MethodName }}{{/*
+*/}}// {{ $typeName }} wraps the websocket connection established with the {{ .Action.Name }} action
+// endpoint of the {{ .Action.ResourceName }} resource.
+type {{ $typeName }} struct {
+	conn    *websocket.Conn
+	decoder *goa.HTTPDecoder
+}
+
+// {{ $funcName }} establishes a websocket connection to the {{ .Action.Name }} action endpoint of
+// the {{ .Action.ResourceName }} resource and wraps it in a {{ $typeName }}.
+func (c *Client) {{ $funcName }}(ctx context.Context, path string{{ if .Action.Params }}, {{ .Action.Params }}{{ end }}) (*{{ $typeName }}, error) {
+	conn, err := c.{{ $connFunc }}(ctx, path{{ if .Action.ParamNames }}, {{ .Action.ParamNames }}{{ end }})
+	if err != nil {
+		return nil, err
+	}
+	return &{{ $typeName }}{conn: conn, decoder: c.Decoder}, nil
+}
+
+// Read reads the next message sent by the server and decodes it.
+func (s *{{ $typeName }}) Read() ({{ gotyperef .MediaType .MediaType.AllRequired 0 false }}, error) {
+	var msg []byte
+	if err := websocket.Message.Receive(s.conn, &msg); err != nil {
+		return nil, err
+	}
+	var decoded {{ gotypename .MediaType .MediaType.AllRequired 0 false }}
+	err := s.decoder.Decode(&decoded, bytes.NewReader(msg), "")
+	return {{ if .MediaType.IsObject }}&{{ end }}decoded, err
+}
+
+// Close closes the underlying websocket connection.
+func (s *{{ $typeName }}) Close() error {
+	return s.conn.Close()
+}
+`
+
+const requestsTmpl = `{{ $funcName := printf "New%sRequest" .MethodName }}{{/*
+*/}}{{ if and .Raw (not .FormFields) }}// {{ $funcName }}As create the request corresponding to the {{ .Name }} action endpoint of the {{ .ResourceName }} resource
+// encoding the payload with the client encoder registered for contentType, the default encoder is
+// used if there is none. The Content-Type header is set to contentType.
+func (c *Client) {{ $funcName }}As(ctx context.Context, path string, payload {{ .PayloadType }}, contentType string{{ if .RawParams }}, {{ .RawParams }}{{ end }}) (*http.Request, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("invalid content type %q: %s", contentType, err)
+	}
+	var body bytes.Buffer
+	rest, err := c.encodeBody(payload, &body, mediaType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode body: %s", err)
+	}
+	req, err := c.{{ $funcName }}Raw(ctx, path, &body, contentType{{ if .RawParamNames }}, {{ .RawParamNames }}{{ end }})
+	if err != nil {
+		if rest != nil {
+			rest.Close()
+		}
+		return nil, err
+	}
+	if rest != nil {
+		streamBody(req, rest)
+	}
+	return req, nil
+}
+
+{{ end }}{{ if .Raw }}// {{ $funcName }}Raw create the request corresponding to the {{ .Name }} action endpoint of the {{ .ResourceName }} resource
+// using body as is for the request body. The Content-Type header is set to contentType unless empty.
+func (c *Client) {{ $funcName }}Raw(ctx context.Context, path string, body io.Reader, contentType string{{ if .RawParams }}, {{ .RawParams }}{{ end }}) (*http.Request, error) {
+{{ if .RoutesVar }}	actionRoute := goaclient.SelectRoute(ctx, {{ .RoutesVar }}, path)
+{{ end }}{{ else if .RoutesVar }}// {{ .RoutesVar }} lists the routes of the {{ .Name }} action of the {{ .ResourceName }} resource.
+var {{ .RoutesVar }} = []goaclient.Route{
+{{ range .Routes }}	{Verb: "{{ .Verb }}", Path: "{{ .FullPath }}"},
+{{ end }}}
+
+{{ range $i, $route := .Routes }}{{ $suffix := (or (and $i (add $i 1)) "") | printf "%v" }}{{/*
+*/}}// {{ $funcName }}{{ $suffix }} create the request corresponding to the {{ $.Name }} action endpoint of the {{ $.ResourceName }} resource
+// using the {{ $route.Verb }} {{ $route.FullPath }} route.
+func (c *Client) {{ $funcName }}{{ $suffix }}(ctx context.Context, path string{{ if $.Params }}, {{ $.Params }}{{ end }}) (*http.Request, error) {
+	return c.new{{ $.MethodName }}Request(ctx, {{ $.RoutesVar }}[{{ $i }}], path{{ if $.ParamNames }}, {{ $.ParamNames }}{{ end }})
+}
+
+{{ end }}// new{{ .MethodName }}Request create the request corresponding to the {{ .Name }} action endpoint of the {{ .ResourceName }} resource
+// using the given route.
+func (c *Client) new{{ .MethodName }}Request(ctx context.Context, actionRoute goaclient.Route, path string{{ if .Params }}, {{ .Params }}{{ end }}) (*http.Request, error) {
+{{ else }}// {{ $funcName }} create the request corresponding to the {{ .Name }} action endpoint of the {{ .ResourceName }} resource.
+//
+{{ range .Routes }}// {{ .Verb }} {{ or .FullPath "/" }}
+{{ end }}func (c *Client) {{ $funcName }}(ctx context.Context, path string{{ if .Params }}, {{ .Params }}{{ end }}) (*http.Request, error) {
+{{ end }}{{ range .Headers }}{{ if and .Required (eq .TypeName "string") }}	if {{ .VarName }} == "" {
+		return nil, fmt.Errorf("missing required {{ .Name }} header")
+	}
+{{ end }}{{ end }}{{ if or .Raw .Binary }}{{ else if .FormFields }}	var body bytes.Buffer
+	if payload != nil {
+		form := url.Values{}
+{{ range .FormFields }}{{ if .CheckNil }}	if {{ .VarName }} != nil {
+	{{ end }}{{ if .MustToString }}{{ $tmp := tempvar }}	{{ toString .ValueName $tmp .Attribute }}
+	form.Set("{{ .Name }}", {{ $tmp }})
+{{ else }}	form.Set("{{ .Name }}", {{ .ValueName }})
+{{ end }}{{ if .CheckNil }}	}
+{{ end }}{{ end }}		body.WriteString(form.Encode())
+	}
+{{ else if and .OptionalBody .ContentMD5 }}	var body bytes.Buffer
+	if payload != nil {
+		err := c.Encoder.Encode(payload, &body, "{{ .ContentType }}"){{ if eq .ContentType "*/*" }} // Use default encoder{{ end }}
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode body: %s", err)
+		}
+	}
+{{ else if .OptionalBody }}	var body bytes.Buffer
+	var rest io.ReadCloser
+	if payload != nil {
+		var err error
+		rest, err = c.encodeBody(payload, &body, "{{ .ContentType }}"){{ if eq .ContentType "*/*" }} // Use default encoder{{ end }}
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode body: %s", err)
+		}
+	}
+{{ else if and .HasPayload .ContentMD5 }}	var body bytes.Buffer
+	err := c.Encoder.Encode(payload, &body, "{{ .ContentType }}"){{ if eq .ContentType "*/*" }} // Use default encoder{{ end }}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode body: %s", err)
+	}
+{{ else if .HasPayload }}	var body bytes.Buffer
+	rest, err := c.encodeBody(payload, &body, "{{ .ContentType }}"){{ if eq .ContentType "*/*" }} // Use default encoder{{ end }}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode body: %s", err)
+	}
+{{ end }}	scheme := c.Scheme
+	if scheme == "" {
+		scheme = "{{ .DefaultScheme }}"
+	}
+	u := url.URL{Host: c.Host, Scheme: scheme, Path: c.requestPath(ctx, path)}
+{{ if and .QueryParams .OrderedQuery }}	var pairs []string
+{{ range .QueryParams }}{{ if .CheckNil }}	if {{ .VarName }} != nil {
+	{{ end }}{{ if .MustToString }}{{ $tmp := tempvar }}	{{ toString .ValueName $tmp .Attribute }}
+	pairs = append(pairs, url.QueryEscape("{{ .Name }}")+"="+url.QueryEscape({{ $tmp }}))
+{{ else }}	pairs = append(pairs, url.QueryEscape("{{ .Name }}")+"="+url.QueryEscape({{ .ValueName }}))
+{{ end }}{{ if .CheckNil }}	}
+{{ end }}{{ end }}	u.RawQuery = strings.Join(pairs, "&")
+{{ else if .QueryParams }}	values := u.Query()
+{{ range .QueryParams }}{{ if .CheckNil }}	if {{ .VarName }} != nil {
+	{{ end }}{{ if .MustToString }}{{ $tmp := tempvar }}	{{ toString .ValueName $tmp .Attribute }}
+	values.Set("{{ .Name }}", {{ $tmp }})
+{{ else }}	values.Set("{{ .Name }}", {{ .ValueName }})
+{{ end }}{{ if .CheckNil }}	}
+{{ end }}{{ end }}	u.RawQuery = values.Encode()
+{{ end }}{{ $verb := printf "%q" (index .Routes 0).Verb }}{{ if .RoutesVar }}{{ $verb = "actionRoute.Verb" }}{{ end }}{{/*
+*/}}{{ if or .Raw .Binary }}	req, err := http.NewRequest({{ $verb }}, u.String(), body)
+{{ else if .HasPayload }}	req, err := http.NewRequest({{ $verb }}, u.String(), &body)
+{{ else }}	req, err := http.NewRequest({{ $verb }}, u.String(), nil)
+{{ end }}	if err != nil {
+{{ if and .HasPayload (not (or .Raw .Binary .FormFields .ContentMD5)) }}		if rest != nil {
+			rest.Close()
+		}
+{{ end }}		return nil, err
+	}
+	req = req.WithContext(ctx)
+{{ if and .HasPayload (not (or .Raw .Binary .FormFields .ContentMD5)) }}	if rest != nil {
+		streamBody(req, rest)
+	}
+{{ end }}{{ if .RoutesVar }}	req = goaclient.WithRequestRoute(req, actionRoute)
+{{ end }}	for name, values := range c.headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	if id := middleware.ContextRequestID(ctx); id != "" {
+		req.Header.Set(middleware.RequestIDHeader, id)
+	}
+	c.forwardHeaders(ctx, req)
+{{ if .Raw }}	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+{{ else if and .OptionalBody (ne .ContentType "*/*") }}	if payload != nil {
+		req.Header.Set("Content-Type", "{{ .ContentType }}")
+	}
+{{ else if and .HasPayload (ne .ContentType "*/*") }}	req.Header.Set("Content-Type", "{{ .ContentType }}")
+{{ end }}{{ if .Accept }}	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "{{ .Accept }}")
+	}
+{{ end }}{{ if and .ContentMD5 (not .Raw) }}{{ if .OptionalBody }}	if payload != nil {
+		sum := md5.Sum(body.Bytes())
+		req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	}
+{{ else }}	sum := md5.Sum(body.Bytes())
+	req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+{{ end }}{{ end }}{{ if .EventStream }}	req.Header.Set("Accept", "text/event-stream")
+{{ end }}{{ if .JSONLines }}	req.Header.Set("Accept", "application/x-ndjson")
+{{ end }}{{ if .Multipart }}	req.Header.Set("Accept", "{{ .Multipart }}")
+{{ end }}{{ if .ViewIdentifier }}	if view != "" {
+		req.Header.Set("Accept", "{{ .ViewIdentifier }}; view="+view)
+	}
+{{ end }}{{ if .Headers }}	header := req.Header
+{{ range .Headers }}{{ if .CheckNil }}	if {{ .VarName }} != nil {
+	{{ end }}{{ if .MustToString }}{{ $tmp := tempvar }}	{{ toString .ValueName $tmp .Attribute }}
+	header.Set("{{ .Name }}", {{ $tmp }}){{ else }}
+	header.Set("{{ .Name }}", {{ .ValueName }})
+{{ end }}{{ if .CheckNil }}	}
+{{ end }}{{ end }}{{ end }}	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+{{ if .IdempotencyKey }}	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+{{ end }}{{ if .IfMatch }}	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+{{ end }}{{ if .Signers }}	if c.ShouldSign == nil || c.ShouldSign(req) {
+{{ if .TokenSigners }}		_, hasToken := goaclient.ContextToken(ctx)
+{{ end }}{{ if .HeaderSigners }}		authorized := req.Header.Get("Authorization") != ""
+{{ end }}{{ if .Nonce }}		nonce, err := goaclient.NewNonce()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-Nonce", nonce)
+		req.Header.Set("X-Timestamp", strconv.FormatInt(time.Now().Unix(), 10))
+{{ end }}{{ range .Signers }}		if !c.{{ . }}Signer.Configured(){{ if index $.TokenSigners . }} && !hasToken{{ end }} {
+			{{ if index $.HeaderSigners . }}if !authorized {
+				return nil, fmt.Errorf("cannot sign the {{ $.Name }} {{ $.ResourceName }} request: the {{ . }}Signer of the client is not configured")
+			}{{ else }}return nil, fmt.Errorf("cannot sign the {{ $.Name }} {{ $.ResourceName }} request: the {{ . }}Signer of the client is not configured"){{ end }}
+		} else if err := c.{{ . }}Signer.Sign(ctx, req); err != nil {
+			return nil, err
+		}
+{{ end }}	}
+{{ end }}	for _, mutate := range c.mutators {
+		if err := mutate(req); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+`
+
+const chunkedTmpl = `{{ $funcName := printf "New%sRequest" .MethodName }}{{/*
+*/}}// {{ $funcName }}Chunked create the request corresponding to the {{ .Name }} action endpoint of the {{ .ResourceName }} resource
+// streaming body with the chunked transfer encoding instead of reading it in memory, see {{ $funcName }}Raw.
+// The request is never retried as its body cannot be replayed.
+func (c *Client) {{ $funcName }}Chunked(ctx context.Context, path string, body io.Reader, contentType string{{ if .RawParams }}, {{ .RawParams }}{{ end }}) (*http.Request, error) {
+	// Hide the type of body so that http.NewRequest does not compute the content length.
+	req, err := c.{{ $funcName }}Raw(ctx, path, ioutil.NopCloser(body), contentType{{ if .RawParamNames }}, {{ .RawParamNames }}{{ end }})
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = -1
+	return req, nil
+}
+`
+
+const fromMapTmpl = `{{ $funcName := printf "New%sRequest" .Action.MethodName }}// {{ .FieldsVar }} describes the attributes of the {{ .Action.Name }} action payload of the {{ .Action.ResourceName }} resource.
+var {{ .FieldsVar }} = {{ .Fields }}
+
+// {{ $funcName }}FromMap create the request corresponding to the {{ .Action.Name }} action endpoint of the {{ .Action.ResourceName }} resource
+// building the payload from the attribute values held in data, keyed by attribute name. The values
+// are validated against the payload attributes and coerced to their types, e.g. the string "42" is
+// accepted for an integer attribute, see goaclient.CoerceMap.
+func (c *Client) {{ $funcName }}FromMap(ctx context.Context, path string, data map[string]interface{}{{ if .Action.RawParams }}, {{ .Action.RawParams }}{{ end }}) (*http.Request, error) {
+	coerced, err := goaclient.CoerceMap(data, {{ .FieldsVar }})
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(coerced)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode payload: %s", err)
+	}
+	var payload {{ .Action.PayloadType }}
+	if err := json.Unmarshal(b, &payload); err != nil {
+		return nil, fmt.Errorf("failed to decode payload: %s", err)
+	}
+	return c.{{ $funcName }}(ctx, path, payload{{ if .Action.RawParamNames }}, {{ .Action.RawParamNames }}{{ end }})
+}
+`
+
+const contentTypesTmpl = `// SupportedContentTypes returns the MIME types of the encoders and decoders registered by the
+// generated client code, sorted alphabetically. Request bodies may be encoded and response bodies
+// decoded with any of them.
+func (c *Client) SupportedContentTypes() []string {
+	return {{ if . }}[]string{ {{ range $i, $m := . }}{{ if $i }}, {{ end }}{{ printf "%q" $m }}{{ end }} }{{ else }}nil{{ end }}
+}
+`
+
+const pingTmpl = `// Ping calls the {{ .Action.Name }} action of {{ .Action.Parent.Name }} which is the API health check
+// and returns an error if the response status code is not 2xx.
+func (c *Client) Ping(ctx context.Context) error {
+	resp, err := c.{{ .MethodName }}(ctx, "{{ .Path }}"{{ .Args }})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("health check failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
+`
+
+const clientTmpl = `// Client is the {{ .API.Name }} service client.
+type Client struct {
+	*goaclient.Client{{range $security := .API.SecuritySchemes }}{{ $signer := signerType $security }}{{ if $signer }}
+	{{ goify $security.SchemeName true }}Signer *{{ $signer }}{{ end }}{{ end }}
+	Encoder *goa.HTTPEncoder
+	Decoder *goa.HTTPDecoder
+	// PathPrefixFunc is called with the context of each request if not nil, the request path is
+	// prefixed with the returned value, e.g. to add a locale prefix. The prefix follows the base
+	// path set with WithBasePath if any.
+	PathPrefixFunc func(ctx context.Context) string{{ if .API.SecuritySchemes }}
+	// ShouldSign is called with the requests made to secured actions if not nil, the requests
+	// are signed only if it returns true. Requests are always signed by default.
+	ShouldSign func(*http.Request) bool{{ end }}
+	retries       int
+	ctx           context.Context
+	headers       http.Header
+	forwarded     []string
+	mutators      []func(*http.Request) error
+	limiter       *goaclient.RateLimiter
+	metrics       Metrics
+	tracer        Tracer
+	dump          io.Writer
+	slash         *bool
+	basePath      string
+	timeout       time.Duration
+	concurrency   int
+	recording     *recording
+	fixtures      *fixtures
+	cache         *responseCache
+	flight        *goaclient.CallGroup
+	http2         *bool
+	tlsConfig     *tls.Config
+	unixSocket    string
+	maxIdleConns  int
+	maxConns      int
+	strictJSON    bool
+	numberJSON    bool
+	decompressors map[string]func(io.Reader) (io.Reader, error)
+	maxBody       int64
+	streaming     int64
+	redirectAuth  bool
+}
+
+// Metrics is the interface implemented by the sinks receiving observations of the requests made
+// by the client, for example adapters to Prometheus or statsd.
+type Metrics interface {
+	// ObserveRequest is called once per call with the name of the action formatted as
+	// "<resource>.<action>", the response status code, 0 if the request failed, and the
+	// duration of the call.
+	ObserveRequest(action string, status int, dur time.Duration)
+}
+
+// Tracer is the interface implemented by the tracers propagating the trace context of the
+// requests made by the client, for example adapters to OpenTracing or OpenTelemetry.
+type Tracer interface {
+	// StartSpan starts a span for the call to the action, whose name is formatted as
+	// "<resource>.<action>", as a child of the span held by ctx if any.
+	StartSpan(ctx context.Context, action string) Span
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	// Inject writes the headers propagating the trace context of the span to h.
+	Inject(h http.Header)
+	// Finish ends the span recording the response status code, 0 if the request failed, and
+	// the error that caused the request to fail if any.
+	Finish(status int, err error)
+}
+
+// recording holds the last request sent by a client created with WithRecording and its response.
+type recording struct {
+	sync.Mutex
+	req  *http.Request
+	resp *http.Response
+}
+
+// fixtures holds the directory where a client created with WithFixtureDir writes the requests it
+// sends and the number of fixtures written so far.
+type fixtures struct {
+	sync.Mutex
+	dir   string
+	count int
+}
+
+// responseCache holds the responses to the GET and HEAD requests sent by a client created with
+// WithCache.
+type responseCache struct {
+	sync.Mutex
+	max     int
+	ttl     time.Duration
+	entries map[string]*cacheEntry
+	keys    []string
+}
+
+// cacheEntry is a response held by a responseCache.
+type cacheEntry struct {
+	status  string
+	code    int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// sharedResponse is the response to a request sent once on behalf of the concurrent callers of a
+// client created with WithSingleflight. The body is read in memory so that each caller gets its
+// own copy.
+type sharedResponse struct {
+	resp *http.Response
+	body []byte
+}
+
+// BatchResult is the result of one of the requests sent with Batch.
+type BatchResult struct {
+	// Response is the response to the request, nil if Err is not nil.
+	Response *http.Response
+	// Err is the error that caused the request to fail if any.
+	Err error
+}
+
+// ErrShutdown is the error returned by calls made with a client whose context, set with
+// WithContext, is done.
+var ErrShutdown = errors.New("client is shut down")
+
+// ErrResponseTooLarge is the error returned by the decode helpers when the body of the response
+// exceeds the limit set with WithMaxResponseBytes.
+var ErrResponseTooLarge = errors.New("response body too large")
+
+// Option configures a client created with New or NewWithDoer.
+type Option func(*Client)
+
+// Config is the configuration of a client created with NewWithConfig, the zero value of a field
+// leaves the corresponding client setting unchanged.
+type Config struct {
+	// Host is the API hostname.
+	Host string
+	// Scheme is the scheme used to send the requests, the scheme of the actions is used if empty.
+	Scheme string
+	// Timeout is the time limit of the calls, see WithTimeout.
+	Timeout time.Duration
+	// BasePath is the prefix of the request paths, see WithBasePath.
+	BasePath string
+	// Headers are the headers sent with each request, see WithDefaultHeader.
+	Headers map[string]string{{ range $security := .API.SecuritySchemes }}{{/*
+*/}}{{ $signer := signerType $security }}{{ $name := goify $security.SchemeName true }}{{/*
+*/}}{{ if eq $signer "goaclient.BasicSigner" }}
+	// {{ $name }}Username is the username used by the {{ $name }}Signer.
+	{{ $name }}Username string
+	// {{ $name }}Password is the password used by the {{ $name }}Signer.
+	{{ $name }}Password string{{/*
+*/}}{{ else if eq $signer "goaclient.APIKeySigner" }}
+	// {{ $name }}Key is the API key used by the {{ $name }}Signer.
+	{{ $name }}Key string{{/*
+*/}}{{ else if eq $signer "goaclient.JWTSigner" }}
+	// {{ $name }}Token is the JWT used by the {{ $name }}Signer.
+	{{ $name }}Token string{{/*
+*/}}{{ else if eq $signer "goaclient.OAuth2Signer" }}
+	// {{ $name }}RefreshURLFormat is the format of the refresh URL used by the {{ $name }}Signer.
+	{{ $name }}RefreshURLFormat string
+	// {{ $name }}RefreshToken is the refresh token used by the {{ $name }}Signer.
+	{{ $name }}RefreshToken string{{/*
+*/}}{{ else if eq $signer "goaclient.AWSV4Signer" }}
+	// {{ $name }}AccessKeyID is the AWS access key ID used by the {{ $name }}Signer.
+	{{ $name }}AccessKeyID string
+	// {{ $name }}SecretAccessKey is the AWS secret access key used by the {{ $name }}Signer.
+	{{ $name }}SecretAccessKey string
+	// {{ $name }}Region is the AWS region used by the {{ $name }}Signer.
+	{{ $name }}Region string{{ end }}{{ end }}
+}
+
+// WithUserAgent sets the User-Agent header sent with each request, an empty value disables it.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.UserAgent = userAgent
+	}
+}
+
+// WithTimeout sets the time limit of the calls made by the client, including retries and the
+// time taken to read the response body. Actions whose design defines the client:timeout metadata
+// use that value instead.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithRecording makes the client record the last request it sent and the response it received,
+// see LastRequest and LastResponse. This is meant for testing and debugging.
+func WithRecording() Option {
+	return func(c *Client) {
+		c.recording = &recording{}
+	}
+}
+
+// WithFixtureDir makes the client write each request it sends to a file in dir before sending
+// it, see goaclient.Fixture. The files are named after the request metric and a sequence number,
+// e.g. "bottle.show-1.json". This is meant for golden file testing, the fixtures can be loaded
+// back with goaclient.ReadFixture.
+func WithFixtureDir(dir string) Option {
+	return func(c *Client) {
+		c.fixtures = &fixtures{dir: dir}
+	}
+}
+
+// WithCache makes the client keep the successful responses to GET and HEAD requests in memory
+// for ttl and return them instead of sending the same requests again. The requests are
+// identified by their method, URL, Accept header and the headers holding the credentials set by
+// the signers so that callers with different credentials never share responses. The cache holds
+// at most maxEntries responses, the oldest are evicted first. Clones share the cache of the
+// client.
+func WithCache(maxEntries int, ttl time.Duration) Option {
+	return func(c *Client) {
+		c.cache = &responseCache{
+			max:     maxEntries,
+			ttl:     ttl,
+			entries: make(map[string]*cacheEntry),
+		}
+	}
+}
+
+// WithSingleflight makes the client send identical GET and HEAD requests made concurrently only
+// once, the callers all receive a copy of the same response. The requests are identified by their
+// method, URL, Accept header and credential headers as with WithCache. The response bodies are read
+// in memory, the responses of the streaming and server-sent events actions are never shared.
+// Cancelling the context of the first caller cancels the shared request. Clones share the
+// in-flight requests of the client.
+func WithSingleflight() Option {
+	return func(c *Client) {
+		c.flight = &goaclient.CallGroup{}
+	}
+}
+
+// WithConcurrency sets the maximum number of requests sent concurrently by Batch, 1 by default.
+func WithConcurrency(n int) Option {
+	return func(c *Client) {
+		c.concurrency = n
+	}
+}
+
+// WithRetries makes the client retry idempotent requests up to n times when they fail with a
+// transport error or a 429, 502, 503 or 504 response. The client waits for the duration given by
+// the Retry-After response header if any before retrying. All the attempts carry the same
+// X-Request-Id correlation ID header, generated if the request has none, and an X-Attempt header
+// counting the attempts from 1. The requests signed with a nonce, see the client:nonce metadata,
+// are never retried as the server rejects replayed nonces.
+func WithRetries(n int) Option {
+	return func(c *Client) {
+		c.retries = n
+	}
+}
+
+// WithContext makes the client fail calls fast with ErrShutdown and cancel in-flight requests
+// once ctx is done.
+func WithContext(ctx context.Context) Option {
+	return func(c *Client) {
+		c.ctx = ctx
+	}
+}
+
+// WithDefaultHeader sets a header sent with each request, for example an API version header.
+// Headers set by the action requests override default headers with the same name.
+func WithDefaultHeader(name, value string) Option {
+	return func(c *Client) {
+		if c.headers == nil {
+			c.headers = make(http.Header)
+		}
+		c.headers.Set(name, value)
+	}
+}
+
+// WithForwardedHeaders makes the client copy the given headers of the goa request being served,
+// see goa.ContextRequest, to the requests made with the same context. This makes headers such as
+// the caller credentials or tracing headers set upstream flow through services calling other
+// services. Headers set by the action requests and the signers override forwarded headers.
+func WithForwardedHeaders(names ...string) Option {
+	return func(c *Client) {
+		c.forwarded = append(c.forwarded, names...)
+	}
+}
+
+// WithBearerToken sets the Authorization header sent with each request to a bearer token, it
+// does not require the design to define a JWT or OAuth2 security scheme. Configured signers of
+// secured actions override the header, the requests to secured actions whose signer is not
+// configured are sent with the token instead of failing.
+func WithBearerToken(token string) Option {
+	return WithDefaultHeader("Authorization", "Bearer "+token)
+}
+
+// WithRequestMutator adds functions that modify the requests built by the client, for example to
+// set additional headers. The mutators run in order once the request is signed, the request build
+// fails if a mutator returns an error.
+func WithRequestMutator(mutators ...func(*http.Request) error) Option {
+	return func(c *Client) {
+		c.mutators = append(c.mutators, mutators...)
+	}
+}
+
+// WithStrictDecoding makes the client fail to decode JSON response bodies that contain fields
+// not defined in the design.
+func WithStrictDecoding() Option {
+	return func(c *Client) {
+		c.strictJSON = true
+		c.registerJSONDecoder()
+	}
+}
+
+// WithNumberDecoding makes the client decode the JSON numbers held in response body fields of
+// type interface{} into json.Number values rather than float64 values so that large integers
+// keep their precision, see goaclient.Int64.
+func WithNumberDecoding() Option {
+	return func(c *Client) {
+		c.numberJSON = true
+		c.registerJSONDecoder()
+	}
+}
+
+// WithStreamingThreshold makes the request builders stream the payloads whose encoded size exceeds
+// n bytes using the chunked transfer encoding instead of reading them in memory. Smaller payloads
+// are buffered so that the requests can be retried, the requests streaming their payloads are
+// never retried. Payloads are always buffered by default. The option has no effect on the
+// requests that set the Content-MD5 header which requires the complete body.
+func WithStreamingThreshold(n int64) Option {
+	return func(c *Client) {
+		c.streaming = n
+	}
+}
+
+// WithMaxResponseBytes makes the decode helpers fail with ErrResponseTooLarge when the body of
+// the response, once decompressed, is larger than n bytes. There is no limit by default.
+func WithMaxResponseBytes(n int64) Option {
+	return func(c *Client) {
+		c.maxBody = n
+	}
+}
+
+// WithDecompressor makes the decode helpers decompress the bodies of the responses whose
+// Content-Encoding header is encoding with f, for example to support brotli. gzip and deflate
+// are supported by default.
+func WithDecompressor(encoding string, f func(io.Reader) (io.Reader, error)) Option {
+	return func(c *Client) {
+		if c.decompressors == nil {
+			c.decompressors = make(map[string]func(io.Reader) (io.Reader, error))
+		}
+		c.decompressors[strings.ToLower(encoding)] = f
+	}
+}
+
+// WithAcceptGzip sets the Accept-Encoding header of the requests to gzip. The http client leaves
+// the response bodies compressed when the header is set explicitly, the decode helpers decompress
+// them. The bodies of the responses returned as is, e.g. by the stream methods, must be
+// decompressed by the caller.
+func WithAcceptGzip() Option {
+	return WithDefaultHeader("Accept-Encoding", "gzip")
+}
+
+// registerJSONDecoder registers the JSON decoder configured with WithStrictDecoding and
+// WithNumberDecoding.
+func (c *Client) registerJSONDecoder() {
+{{ range .Decoders }}{{ if and (eq .PackageName "goa") (eq .Function "NewJSONDecoder") }}{{/*
+*/}}	strict, numbers := c.strictJSON, c.numberJSON
+	c.Decoder.Register(func(r io.Reader) goa.Decoder {
+		if r == nil {
+			// Register makes a decoder with a nil reader to check whether it can be pooled.
+			r = strings.NewReader("")
+		}
+		d := json.NewDecoder(r)
+		if strict {
+			d.DisallowUnknownFields()
+		}
+		if numbers {
+			d.UseNumber()
+		}
+		return d
+	}, "{{ joinStrings .MIMETypes "\", \"" }}"{{ if .Default }}, "*/*"{{ end }})
+{{ end }}{{ end }}}
+
+{{ range $security := .API.SecuritySchemes }}{{ if eq (signerType $security) "goaclient.BasicSigner" }}{{/*
+*/}}{{ $signer := printf "%sSigner" (goify $security.SchemeName true) }}{{/*
+*/}}// With{{ goify $security.SchemeName true }}Auth sets the credentials used by the {{ $signer }} to sign requests.
+func With{{ goify $security.SchemeName true }}Auth(username, password string) Option {
+	return func(c *Client) {
+		c.{{ $signer }}.Username = username
+		c.{{ $signer }}.Password = password
+	}
+}
+
+{{ else if eq (signerType $security) "goaclient.AWSV4Signer" }}{{/*
+*/}}{{ $signer := printf "%sSigner" (goify $security.SchemeName true) }}{{/*
+*/}}// With{{ goify $security.SchemeName true }}Credentials sets the AWS credentials and region used by the {{ $signer }} to
+// sign requests with the AWS Signature Version 4 algorithm.
+func With{{ goify $security.SchemeName true }}Credentials(accessKeyID, secretAccessKey, region string) Option {
+	return func(c *Client) {
+		c.{{ $signer }}.AccessKeyID = accessKeyID
+		c.{{ $signer }}.SecretAccessKey = secretAccessKey
+		c.{{ $signer }}.Region = region
+	}
+}
+
+{{ end }}{{ end }}// WithRateLimit limits the rate of requests sent by the client to r requests per second with
+// bursts of at most b requests. Calls block until the limiter allows them or their context is done.
+func WithRateLimit(r float64, b int) Option {
+	return func(c *Client) {
+		c.limiter = goaclient.NewRateLimiter(r, b)
+	}
+}
+
+// WithMetrics makes the client report an observation to m after each call.
+func WithMetrics(m Metrics) Option {
+	return func(c *Client) {
+		c.metrics = m
+	}
+}
+
+// WithTracer makes the client start a span with t for each call and inject its trace context in
+// the request headers.
+func WithTracer(t Tracer) Option {
+	return func(c *Client) {
+		c.tracer = t
+	}
+}
+
+// WithDump makes the client write the raw requests it sends and the raw responses it receives to
+// w, this is meant for debugging. Response bodies are read in memory before being returned.
+func WithDump(w io.Writer) Option {
+	return func(c *Client) {
+		c.dump = w
+	}
+}
+
+// WithTrailingSlash makes the client add a trailing slash to the request paths if enabled is true
+// and remove it otherwise. Request paths are used as is by default.
+func WithTrailingSlash(enabled bool) Option {
+	return func(c *Client) {
+		c.slash = &enabled
+	}
+}
+
+// WithBasePath makes the client prefix the request paths with basePath, for example when the API
+// is served behind a reverse proxy under a path prefix.
+func WithBasePath(basePath string) Option {
+	return func(c *Client) {
+		c.basePath = basePath
+	}
+}
+
+// WithHTTP2 makes the client send requests using HTTP/2. HTTP/2 is negotiated with ALPN over TLS
+// connections unless h2c is true in which case all requests are sent using HTTP/2 over cleartext
+// TCP connections, this requires prior knowledge that the server supports h2c. The option has no
+// effect on the requests sent by clients created with NewWithDoer.
+func WithHTTP2(h2c bool) Option {
+	return func(c *Client) {
+		c.http2 = &h2c
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used by the client transport, for example to trust
+// custom certificate authorities or to present client certificates. The option may be combined
+// with WithHTTP2 and has no effect on the requests sent by clients created with NewWithDoer.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(c *Client) {
+		c.tlsConfig = cfg
+	}
+}
+
+// WithUnixSocket makes the client send requests over connections to the unix domain socket at
+// path, for example to reach a local agent or a sidecar. The requests are sent using the http
+// scheme to a placeholder host, the request paths are unchanged. The option may be combined with
+// WithHTTP2, with WithHTTP2(false) the TLS handshake is made over the socket connections and the
+// client Scheme must be set to https. The option has no effect on the requests sent by clients
+// created with NewWithDoer.
+func WithUnixSocket(path string) Option {
+	return func(c *Client) {
+		c.unixSocket = path
+		c.Scheme = "http"
+		c.Host = "unix"
+	}
+}
+
+// WithMaxIdleConns sets the maximum number of idle connections kept open by the client
+// transport, in total and per host. The transport keeps at most 2 idle connections per host by
+// default which limits the reuse of connections by clients sending many concurrent requests to
+// the same host. The option may be combined with WithTLSConfig and WithUnixSocket, it is ignored
+// by the transport installed with WithHTTP2 which multiplexes the requests over one connection
+// per host and has no effect on the requests sent by clients created with NewWithDoer.
+func WithMaxIdleConns(n int) Option {
+	return func(c *Client) {
+		c.maxIdleConns = n
+	}
+}
+
+// WithMaxConnsPerHost limits the number of connections, including the connections in use, that
+// the client transport opens to each host. Requests wait for a connection once the limit is
+// reached. The option may be combined with WithTLSConfig and WithUnixSocket, it is ignored by
+// the transport installed with WithHTTP2 which multiplexes the requests over one connection per
+// host and has no effect on the requests sent by clients created with NewWithDoer.
+func WithMaxConnsPerHost(n int) Option {
+	return func(c *Client) {
+		c.maxConns = n
+	}
+}
+
+// WithoutRedirects makes the client return the redirect responses instead of following them. The
+// option has no effect on the requests sent by clients created with NewWithDoer.
+func WithoutRedirects() Option {
+	return func(c *Client) {
+		// Copy the http client so that clients shared with other code, e.g.
+		// http.DefaultClient, keep following redirects.
+		hc := *c.Client.Client
+		hc.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+		c.Client.Client = &hc
+	}
+}
+
+// WithRedirectAuth makes the client keep the Authorization header and the other headers set by
+// the signers when following redirects to another host, they are removed from the redirected
+// requests by default. The option has no effect on the requests sent by clients created with
+// NewWithDoer.
+func WithRedirectAuth() Option {
+	return func(c *Client) {
+		c.redirectAuth = true
+	}
+}
+
+// New instantiates the client.
+func New(c *http.Client, opts ...Option) *Client {
+	return newClient(goaclient.New(c), opts)
+}
+
+// NewWithDoer instantiates a client that sends requests using doer instead of a *http.Client.
+func NewWithDoer(doer goaclient.Doer, opts ...Option) *Client {
+	return newClient(goaclient.NewWithDoer(doer), opts)
+}
+
+// NewWithConfig instantiates a client configured with cfg.
+func NewWithConfig(cfg Config) *Client {
+	var opts []Option
+	if cfg.Timeout > 0 {
+		opts = append(opts, WithTimeout(cfg.Timeout))
+	}
+	if cfg.BasePath != "" {
+		opts = append(opts, WithBasePath(cfg.BasePath))
+	}
+	for name, value := range cfg.Headers {
+		opts = append(opts, WithDefaultHeader(name, value))
+	}
+	client := New(nil, opts...)
+	if cfg.Host != "" {
+		client.Host = cfg.Host
+	}
+	client.Scheme = cfg.Scheme{{ range $security := .API.SecuritySchemes }}{{/*
+*/}}{{ $signer := signerType $security }}{{ $name := goify $security.SchemeName true }}{{/*
+*/}}{{ if eq $signer "goaclient.BasicSigner" }}
+	client.{{ $name }}Signer.Username = cfg.{{ $name }}Username
+	client.{{ $name }}Signer.Password = cfg.{{ $name }}Password{{/*
+*/}}{{ else if eq $signer "goaclient.APIKeySigner" }}
+	client.{{ $name }}Signer.Key = cfg.{{ $name }}Key{{/*
+*/}}{{ else if eq $signer "goaclient.JWTSigner" }}
+	client.{{ $name }}Signer.Token = cfg.{{ $name }}Token{{/*
+*/}}{{ else if eq $signer "goaclient.OAuth2Signer" }}
+	client.{{ $name }}Signer.RefreshURLFormat = cfg.{{ $name }}RefreshURLFormat
+	client.{{ $name }}Signer.RefreshToken = cfg.{{ $name }}RefreshToken{{/*
+*/}}{{ else if eq $signer "goaclient.AWSV4Signer" }}
+	client.{{ $name }}Signer.AccessKeyID = cfg.{{ $name }}AccessKeyID
+	client.{{ $name }}Signer.SecretAccessKey = cfg.{{ $name }}SecretAccessKey
+	client.{{ $name }}Signer.Region = cfg.{{ $name }}Region{{ end }}{{ end }}
+	return client
+}
+
+// newClient initializes the signers, encoders and decoders of a client wrapping c then applies
+// the options.
+func newClient(c *goaclient.Client, opts []Option) *Client {
+	c.UserAgent = "{{ .API.Name }}-client/{{ .Version }}"
+	client := &Client{
+		Client: c,{{range $security := .API.SecuritySchemes }}{{ $signer := signerType $security }}{{ if $signer }}
+		{{ goify $security.SchemeName true }}Signer: &{{ $signer }}{{ printf "{%s}" (signerFields $security) }},{{ end }}{{ end }}
+		Encoder: goa.NewHTTPEncoder(),
+		Decoder: goa.NewHTTPDecoder(),
+	}
+
+{{ if .Encoders }}	// Setup encoders and decoders
+{{ range .Encoders }}{{/*
+*/}}	client.Encoder.Register({{ .PackageName }}.{{ .Function }}, "{{ joinStrings .MIMETypes "\", \"" }}")
+{{ end }}{{ range .Decoders }}{{/*
+*/}}	client.Decoder.Register({{ .PackageName }}.{{ .Function }}, "{{ joinStrings .MIMETypes "\", \"" }}")
+{{ end }}
+
+	// Setup default encoder and decoder
+{{ range .Encoders }}{{ if .Default }}{{/*
+*/}}	client.Encoder.Register({{ .PackageName }}.{{ .Function }}, "*/*")
+{{ end }}{{ end }}{{ range .Decoders }}{{ if .Default }}{{/*
+*/}}	client.Decoder.Register({{ .PackageName }}.{{ .Function }}, "*/*")
+{{ end }}{{ end }}
+{{ end }}	for _, opt := range opts {
+		opt(client)
+	}
+	client.setupTransport()
+	client.setupRedirects()
+	return client
+}
+
+// Clone returns a copy of the client that can be configured without affecting c, e.g. to change
+// the host or the default headers. The copy shares the underlying http client, the signers, the
+// encoders and the decoders with c.
+func (c *Client) Clone() *Client {
+	clone := *c
+	gc := *c.Client
+	clone.Client = &gc
+	if c.headers != nil {
+		clone.headers = make(http.Header, len(c.headers))
+		for name, values := range c.headers {
+			clone.headers[name] = append([]string(nil), values...)
+		}
+	}
+	clone.forwarded = append([]string(nil), c.forwarded...)
+	clone.mutators = append([]func(*http.Request) error(nil), c.mutators...)
+	if c.recording != nil {
+		clone.recording = &recording{}
+	}
+	return &clone
+}
+
+// Encode encodes v into w using the encoder registered for the content type ct, the default
+// encoder if ct is empty or has no registered encoder. This is the encoding applied to the request
+// payloads.
+func (c *Client) Encode(v interface{}, w io.Writer, ct string) error {
+	return c.Encoder.Encode(v, w, ct)
+}
+
+// Decode decodes the data read from r into v using the decoder registered for the content type
+// ct, the default decoder if ct is empty or has no registered decoder. This is the decoding
+// applied to the response bodies.
+func (c *Client) Decode(v interface{}, r io.Reader, ct string) error {
+	return c.Decoder.Decode(v, r, ct)
+}
+
+// SetTransport makes the client send requests with rt, for example to wrap the transport of the
+// client with a recorder in tests. The http client is copied so that clients sharing it, e.g.
+// clients created with Clone, keep their transport.
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	hc := *c.Client.Client
+	hc.Transport = rt
+	c.Client.Client = &hc
+}
+
+// setupTransport installs the transport configured with WithHTTP2, WithTLSConfig,
+// WithUnixSocket, WithMaxIdleConns and WithMaxConnsPerHost if any. The http client is copied so that the transport of clients shared with
+// other code, e.g. http.DefaultClient, is left untouched, the other settings such as Timeout are
+// preserved.
+func (c *Client) setupTransport() {
+	if c.http2 == nil && c.tlsConfig == nil && c.unixSocket == "" && c.maxIdleConns == 0 && c.maxConns == 0 {
+		return
+	}
+	var rt http.RoundTripper
+	if c.http2 != nil {
+		var dial goaclient.DialFunc
+		if *c.http2 || c.unixSocket != "" {
+			dial = c.dial
+		}
+		rt = goaclient.NewHTTP2Transport(c.tlsConfig, *c.http2, dial)
+	} else {
+		t, ok := c.Client.Client.Transport.(*http.Transport)
+		if !ok {
+			t = http.DefaultTransport.(*http.Transport)
+		}
+		t = t.Clone()
+		if c.tlsConfig != nil {
+			t.TLSClientConfig = c.tlsConfig
+		}
+		if c.unixSocket != "" {
+			t.DialContext = c.dial
+		}
+		if c.maxIdleConns > 0 {
+			t.MaxIdleConns = c.maxIdleConns
+			t.MaxIdleConnsPerHost = c.maxIdleConns
+		}
+		if c.maxConns > 0 {
+			t.MaxConnsPerHost = c.maxConns
+		}
+		rt = t
+	}
+	hc := *c.Client.Client
+	hc.Transport = rt
+	c.Client.Client = &hc
+}
+
+// setupRedirects makes the http client remove the headers set by the signers from the requests
+// redirected to another host unless the client was created with WithRedirectAuth. The redirect
+// policy of the http client, e.g. the one set by WithoutRedirects, applies once the headers are
+// removed. The http client is copied so that clients shared with other code, e.g.
+// http.DefaultClient, are left untouched.
+func (c *Client) setupRedirects() {
+	if c.redirectAuth {
+		return
+	}
+	hc := *c.Client.Client
+	check := hc.CheckRedirect
+	hc.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+			for _, name := range c.authHeaders() {
+				req.Header.Del(name)
+			}
+		}
+		if check != nil {
+			return check(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	c.Client.Client = &hc
+}
+
+// authHeaders returns the names of the headers holding credentials set by the signers of the
+// client.
+func (c *Client) authHeaders() []string {
+	headers := []string{"Authorization", "Cookie"}{{ range $security := .API.SecuritySchemes }}{{/*
+*/}}{{ $signer := signerType $security }}{{ $name := goify $security.SchemeName true }}{{/*
+*/}}{{ if or (eq $signer "goaclient.APIKeySigner") (eq $signer "goaclient.JWTSigner") }}
+	if c.{{ $name }}Signer.Header != "" {
+		headers = append(headers, c.{{ $name }}Signer.Header)
+	}{{ else if eq $signer "goaclient.AWSV4Signer" }}
+	headers = append(headers, "X-Amz-Date", "X-Amz-Security-Token"){{ end }}{{/*
+*/}}{{ if nonceScheme $security }}
+	headers = append(headers, "X-Nonce", "X-Timestamp"){{ end }}{{ end }}
+	return headers
+}
+
+// dial opens a connection to addr or to the unix domain socket set with WithUnixSocket if any.
+func (c *Client) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	var d net.Dialer
+	if c.unixSocket != "" {
+		return d.DialContext(ctx, "unix", c.unixSocket)
+	}
+	return d.DialContext(ctx, network, addr)
+}
+
+// send sends req. If the client was created with WithDump the request and the response are
+// written to the dump writer.
+func (c *Client) send(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if c.dump == nil {
+		return c.sendWithContext(ctx, req)
+	}
+	dump, err := httputil.DumpRequestOut(req, true)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(c.dump, "%s\n", dump)
+	resp, err := c.sendWithContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	// DumpResponse replaces the response body with an in-memory copy so that it can still be
+	// read by the caller.
+	dump, err = httputil.DumpResponse(resp, true)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	fmt.Fprintf(c.dump, "%s\n", dump)
+	return resp, nil
 }
 
-type byParamName []*paramData
+// sendWithContext sends req. If the client was created with WithContext the request is cancelled
+// when the client context is done and sendWithContext fails with ErrShutdown.
+func (c *Client) sendWithContext(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if c.ctx == nil {
+		return c.sendWithRetries(ctx, req)
+	}
+	if c.ctx.Err() != nil {
+		return nil, ErrShutdown
+	}
+	reqCtx, cancel := context.WithCancel(req.Context())
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-c.ctx.Done():
+			cancel()
+		case <-done:
+		}
+	}()
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			close(done)
+			cancel()
+		})
+	}
+	resp, err := c.sendWithRetries(ctx, req.WithContext(reqCtx))
+	if err != nil {
+		stop()
+		if c.ctx.Err() != nil {
+			return nil, ErrShutdown
+		}
+		return nil, err
+	}
+	resp.Body = &stopOnClose{ReadCloser: resp.Body, stop: stop}
+	return resp, nil
+}
 
-func (b byParamName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
-func (b byParamName) Less(i, j int) bool { return b[i].Name < b[j].Name }
-func (b byParamName) Len() int           { return len(b) }
+// stopOnClose is a response body that releases the resources used to watch the client context
+// when closed.
+type stopOnClose struct {
+	io.ReadCloser
+	stop func()
+}
 
-const arrayToStringT = `	{{ $tmp := tempvar }}{{ $tmp }} := make([]string, len({{ .Name }}))
-	for i, e := range {{ .Name }} {
-		{{ $tmp2 := tempvar }}{{ toString "e" $tmp2 .ElemType }}
-		{{ $tmp }}[i] = {{ $tmp2 }}
+// Close closes the response body.
+func (b *stopOnClose) Close() error {
+	b.stop()
+	return b.ReadCloser.Close()
+}
+
+// LastRequest returns the last request sent by a client created with WithRecording, nil if there
+// is none.
+func (c *Client) LastRequest() *http.Request {
+	if c.recording == nil {
+		return nil
 	}
-	{{ .Target }} := strings.Join({{ $tmp }}, ",")`
+	c.recording.Lock()
+	defer c.recording.Unlock()
+	return c.recording.req
+}
 
-const payloadTmpl = `// {{ gotypename .Payload nil 0 false }} is the {{ .Parent.Name }} {{ .Name }} action payload.
-type {{ gotypename .Payload nil 1 false }} {{ gotypedef .Payload 0 true false }}
-`
+// LastResponse returns the response to the last request sent by a client created with
+// WithRecording, nil if there is none or if the request failed. The response body may have been
+// read and closed already.
+func (c *Client) LastResponse() *http.Response {
+	if c.recording == nil {
+		return nil
+	}
+	c.recording.Lock()
+	defer c.recording.Unlock()
+	return c.recording.resp
+}
 
-const userTypeTmpl = `// {{ gotypedesc . true }}
-type {{ gotypename . .AllRequired 1 false }} {{ gotypedef . 0 true false }}
-`
+// record records req and resp if the client was created with WithRecording.
+func (c *Client) record(req *http.Request, resp *http.Response) {
+	if c.recording == nil {
+		return
+	}
+	c.recording.Lock()
+	defer c.recording.Unlock()
+	c.recording.req = req
+	c.recording.resp = resp
+}
 
-const typeDecodeTmpl = `{{ $typeName := typeName . }}{{ $funcName := printf "Decode%s" $typeName }}// {{ $funcName }} decodes the {{ $typeName }} instance encoded in resp body.
-func (c *Client) {{ $funcName }}(resp *http.Response) ({{ gotyperef . .AllRequired 0 false }}, error) {
-	var decoded {{ gotypename . .AllRequired 0 false }}
-	err := c.Decoder.Decode(&decoded, resp.Body, resp.Header.Get("Content-Type"))
-	return {{ if .IsObject }}&{{ end }}decoded, err
+// writeFixture writes req to the fixture directory set with WithFixtureDir if any. Request bodies
+// that cannot be read again are buffered so that req can still be sent.
+func (c *Client) writeFixture(name string, req *http.Request) error {
+	if c.fixtures == nil {
+		return nil
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		if err := setReplayableBody(req, req.Body); err != nil {
+			return err
+		}
+	}
+	f, err := goaclient.NewFixture(req)
+	if err != nil {
+		return err
+	}
+	c.fixtures.Lock()
+	c.fixtures.count++
+	file := filepath.Join(c.fixtures.dir, fmt.Sprintf("%s-%d.json", name, c.fixtures.count))
+	c.fixtures.Unlock()
+	if err := f.Write(file); err != nil {
+		return fmt.Errorf("failed to write fixture: %s", err)
+	}
+	return nil
 }
-`
 
-const pathTmpl = `{{ $funcName := printf "%sPath%s" (goify (printf "%s%s" .Route.Parent.Name (title .Route.Parent.Parent.Name)) true) ((or (and .Index (add .Index 1)) "") | printf "%v") }}{{/*
-*/}}{{ with .Route }}// {{ $funcName }} computes a request path to the {{ .Parent.Name }} action of {{ .Parent.Parent.Name }}.
-func {{ $funcName }}({{ pathParams . }}) string {
-	return fmt.Sprintf("{{ pathTemplate . }}", {{ pathParamNames . }})
+// cachedResponse returns the response to req held by the cache set with WithCache, nil if there
+// is none or if it expired.
+func (c *Client) cachedResponse(req *http.Request) *http.Response {
+	if c.cache == nil || (req.Method != "GET" && req.Method != "HEAD") {
+		return nil
+	}
+	c.cache.Lock()
+	defer c.cache.Unlock()
+	e, ok := c.cache.entries[c.cacheKey(req)]
+	if !ok || time.Now().After(e.expires) {
+		return nil
+	}
+	return &http.Response{
+		Status:        e.status,
+		StatusCode:    e.code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        cloneHeader(e.header),
+		Body:          ioutil.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
 }
-{{ end }}`
 
-const clientsTmpl = `{{ $funcName := goify (printf "%s%s" .Name (title .ResourceName)) true }}{{ $desc := .Description }}{{/*
-*/}}{{ if $desc }}{{ multiComment $desc }}{{ else }}{{/*
-*/}}// {{ $funcName }} makes a request to the {{ .Name }} action endpoint of the {{ .ResourceName }} resource{{ end }}
-func (c *Client) {{ $funcName }}(ctx context.Context, path string{{ if .Params}},  {{ .Params }}{{ end }}) (*http.Response, error) {
-	req, err := c.New{{ $funcName }}Request(ctx, path{{ if .ParamNames }}, {{ .ParamNames }}{{ end }})
+// cacheResponse stores resp in the cache set with WithCache if req is a GET or HEAD request and
+// resp is a successful response. The response body is read and replaced with an in-memory copy.
+func (c *Client) cacheResponse(req *http.Request, resp *http.Response) (*http.Response, error) {
+	if c.cache == nil || (req.Method != "GET" && req.Method != "HEAD") {
+		return resp, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp, nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
 	if err != nil {
 		return nil, err
 	}
-	return c.Client.Do(ctx, req)
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	c.cache.Lock()
+	defer c.cache.Unlock()
+	key := c.cacheKey(req)
+	if _, ok := c.cache.entries[key]; !ok {
+		if c.cache.max > 0 && len(c.cache.keys) >= c.cache.max {
+			delete(c.cache.entries, c.cache.keys[0])
+			c.cache.keys = c.cache.keys[1:]
+		}
+		c.cache.keys = append(c.cache.keys, key)
+	}
+	c.cache.entries[key] = &cacheEntry{
+		status:  resp.Status,
+		code:    resp.StatusCode,
+		header:  cloneHeader(resp.Header),
+		body:    body,
+		expires: time.Now().Add(c.cache.ttl),
+	}
+	return resp, nil
 }
-`
 
-const clientsWSTmpl = `{{ $funcName := goify (printf "%s%s" .Name (title .ResourceName)) true }}{{ $desc := .Description }}{{/*
-*/}}{{ if $desc }}{{ multiComment $desc }}{{ else }}// {{ $funcName }} establishes a websocket connection to the {{ .Name }} action endpoint of the {{ .ResourceName }} resource{{ end }}
-func (c *Client) {{ $funcName }}(ctx context.Context, path string{{ if .Params }}, {{ .Params }}{{ end }}) (*websocket.Conn, error) {
-	scheme := c.Scheme
-	if scheme == "" {
-		scheme = "{{ .CanonicalScheme }}"
+// cacheKey returns the key identifying req in a responseCache and in the in-flight requests, see
+// authHeaders for the credential headers it includes.
+func (c *Client) cacheKey(req *http.Request) string {
+	parts := []string{req.Method, req.URL.String(), req.Header.Get("Accept")}
+	for _, name := range c.authHeaders() {
+		parts = append(parts, name+":"+strings.Join(req.Header[http.CanonicalHeaderKey(name)], ","))
 	}
-	u := url.URL{Host: c.Host, Scheme: scheme, Path: path}
-{{ if .QueryParams }}	values := u.Query()
-{{ range .QueryParams }}{{ if .CheckNil }}	if {{ .VarName }} != nil {
-	{{ end }}{{ if .MustToString}}{{ $tmp := tempvar }}	{{ toString .ValueName $tmp .Attribute }}
-	values.Set("{{ .Name }}", {{ $tmp }})
-{{ else }}	values.Set("{{ .Name }}", {{ .ValueName }})
-{{ end }}{{ if .CheckNil }}	}
-{{ end }}{{ end }}	u.RawQuery = values.Encode()
-{{ end }}	return websocket.Dial(u.String(), "", u.String())
+	return strings.Join(parts, "\n")
 }
-`
 
-const requestsTmpl = `{{ $funcName := goify (printf "New%s%sRequest" (title .Name) (title .ResourceName)) true }}{{/*
-*/}}// {{ $funcName }} create the request corresponding to the {{ .Name }} action endpoint of the {{ .ResourceName }} resource.
-func (c *Client) {{ $funcName }}(ctx context.Context, path string{{ if .Params }}, {{ .Params }}{{ end }}) (*http.Request, error) {
-{{ if .HasPayload }}	var body bytes.Buffer
-	err := c.Encoder.Encode(payload, &body, "*/*") // Use default encoder
+// sendShared sends req or waits for the response to an identical request already in flight if
+// the client was created with WithSingleflight and req is a GET or HEAD request.
+func (c *Client) sendShared(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if c.flight == nil || (req.Method != "GET" && req.Method != "HEAD") {
+		return c.send(ctx, req)
+	}
+	v, err, _ := c.flight.Do(c.cacheKey(req), func() (interface{}, error) {
+		resp, err := c.send(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return &sharedResponse{resp: resp, body: body}, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to encode body: %s", err)
+		return nil, err
 	}
-{{ end }}	scheme := c.Scheme
-	if scheme == "" {
-		scheme = "{{ .CanonicalScheme }}"
+	shared := v.(*sharedResponse)
+	resp := *shared.resp
+	resp.Header = cloneHeader(shared.resp.Header)
+	resp.Body = ioutil.NopCloser(bytes.NewReader(shared.body))
+	resp.Request = req
+	return &resp, nil
+}
+
+// cloneHeader returns a deep copy of h.
+func cloneHeader(h http.Header) http.Header {
+	clone := make(http.Header, len(h))
+	for name, values := range h {
+		clone[name] = append([]string(nil), values...)
 	}
-	u := url.URL{Host: c.Host, Scheme: scheme, Path: path}
-{{ if .QueryParams }}	values := u.Query()
-{{ range .QueryParams }}{{ if .CheckNil }}	if {{ .VarName }} != nil {
-	{{ end }}{{ if .MustToString }}{{ $tmp := tempvar }}	{{ toString .ValueName $tmp .Attribute }}
-	values.Set("{{ .Name }}", {{ $tmp }})
-{{ else }}	values.Set("{{ .Name }}", {{ .ValueName }})
-{{ end }}{{ if .CheckNil }}	}
-{{ end }}{{ end }}	u.RawQuery = values.Encode()
-{{ end }}{{ if .HasPayload }}	req, err := http.NewRequest({{ $route := index .Routes 0 }}"{{ $route.Verb }}", u.String(), &body)
-{{ else }}	req, err := http.NewRequest({{ $route := index .Routes 0 }}"{{ $route.Verb }}", u.String(), nil)
-{{ end }}	if err != nil {
+	return clone
+}
+
+// forwardHeaders copies the headers set with WithForwardedHeaders from the goa request held by ctx
+// if any to req.
+func (c *Client) forwardHeaders(ctx context.Context, req *http.Request) {
+	if len(c.forwarded) == 0 {
+		return
+	}
+	incoming := goa.ContextRequest(ctx)
+	if incoming == nil || incoming.Request == nil {
+		return
+	}
+	for _, name := range c.forwarded {
+		if values := incoming.Header[http.CanonicalHeaderKey(name)]; len(values) > 0 {
+			req.Header[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+		}
+	}
+}
+
+// Batch sends the given requests, typically built with the New<Action>Request methods, with at
+// most the number of concurrent requests set with WithConcurrency and returns their results in
+// the same order. The requests that are not sent yet once ctx is done fail with the context error
+// and the requests in flight are cancelled. The caller must close the bodies of the responses.
+func (c *Client) Batch(ctx context.Context, reqs []*http.Request) []BatchResult {
+	n := c.concurrency
+	if n <= 0 {
+		n = 1
+	}
+	results := make([]BatchResult, len(reqs))
+	sem := make(chan struct{}, n)
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			for j := i; j < len(reqs); j++ {
+				results[j].Err = ctx.Err()
+			}
+			wg.Wait()
+			return results
+		}
+		wg.Add(1)
+		go func(i int, req *http.Request) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			resp, err := c.sendCancelable(ctx, req)
+			results[i] = BatchResult{Response: resp, Err: err}
+		}(i, req)
+	}
+	wg.Wait()
+	return results
+}
+
+// sendCancelable sends req cancelling it if ctx is done before the response body is closed.
+func (c *Client) sendCancelable(ctx context.Context, req *http.Request) (*http.Response, error) {
+	reqCtx, cancel := context.WithCancel(req.Context())
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-reqCtx.Done():
+		}
+	}()
+	resp, err := c.send(ctx, req.WithContext(reqCtx))
+	return cancelOnClose(resp, err, cancel)
+}
+
+// withTimeout returns a context derived from ctx that is cancelled after the timeout set with
+// WithTimeout, it returns ctx and a nil cancel function if there is none.
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return ctx, nil
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// cancelOnClose calls cancel once the body of resp is closed so that the call context remains
+// valid while the body is read. cancel is called right away if the call failed.
+func cancelOnClose(resp *http.Response, err error, cancel context.CancelFunc) (*http.Response, error) {
+	if cancel == nil {
+		return resp, err
+	}
+	if err != nil {
+		cancel()
+		return resp, err
+	}
+	resp.Body = &stopOnClose{ReadCloser: resp.Body, stop: cancel}
+	return resp, nil
+}
+
+// requestPath returns path prefixed with the base path set with WithBasePath and the prefix
+// returned by PathPrefixFunc and with a trailing slash added or removed as configured with
+// WithTrailingSlash.
+func (c *Client) requestPath(ctx context.Context, path string) string {
+	if c.PathPrefixFunc != nil {
+		path = strings.TrimRight(c.PathPrefixFunc(ctx), "/") + path
+	}
+	if c.basePath != "" {
+		path = strings.TrimRight(c.basePath, "/") + path
+	}
+	if c.slash == nil {
+		return path
+	}
+	trimmed := strings.TrimRight(path, "/")
+	if *c.slash || trimmed == "" {
+		return trimmed + "/"
+	}
+	return trimmed
+}
+
+// responseBody returns a reader of the body of resp decompressed according to its
+// Content-Encoding header. The http client only decompresses gzip encoded bodies when it sets the
+// Accept-Encoding header itself.
+func (c *Client) responseBody(resp *http.Response) (io.Reader, error) {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if f, ok := c.decompressors[encoding]; ok {
+		return f(resp.Body)
+	}
+	switch encoding {
+	case "", "identity":
+		return resp.Body, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(resp.Body)
+	case "deflate":
+		// deflate bodies should be zlib streams but some servers send raw deflate data
+		br := bufio.NewReader(resp.Body)
+		if h, err := br.Peek(2); err == nil && h[0]&0x0f == 8 && (uint(h[0])<<8|uint(h[1]))%31 == 0 {
+			return zlib.NewReader(br)
+		}
+		return flate.NewReader(br), nil
+	}
+	return nil, fmt.Errorf("unsupported response Content-Encoding %q", encoding)
+}
+
+// limitBody returns a reader of body that fails with ErrResponseTooLarge once more than the
+// number of bytes set with WithMaxResponseBytes are read, body if there is no limit.
+func (c *Client) limitBody(body io.Reader) io.Reader {
+	if c.maxBody <= 0 {
+		return body
+	}
+	return &limitedReader{r: io.LimitReader(body, c.maxBody+1), max: c.maxBody}
+}
+
+// limitedReader is a reader that fails with ErrResponseTooLarge once more than max bytes are read.
+type limitedReader struct {
+	r    io.Reader
+	max  int64
+	read int64
+}
+
+// Read reads from the underlying reader.
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.max {
+		return n, ErrResponseTooLarge
+	}
+	return n, err
+}
+
+// DecodeProblem decodes the RFC 7807 problem details encoded in resp body, see
+// goaclient.IsProblem.
+func (c *Client) DecodeProblem(resp *http.Response) (*goaclient.Problem, error) {
+	body, err := c.responseBody(resp)
+	if err != nil {
 		return nil, err
 	}
-{{ if .Headers }}	header := req.Header
-{{ range .Headers }}{{ if .CheckNil }}	if {{ .VarName }} != nil {
-	{{ end }}{{ if .MustToString }}{{ $tmp := tempvar }}	{{ toString .ValueName $tmp .Attribute }}
-	header.Set("{{ .Name }}", {{ $tmp }}){{ else }}
-	header.Set("{{ .Name }}", {{ .ValueName }})
-{{ end }}{{ if .CheckNil }}	}
-{{ end }}{{ end }}{{ end }}{{ if .Signer }}	c.{{ .Signer }}Signer.Sign(ctx, req)
-{{ end }}	return req, nil
+	var problem goaclient.Problem
+	if err := json.NewDecoder(c.limitBody(body)).Decode(&problem); err != nil {
+		return nil, err
+	}
+	return &problem, nil
 }
-`
 
-const clientTmpl = `// Client is the {{ .API.Name }} service client.
-type Client struct {
-	*goaclient.Client{{range $security := .API.SecuritySchemes }}{{ $signer := signerType $security }}{{ if $signer }}
-	{{ goify $security.SchemeName true }}Signer *{{ $signer }}{{ end }}{{ end }}
-	Encoder *goa.HTTPEncoder
-	Decoder *goa.HTTPDecoder
+// followLink sends a GET request to href, the client host and scheme are used if href is a path.
+func (c *Client) followLink(ctx context.Context, href string) (*http.Response, error) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		u.Host = c.Host
+	}
+	if u.Scheme == "" {
+		u.Scheme = c.Scheme
+		if u.Scheme == "" {
+			u.Scheme = "{{ .Scheme }}"
+		}
+	}
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for name, values := range c.headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	for _, mutate := range c.mutators {
+		if err := mutate(req); err != nil {
+			return nil, err
+		}
+	}
+	return c.send(ctx, req)
 }
 
-// New instantiates the client.
-func New(c *http.Client) *Client {
-	client := &Client{
-		Client: goaclient.New(c),{{range $security := .API.SecuritySchemes }}{{ $signer := signerType $security }}{{ if $signer }}
-		{{ goify $security.SchemeName true }}Signer: &{{ $signer }}{},{{ end }}{{ end }}
-		Encoder: goa.NewHTTPEncoder(),
-		Decoder: goa.NewHTTPDecoder(),
+// observe reports the call to action that started at start and received resp to the client
+// metrics if any.
+func (c *Client) observe(action string, start time.Time, resp *http.Response) {
+	if c.metrics == nil {
+		return
 	}
+	var status int
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	c.metrics.ObserveRequest(action, status, time.Since(start))
+}
 
-{{ if .Encoders }}	// Setup encoders and decoders
-{{ range .Encoders }}{{/*
-*/}}	client.Encoder.Register({{ .PackageName }}.{{ .Function }}, "{{ joinStrings .MIMETypes "\", \"" }}")
-{{ end }}{{ range .Decoders }}{{/*
-*/}}	client.Decoder.Register({{ .PackageName }}.{{ .Function }}, "{{ joinStrings .MIMETypes "\", \"" }}")
-{{ end }}
+// startSpan starts the span of the call to action with the client tracer and injects its trace
+// context in the headers of req. It returns nil if the client has no tracer.
+func (c *Client) startSpan(ctx context.Context, action string, req *http.Request) Span {
+	if c.tracer == nil {
+		return nil
+	}
+	span := c.tracer.StartSpan(ctx, action)
+	span.Inject(req.Header)
+	return span
+}
 
-	// Setup default encoder and decoder
-{{ range .Encoders }}{{ if .Default }}{{/*
-*/}}	client.Encoder.Register({{ .PackageName }}.{{ .Function }}, "*/*")
-{{ end }}{{ end }}{{ range .Decoders }}{{ if .Default }}{{/*
-*/}}	client.Decoder.Register({{ .PackageName }}.{{ .Function }}, "*/*")
-{{ end }}{{ end }}
-{{ end }}	return client
+// finishSpan finishes span, if not nil, with the status code of resp and err.
+func finishSpan(span Span, resp *http.Response, err error) {
+	if span == nil {
+		return
+	}
+	var status int
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	span.Finish(status, err)
+}
+
+// sendWithRetries sends req retrying as configured with WithRetries, the request body is rewound
+// before each retry. Requests whose body length is unknown (-1) are streamed and never retried,
+// neither are the requests carrying a nonce.
+func (c *Client) sendWithRetries(ctx context.Context, req *http.Request) (*http.Response, error) {
+	retries := c.retries
+	if !isIdempotent(req) || req.ContentLength < 0 || req.Header.Get("X-Nonce") != "" {
+		retries = 0
+	}
+	if retries > 0 && req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		if err := setReplayableBody(req, req.Body); err != nil {
+			return nil, err
+		}
+	}
+	if retries > 0 && req.Header.Get(middleware.RequestIDHeader) == "" {
+		id, err := goaclient.NewNonce()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(middleware.RequestIDHeader, id)
+	}
+	for attempt := 0; ; attempt++ {
+		if retries > 0 {
+			req.Header.Set("X-Attempt", strconv.Itoa(attempt+1))
+		}
+		if c.limiter != nil {
+			if err := c.limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+		resp, err := c.Client.Do(ctx, req)
+		if attempt >= retries || !shouldRetry(resp, err) {
+			return resp, err
+		}
+		delay := retryDelay(resp, attempt)
+		if expiresWithin(ctx, delay) || expiresWithin(req.Context(), delay) {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to rewind body: %s", err)
+			}
+			req.Body = body
+		}
+	}
+}
+
+// isIdempotent returns true if req may be sent more than once, that is if it uses an idempotent
+// verb or carries an Idempotency-Key header.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// shouldRetry returns true if the outcome of a request is a transient failure.
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryDelay returns the time to wait for before retrying the request that received resp at the
+// given attempt. It is the value of the Retry-After response header given either in seconds or as
+// an HTTP date if any and a linear backoff otherwise.
+func retryDelay(resp *http.Response, attempt int) time.Duration {
+	backoff := time.Duration(attempt+1) * 100 * time.Millisecond
+	if resp == nil {
+		return backoff
+	}
+	retryAfter := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if retryAfter == "" {
+		return backoff
+	}
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(retryAfter); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+		return 0
+	}
+	return backoff
+}
+
+// expiresWithin returns true if the deadline of ctx, if any, is less than d away.
+func expiresWithin(ctx context.Context, d time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return ok && time.Until(deadline) < d
+}
+
+// encodeBody encodes payload into body using the encoder registered for contentType. If the
+// client was created with WithStreamingThreshold and the encoded payload is larger than the
+// threshold, body only contains the beginning of the encoded payload and the returned reader
+// streams the rest of it as it is encoded. Closing the reader stops the encoding.
+func (c *Client) encodeBody(payload interface{}, body *bytes.Buffer, contentType string) (io.ReadCloser, error) {
+	if c.streaming <= 0 {
+		return nil, c.Encoder.Encode(payload, body, contentType)
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(c.Encoder.Encode(payload, pw, contentType))
+	}()
+	if _, err := io.CopyN(body, pr, c.streaming+1); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return pr, nil
+}
+
+// streamBody makes req send the content of its body followed by rest using the chunked transfer
+// encoding, see encodeBody. The request body cannot be replayed.
+func streamBody(req *http.Request, rest io.ReadCloser) {
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(req.Body, rest), rest}
+	req.ContentLength = -1
+	req.GetBody = nil
+}
+
+// setReplayableBody sets the body of req so that it can be sent again when the request is retried.
+// Bodies implementing io.ReadSeeker are rewound to their current offset, other bodies are read
+// into memory.
+func setReplayableBody(req *http.Request, body io.Reader) error {
+	if seeker, ok := body.(io.ReadSeeker); ok {
+		offset, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return fmt.Errorf("failed to seek body: %s", err)
+		}
+		req.Body = ioutil.NopCloser(seeker)
+		req.GetBody = func() (io.ReadCloser, error) {
+			if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+				return nil, err
+			}
+			return ioutil.NopCloser(seeker), nil
+		}
+		return nil
+	}
+	b, err := ioutil.ReadAll(body)
+	if closer, ok := body.(io.Closer); ok {
+		closer.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read body: %s", err)
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	req.ContentLength = int64(len(b))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(b)), nil
+	}
+	return nil
 }
 `
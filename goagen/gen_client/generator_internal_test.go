@@ -0,0 +1,44 @@
+package genclient
+
+import (
+	"github.com/goadesign/goa/design"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("goTypeRefExt", func() {
+	var bottle *design.MediaTypeDefinition
+
+	BeforeEach(func() {
+		bottle = &design.MediaTypeDefinition{
+			UserTypeDefinition: &design.UserTypeDefinition{
+				AttributeDefinition: &design.AttributeDefinition{
+					Type: design.Object{"id": &design.AttributeDefinition{Type: design.Integer}},
+				},
+				TypeName: "Bottle",
+			},
+			Identifier: "application/vnd.bottle+json",
+		}
+	})
+
+	It("qualifies user types", func() {
+		Ω(goTypeRefExt(bottle, 0, "client")).Should(Equal("client.Bottle"))
+	})
+
+	It("does not qualify primitive types", func() {
+		Ω(goTypeRefExt(design.Integer, 0, "client")).Should(Equal("int"))
+	})
+
+	It("qualifies the element types of arrays", func() {
+		array := &design.Array{ElemType: &design.AttributeDefinition{Type: bottle}}
+		Ω(goTypeRefExt(array, 0, "client")).Should(Equal("[]*client.Bottle"))
+	})
+
+	It("qualifies the key and element types of hashes", func() {
+		hash := &design.Hash{
+			KeyType:  &design.AttributeDefinition{Type: design.String},
+			ElemType: &design.AttributeDefinition{Type: &design.Array{ElemType: &design.AttributeDefinition{Type: bottle}}},
+		}
+		Ω(goTypeRefExt(hash, 0, "client")).Should(Equal("map[string][]*client.Bottle"))
+	})
+})
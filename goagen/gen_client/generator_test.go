@@ -1,12 +1,16 @@
 package genclient_test
 
 import (
+	"go/parser"
+	"go/token"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/goadesign/goa/design"
+	"github.com/goadesign/goa/dslengine"
 	"github.com/goadesign/goa/goagen/codegen"
 	"github.com/goadesign/goa/goagen/gen_client"
 	. "github.com/onsi/ginkgo"
@@ -36,6 +40,66 @@ var _ = Describe("Generate", func() {
 		os.RemoveAll(outDir)
 	})
 
+	Context("with an output directory inside a Go module outside the GOPATH", func() {
+		var root, oldGopath string
+
+		BeforeEach(func() {
+			var err error
+			root, err = ioutil.TempDir("", "genclient")
+			Ω(err).ShouldNot(HaveOccurred())
+			err = ioutil.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n"), 0644)
+			Ω(err).ShouldNot(HaveOccurred())
+			oldGopath = os.Getenv("GOPATH")
+			os.Setenv("GOPATH", filepath.Join(root, "nonexistent-gopath"))
+			os.Args = []string{"goagen", "client", "--out=" + filepath.Join(root, "gen"), "--design=foo"}
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name:   "show",
+								Routes: []*design.RouteDefinition{{Verb: "GET", Path: ""}},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			showAct := fooRes.Actions["show"]
+			showAct.Parent = fooRes
+			showAct.Routes[0].Parent = showAct
+		})
+
+		AfterEach(func() {
+			os.Setenv("GOPATH", oldGopath)
+			os.RemoveAll(root)
+		})
+
+		It("generates the client package and the CLI", func() {
+			Ω(genErr).Should(BeNil())
+			Ω(files).Should(ContainElement(filepath.Join(root, "gen", "client", "client.go")))
+			Ω(files).Should(ContainElement(filepath.Join(root, "gen", "client", "foo.go")))
+			content, err := ioutil.ReadFile(filepath.Join(root, "gen", "client", "testapi-cli", "main.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring(`"example.com/app/gen/client"`))
+		})
+
+		Context("without the CLI", func() {
+			BeforeEach(func() {
+				os.Args = append(os.Args, "--no-cli")
+			})
+
+			It("generates the client package", func() {
+				Ω(genErr).Should(BeNil())
+				content, err := ioutil.ReadFile(filepath.Join(root, "gen", "client", "foo.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).Should(ContainSubstring("func (c *Client) ShowFoo("))
+			})
+		})
+	})
+
 	Context("with an action with multiple routes", func() {
 		BeforeEach(func() {
 			design.Design = &design.APIDefinition{
@@ -70,7 +134,7 @@ var _ = Describe("Generate", func() {
 
 		It("generates Path function with unique names", func() {
 			Ω(genErr).Should(BeNil())
-			Ω(files).Should(HaveLen(7))
+			Ω(files).Should(HaveLen(8))
 			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
 			Ω(err).ShouldNot(HaveOccurred())
 			Ω(content).Should(ContainSubstring("func ShowFooPath("))
@@ -78,44 +142,237 @@ var _ = Describe("Generate", func() {
 			Ω(content).Should(ContainSubstring("func ShowFooPath2("))
 			Ω(strings.Count(string(content), "func ShowFooPath2(")).Should(Equal(1))
 		})
+
+		It("documents all the routes of the action methods", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("resource\n//\n// GET /\n// GET /foo\nfunc (c *Client) ShowFoo(ctx context.Context"))
+		})
 	})
 
-	Context("with an action with security configured", func() {
+	Context("with an action with headers", func() {
 		BeforeEach(func() {
-			codegen.TempCount = 0
-			securitySchemeDef := &design.SecuritySchemeDefinition{
-				SchemeName: "jwt-1",
-				Kind:       design.JWTSecurityKind,
-			}
 			design.Design = &design.APIDefinition{
-				Name:        "testapi",
-				Title:       "dummy API with no resource",
-				Description: "I told you it's dummy",
-				SecuritySchemes: []*design.SecuritySchemeDefinition{
-					securitySchemeDef,
-				},
+				Name: "testapi",
 				Resources: map[string]*design.ResourceDefinition{
 					"foo": {
 						Name: "foo",
 						Actions: map[string]*design.ActionDefinition{
 							"show": {
 								Name: "show",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: ""},
+								},
+								Headers: &design.AttributeDefinition{
+									Type: design.Object{
+										"X-Api-Version": &design.AttributeDefinition{Type: design.String},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			showAct := fooRes.Actions["show"]
+			showAct.Parent = fooRes
+			showAct.Routes[0].Parent = showAct
+		})
+
+		It("applies the default headers before the action headers", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithDefaultHeader(name, value string) Option {"))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			defaults := strings.Index(string(content), "range c.headers")
+			Ω(defaults).Should(BeNumerically(">", 0))
+			Ω(strings.Index(string(content), "header.Set(")).Should(BeNumerically(">", defaults))
+		})
+
+		It("sends the headers with the names defined in the design", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring(`header.Set("X-Api-Version", *xApiVersion)`))
+			Ω(content).ShouldNot(ContainSubstring(`header.Set("xApiVersion"`))
+		})
+
+		It("generates a bearer token option independent of the security schemes", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithBearerToken(token string) Option {"))
+			Ω(content).Should(ContainSubstring(`return WithDefaultHeader("Authorization", "Bearer "+token)`))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("range c.headers"))
+			Ω(content).ShouldNot(ContainSubstring(".Sign(req)"))
+		})
+
+		It("forwards the headers of the goa request found in the context", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithForwardedHeaders(names ...string) Option {"))
+			Ω(content).Should(ContainSubstring("incoming := goa.ContextRequest(ctx)"))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			forward := strings.Index(string(content), "c.forwardHeaders(ctx, req)")
+			Ω(forward).Should(BeNumerically(">", strings.Index(string(content), "range c.headers")))
+			Ω(strings.Index(string(content), `header.Set("X-Api-Version", *xApiVersion)`)).Should(BeNumerically(">", forward))
+		})
+
+		It("sets the request ID found in the context", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring(`"github.com/goadesign/goa/middleware"`))
+			Ω(content).Should(ContainSubstring(`if id := middleware.ContextRequestID(ctx); id != "" {`))
+			Ω(content).Should(ContainSubstring("req.Header.Set(middleware.RequestIDHeader, id)"))
+		})
+
+		It("does not check optional headers", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).ShouldNot(ContainSubstring("missing required"))
+		})
+
+		Context("that are required", func() {
+			BeforeEach(func() {
+				headers := design.Design.Resources["foo"].Actions["show"].Headers
+				headers.Validation = &dslengine.ValidationDefinition{Required: []string{"X-Api-Version"}}
+			})
+
+			It("fails to build the requests if a required header is empty", func() {
+				Ω(genErr).Should(BeNil())
+				content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).Should(ContainSubstring("func (c *Client) NewShowFooRequest(ctx context.Context, path string, xApiVersion string) (*http.Request, error) {\n" +
+					"\tif xApiVersion == \"\" {\n\t\treturn nil, fmt.Errorf(\"missing required X-Api-Version header\")\n\t}\n"))
+				Ω(content).Should(ContainSubstring(`header.Set("X-Api-Version", xApiVersion)`))
+			})
+		})
+	})
+
+	Context("with optional query parameters and headers", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"list": {
+								Name: "list",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: ""},
+								},
 								QueryParams: &design.AttributeDefinition{
 									Type: design.Object{
-										"param": &design.AttributeDefinition{Type: design.Integer},
-										"time":  &design.AttributeDefinition{Type: design.DateTime},
-										"uuid":  &design.AttributeDefinition{Type: design.UUID},
+										"limit": &design.AttributeDefinition{Type: design.Integer},
+										"uid":   &design.AttributeDefinition{Type: design.UUID},
+										"ids":   &design.AttributeDefinition{Type: &design.Array{ElemType: &design.AttributeDefinition{Type: design.Integer}}},
 									},
 								},
+								Headers: &design.AttributeDefinition{
+									Type: design.Object{
+										"X-Count": &design.AttributeDefinition{Type: design.Integer},
+										"X-Trace": &design.AttributeDefinition{Type: design.UUID},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			listAct := fooRes.Actions["list"]
+			listAct.Parent = fooRes
+			listAct.Routes[0].Parent = listAct
+		})
+
+		It("checks the parameters for nil before dereferencing them", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) NewListFooRequest(ctx context.Context, path string, ids []int, limit *int, uid *string, xCount *int, xTrace *string) (*http.Request, error) {"))
+			for _, v := range []string{"limit", "uid", "xCount", "xTrace"} {
+				derefs := regexp.MustCompile(`\*`+v+`\b`).FindAllIndex(content, -1)
+				guarded := regexp.MustCompile(`if `+v+` != nil \{\n\t*\w+ := [^\n]*\*`+v+`\b`).FindAllIndex(content, -1)
+				Ω(derefs).ShouldNot(BeEmpty(), v)
+				Ω(guarded).Should(HaveLen(len(derefs)), v)
+			}
+			Ω(content).Should(MatchRegexp(`if ids != nil \{\n\t*\w+ := make\(\[\]string, len\(ids\)\)`))
+		})
+	})
+
+	Context("with an action with wildcards in multiple routes", func() {
+		BeforeEach(func() {
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name: "show",
 								Routes: []*design.RouteDefinition{
-									{
-										Verb: "GET",
-										Path: "",
+									{Verb: "GET", Path: "/:id"},
+									{Verb: "GET", Path: "/:id/files/*name"},
+								},
+								Params: &design.AttributeDefinition{
+									Type: design.Object{
+										"id":   &design.AttributeDefinition{Type: design.String},
+										"name": &design.AttributeDefinition{Type: design.String},
 									},
 								},
-								Security: &design.SecurityDefinition{
-									Scheme: securitySchemeDef,
+								QueryParams: &design.AttributeDefinition{Type: design.Object{}},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			showAct := fooRes.Actions["show"]
+			showAct.Parent = fooRes
+			showAct.Routes[0].Parent = showAct
+			showAct.Routes[1].Parent = showAct
+		})
+
+		It("generates a function parsing the paths of all the routes", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func ParseShowFooPath(path string) (string, string, bool) {"))
+			Ω(content).Should(ContainSubstring("regexp.MustCompile(`^/([^/]+)$`)"))
+			Ω(content).Should(ContainSubstring("regexp.MustCompile(`^/([^/]+)/files/(.*)$`)"))
+			Ω(content).Should(ContainSubstring(`return values[0], "", true`))
+			Ω(content).Should(ContainSubstring(`return values[0], values[1], true`))
+		})
+	})
+
+	Context("with an action with a wildcard named path", func() {
+		BeforeEach(func() {
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name:   "show",
+								Routes: []*design.RouteDefinition{{Verb: "GET", Path: "/files/*path"}},
+								Params: &design.AttributeDefinition{
+									Type: design.Object{
+										"path": &design.AttributeDefinition{Type: design.String},
+									},
 								},
+								QueryParams: &design.AttributeDefinition{Type: design.Object{}},
 							},
 						},
 					},
@@ -127,21 +384,5214 @@ var _ = Describe("Generate", func() {
 			showAct.Routes[0].Parent = showAct
 		})
 
-		It("generates the correct client Fields", func() {
+		It("generates a function parsing the paths", func() {
 			Ω(genErr).Should(BeNil())
-			Ω(files).Should(HaveLen(7))
-			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			out, err := runGeneratedTest(filepath.Join(outDir, "client"), parsePathTest)
+			Ω(err).ShouldNot(HaveOccurred(), out)
+		})
+	})
+
+	Context("with validated paths enabled", func() {
+		BeforeEach(func() {
+			os.Args = append(os.Args, "--validated-paths")
+			min := 1.0
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name:   "show",
+								Routes: []*design.RouteDefinition{{Verb: "GET", Path: "/:id"}},
+								Params: &design.AttributeDefinition{
+									Type: design.Object{
+										"id": &design.AttributeDefinition{
+											Type:       design.Integer,
+											Validation: &dslengine.ValidationDefinition{Minimum: &min},
+										},
+									},
+								},
+								QueryParams: &design.AttributeDefinition{Type: design.Object{}},
+							},
+							"list": {
+								Name:   "list",
+								Routes: []*design.RouteDefinition{{Verb: "GET", Path: ""}},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			for _, a := range fooRes.Actions {
+				a.Parent = fooRes
+				a.Routes[0].Parent = a
+			}
+		})
+
+		It("validates the path parameters in the path factories", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
 			Ω(err).ShouldNot(HaveOccurred())
-			Ω(content).Should(ContainSubstring("JWT1Signer *goaclient.JWTSigner"))
-			Ω(content).Should(ContainSubstring("JWT1Signer: &goaclient.JWTSigner{},"))
+			Ω(content).Should(ContainSubstring("func ShowFooPath(id int) (string, error) {\n\tvar err error\n"))
+			Ω(content).Should(ContainSubstring("if id < 1 {\n\t\terr = goa.MergeErrors(err, goa.InvalidRangeError(`id`, id, 1, true))\n\t}"))
+			Ω(content).Should(ContainSubstring("if err != nil {\n\t\treturn \"\", err\n\t}\n\treturn fmt.Sprintf(\"/%s\", url.PathEscape(fmt.Sprintf(\"%v\", id))), nil"))
+			Ω(content).Should(ContainSubstring("func ListFooPath() (string, error) {\n\treturn \"/\", nil\n}"))
 		})
+	})
 
-		It("generates the Signer.Sign call from Action", func() {
+	Context("with an action with a UUID path parameter", func() {
+		BeforeEach(func() {
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name: "show",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: "/:id"},
+								},
+								Params: &design.AttributeDefinition{
+									Type: design.Object{
+										"id": &design.AttributeDefinition{Type: design.UUID},
+									},
+								},
+								QueryParams: &design.AttributeDefinition{Type: design.Object{}},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			showAct := fooRes.Actions["show"]
+			showAct.Parent = fooRes
+			showAct.Routes[0].Parent = showAct
+		})
+
+		It("generates a path factory accepting a UUID value", func() {
 			Ω(genErr).Should(BeNil())
-			Ω(files).Should(HaveLen(7))
 			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
 			Ω(err).ShouldNot(HaveOccurred())
-			Ω(content).Should(ContainSubstring("c.JWT1Signer.Sign(ctx, req)"))
+			Ω(content).Should(ContainSubstring("func ShowFooPath(id uuid.UUID) string {"))
+			Ω(content).Should(ContainSubstring(`return fmt.Sprintf("/%s", url.PathEscape(id.String()))`))
+		})
+
+		It("documents the route of the methods", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("resource\n//\n// GET /:id\nfunc (c *Client) ShowFoo(ctx context.Context"))
+			Ω(content).Should(ContainSubstring("resource.\n//\n// GET /:id\nfunc (c *Client) NewShowFooRequest(ctx context.Context"))
 		})
 	})
-})
+
+	Context("with multiple actions using temporary variables", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			query := func() *design.AttributeDefinition {
+				return &design.AttributeDefinition{
+					Type: design.Object{
+						"limit": &design.AttributeDefinition{Type: design.Integer},
+					},
+				}
+			}
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"list": {
+								Name:        "list",
+								Routes:      []*design.RouteDefinition{{Verb: "GET", Path: ""}},
+								QueryParams: query(),
+							},
+							"search": {
+								Name:        "search",
+								Routes:      []*design.RouteDefinition{{Verb: "GET", Path: "/search"}},
+								QueryParams: query(),
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			for _, a := range fooRes.Actions {
+				a.Parent = fooRes
+				a.Routes[0].Parent = a
+			}
+		})
+
+		It("numbers the temporary variables of each function independently", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(strings.Count(string(content), "tmp1 := strconv.Itoa(*limit)")).Should(Equal(2))
+
+			codegen.TempCount = 42
+			_, err = genclient.Generate()
+			Ω(err).ShouldNot(HaveOccurred())
+			regenerated, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(regenerated).Should(Equal(content))
+		})
+	})
+
+	Context("with an action declaring the http and https schemes", func() {
+		BeforeEach(func() {
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name:    "show",
+								Schemes: []string{"http", "https"},
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: ""},
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			showAct := fooRes.Actions["show"]
+			showAct.Parent = fooRes
+			showAct.Routes[0].Parent = showAct
+		})
+
+		It("uses https by default", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("scheme := c.Scheme\n\tif scheme == \"\" {\n\t\tscheme = \"https\"\n\t}"))
+		})
+
+		Context("with wss instead of https", func() {
+			BeforeEach(func() {
+				design.Design.Resources["foo"].Actions["show"].Schemes = []string{"http", "wss"}
+			})
+
+			It("uses https by default", func() {
+				Ω(genErr).Should(BeNil())
+				content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).Should(ContainSubstring(`scheme = "https"`))
+			})
+		})
+	})
+
+	Context("with an action using the fields convention", func() {
+		BeforeEach(func() {
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"list": {
+								Name: "list",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: ""},
+								},
+								Metadata: dslengine.MetadataDefinition{"client:fields": nil},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			listAct := fooRes.Actions["list"]
+			listAct.Parent = fooRes
+			listAct.Routes[0].Parent = listAct
+		})
+
+		It("generates a fields argument serialized in the query string", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) ListFoo(ctx context.Context, path string, fields []string) (*http.Response, error) {"))
+			Ω(content).Should(ContainSubstring(`values.Set("fields", `))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "commands.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring(`cc.Flags().StringSliceVar(&cmd.Fields, "fields", fields, `))
+		})
+
+		Context("declaring the fields parameter with another type", func() {
+			BeforeEach(func() {
+				design.Design.Resources["foo"].Actions["list"].QueryParams = &design.AttributeDefinition{
+					Type: design.Object{
+						"fields": &design.AttributeDefinition{Type: design.String},
+					},
+				}
+			})
+
+			It("returns an error", func() {
+				Ω(genErr).Should(HaveOccurred())
+				Ω(genErr.Error()).Should(ContainSubstring("must be an array of strings"))
+			})
+		})
+	})
+
+	Context("with a query parameter of an array of user types", func() {
+		BeforeEach(func() {
+			filter := &design.UserTypeDefinition{
+				AttributeDefinition: &design.AttributeDefinition{
+					Type: design.Object{
+						"field": &design.AttributeDefinition{Type: design.String},
+					},
+				},
+				TypeName: "Filter",
+			}
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"list": {
+								Name: "list",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: ""},
+								},
+								QueryParams: &design.AttributeDefinition{
+									Type: design.Object{
+										"filters": &design.AttributeDefinition{
+											Type:     &design.Array{ElemType: &design.AttributeDefinition{Type: filter}},
+											Metadata: dslengine.MetadataDefinition{"client:json": nil},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			listAct := fooRes.Actions["list"]
+			listAct.Parent = fooRes
+			listAct.Routes[0].Parent = listAct
+		})
+
+		It("encodes the parameter value in JSON", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring(":= json.Marshal(filters)"))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "commands.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("json.Unmarshal([]byte(cmd.Filters), &filtersParam)"))
+			Ω(content).Should(ContainSubstring(`cc.Flags().StringVar(&cmd.Filters, "filters", filters, `))
+		})
+
+		Context("without the client:json metadata", func() {
+			BeforeEach(func() {
+				params := design.Design.Resources["foo"].Actions["list"].QueryParams
+				params.Type.ToObject()["filters"].Metadata = nil
+			})
+
+			It("returns an error", func() {
+				Ω(genErr).Should(HaveOccurred())
+				Ω(genErr.Error()).Should(ContainSubstring("parameter filters"))
+			})
+
+			Context("and another resource with an unsupported parameter", func() {
+				BeforeEach(func() {
+					barRes := &design.ResourceDefinition{
+						Name: "bar",
+						Actions: map[string]*design.ActionDefinition{
+							"list": {
+								Name: "list",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: ""},
+								},
+								QueryParams: &design.AttributeDefinition{
+									Type: design.Object{
+										"range": &design.AttributeDefinition{
+											Type: design.Object{
+												"min": &design.AttributeDefinition{Type: design.Integer},
+											},
+										},
+									},
+								},
+							},
+						},
+					}
+					design.Design.Resources["bar"] = barRes
+					listAct := barRes.Actions["list"]
+					listAct.Parent = barRes
+					listAct.Routes[0].Parent = listAct
+				})
+
+				It("reports both parameters", func() {
+					Ω(genErr).Should(HaveOccurred())
+					Ω(genErr).Should(BeAssignableToTypeOf(dslengine.MultiError{}))
+					Ω(genErr.(dslengine.MultiError)).Should(HaveLen(2))
+					Ω(genErr.Error()).Should(ContainSubstring("list action of bar: parameter range"))
+					Ω(genErr.Error()).Should(ContainSubstring("list action of foo: parameter filters"))
+				})
+			})
+		})
+	})
+
+	Context("with an action whose only route has a wildcard", func() {
+		BeforeEach(func() {
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name: "show",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: "/:id"},
+								},
+								Params: &design.AttributeDefinition{
+									Type: design.Object{
+										"id": &design.AttributeDefinition{Type: design.String},
+									},
+								},
+								QueryParams: &design.AttributeDefinition{Type: design.Object{}},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			showAct := fooRes.Actions["show"]
+			showAct.Parent = fooRes
+			showAct.Routes[0].Parent = showAct
+		})
+
+		It("generates a command requiring the request path", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "commands.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring(`cc.Flags().StringVar(&cmd.RequestPath, "request-path", "", `))
+			Ω(content).Should(ContainSubstring(`case cmd.RequestPath != "":`))
+			Ω(content).Should(ContainSubstring(`return fmt.Errorf("missing request path, set it as argument, with --request-path or with the --id flag")`))
+			Ω(content).Should(ContainSubstring(`path = fmt.Sprintf("/%v", cmd.ID)`))
+			Ω(content).ShouldNot(ContainSubstring(`"route-index"`))
+		})
+
+		Context("and a second route", func() {
+			BeforeEach(func() {
+				showAct := design.Design.Resources["foo"].Actions["show"]
+				showAct.Routes = append(showAct.Routes, &design.RouteDefinition{Verb: "GET", Path: "/:id/alt", Parent: showAct})
+			})
+
+			It("generates a flag selecting the route", func() {
+				Ω(genErr).Should(BeNil())
+				content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "commands.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).Should(ContainSubstring(`cc.Flags().IntVar(&cmd.RouteIndex, "route-index", -1, "Index of the route built from the path parameter flags (0: GET /:id, 1: GET /:id/alt)")`))
+				Ω(content).Should(ContainSubstring(`path = fmt.Sprintf("/%v/alt", cmd.ID)`))
+				Ω(content).Should(ContainSubstring(`return fmt.Errorf("invalid route index %d, the action has 2 routes", cmd.RouteIndex)`))
+				Ω(content).Should(ContainSubstring("ctx = goaclient.WithRouteIndex(ctx, cmd.RouteIndex)"))
+			})
+		})
+	})
+
+	Context("with descriptions containing special characters", func() {
+		const desc = "Show the `foo`\r\nwith\ttabs and \x01control characters"
+
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name:        "foo",
+						Description: desc,
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name:        "show",
+								Description: desc,
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: ""},
+								},
+								Params: &design.AttributeDefinition{
+									Type: design.Object{
+										"name": &design.AttributeDefinition{Type: design.String, Description: desc},
+									},
+								},
+								QueryParams: &design.AttributeDefinition{
+									Type: design.Object{
+										"name": &design.AttributeDefinition{Type: design.String, Description: desc},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			showAct := fooRes.Actions["show"]
+			showAct.Parent = fooRes
+			showAct.Routes[0].Parent = showAct
+		})
+
+		It("generates valid code", func() {
+			Ω(genErr).Should(BeNil())
+			for _, f := range files {
+				if filepath.Ext(f) != ".go" {
+					continue
+				}
+				_, err := parser.ParseFile(token.NewFileSet(), f, nil, 0)
+				Ω(err).ShouldNot(HaveOccurred())
+			}
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("// Show the `foo`\n// with\ttabs and control characters\n"))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "commands.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("`Show the `+\"`\"+`foo`+\"`\"+`\nwith\ttabs and control characters`"))
+		})
+	})
+
+	Context("with a media type with a tiny view", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			attrs := design.Object{
+				"id":   &design.AttributeDefinition{Type: design.Integer},
+				"name": &design.AttributeDefinition{Type: design.String},
+				"bio":  &design.AttributeDefinition{Type: design.String},
+			}
+			userMT := &design.MediaTypeDefinition{
+				UserTypeDefinition: &design.UserTypeDefinition{
+					AttributeDefinition: &design.AttributeDefinition{Type: attrs},
+					TypeName:            "User",
+				},
+				Identifier: "application/vnd.user+json",
+			}
+			userMT.Views = map[string]*design.ViewDefinition{
+				"default": {
+					AttributeDefinition: &design.AttributeDefinition{Type: attrs},
+					Name:                "default",
+					Parent:              userMT,
+				},
+				"tiny": {
+					AttributeDefinition: &design.AttributeDefinition{
+						Type: design.Object{
+							"id":   &design.AttributeDefinition{Type: design.Integer},
+							"name": &design.AttributeDefinition{Type: design.String},
+						},
+					},
+					Name:   "tiny",
+					Parent: userMT,
+				},
+			}
+			design.Design = &design.APIDefinition{
+				Name:       "testapi",
+				MediaTypes: map[string]*design.MediaTypeDefinition{userMT.Identifier: userMT},
+				Resources: map[string]*design.ResourceDefinition{
+					"user": {
+						Name: "user",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name: "show",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: ""},
+								},
+								Responses: map[string]*design.ResponseDefinition{
+									"OK": {Name: "OK", Status: 200, MediaType: userMT.Identifier},
+								},
+							},
+						},
+					},
+				},
+			}
+			design.GeneratedMediaTypes = make(design.MediaTypeRoot)
+			userRes := design.Design.Resources["user"]
+			showAct := userRes.Actions["show"]
+			showAct.Parent = userRes
+			showAct.Routes[0].Parent = showAct
+		})
+
+		It("generates the MarshalTinyJSON method", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "datatypes.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (mt *User) MarshalTinyJSON() ([]byte, error) {"))
+			Ω(content).Should(ContainSubstring(`for _, n := range []string{"id", "name"} {`))
+		})
+
+		It("generates decode helpers for each view", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "datatypes.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) DecodeUser(resp *http.Response) (*User, error) {"))
+			Ω(content).Should(ContainSubstring("type UserTiny struct {"))
+			Ω(content).Should(ContainSubstring("func (c *Client) DecodeUserTiny(resp *http.Response) (*UserTiny, error) {"))
+			tiny := string(content[strings.Index(string(content), "type UserTiny struct {"):])
+			tiny = tiny[:strings.Index(tiny, "}")]
+			Ω(tiny).Should(ContainSubstring("Name"))
+			Ω(tiny).ShouldNot(ContainSubstring("Bio"))
+		})
+
+		It("wraps the decoding errors with the response context", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "datatypes.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("if err == ErrResponseTooLarge {\n\t\t\treturn nil, err\n\t\t}\n\t\treturn nil, goaclient.NewDecodeError(resp, snippet.Snippet(), err)\n"))
+		})
+
+		It("decompresses the response bodies before decoding them", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "datatypes.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("body, err := c.responseBody(resp)\n\tif err != nil {\n\t\treturn nil, err\n\t}\n\tsnippet := goaclient.NewSnippetReader(c.limitBody(body))\n\tvar decoded User\n"))
+			Ω(content).Should(ContainSubstring(`if err := c.Decoder.Decode(&decoded, snippet, resp.Header.Get("Content-Type")); err != nil {`))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring(`case "deflate":`))
+			Ω(content).Should(ContainSubstring("return zlib.NewReader(br)"))
+			Ω(content).Should(ContainSubstring("return flate.NewReader(br), nil"))
+			Ω(content).Should(ContainSubstring("func WithDecompressor(encoding string, f func(io.Reader) (io.Reader, error)) Option {"))
+			Ω(content).Should(ContainSubstring("func WithAcceptGzip() Option {\n\treturn WithDefaultHeader(\"Accept-Encoding\", \"gzip\")\n}"))
+		})
+
+		It("accepts the view requested in the Accept header", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "user.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) ShowUser(ctx context.Context, path string, view string) (*http.Response, error) {"))
+			Ω(content).Should(ContainSubstring("func (c *Client) NewShowUserRequest(ctx context.Context, path string, view string) (*http.Request, error) {"))
+			Ω(content).Should(ContainSubstring(`req.Header.Set("Accept", "application/vnd.user+json; view="+view)`))
+			commands, err := ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "commands.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(commands).Should(ContainSubstring(`cc.Flags().StringVar(&cmd.View, "view", "", "Name of the User view to request")`))
+			Ω(commands).Should(ContainSubstring("c.ShowUser(ctx, path, cmd.View)"))
+		})
+	})
+
+	Context("with an action with success and error responses", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			userMT := &design.MediaTypeDefinition{
+				UserTypeDefinition: &design.UserTypeDefinition{
+					AttributeDefinition: &design.AttributeDefinition{
+						Type: design.Object{"id": &design.AttributeDefinition{Type: design.Integer}},
+					},
+					TypeName: "User",
+				},
+				Identifier: "application/vnd.user+json",
+			}
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				MediaTypes: map[string]*design.MediaTypeDefinition{
+					userMT.Identifier:            userMT,
+					design.ErrorMedia.Identifier: design.ErrorMedia,
+				},
+				Resources: map[string]*design.ResourceDefinition{
+					"user": {
+						Name: "user",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name:   "show",
+								Routes: []*design.RouteDefinition{{Verb: "GET", Path: ""}},
+								Responses: map[string]*design.ResponseDefinition{
+									"OK":        {Name: "OK", Status: 200, MediaType: userMT.Identifier},
+									"NotFound":  {Name: "NotFound", Status: 404, MediaType: design.ErrorMedia.Identifier},
+									"NoContent": {Name: "NoContent", Status: 204},
+								},
+							},
+						},
+					},
+				},
+			}
+			design.GeneratedMediaTypes = make(design.MediaTypeRoot)
+			userRes := design.Design.Resources["user"]
+			showAct := userRes.Actions["show"]
+			showAct.Parent = userRes
+			showAct.Routes[0].Parent = showAct
+		})
+
+		It("generates a method decoding the response according to its status code", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "user.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("type ShowUserResult struct {"))
+			Ω(content).Should(MatchRegexp(`OK\s+\*User\n`))
+			Ω(content).Should(MatchRegexp(`NotFound\s+\*goa.Error\n`))
+			Ω(content).ShouldNot(MatchRegexp(`NoContent\s`))
+			Ω(content).Should(ContainSubstring("func (c *Client) ShowUserAndDecode(ctx context.Context, path string) (*ShowUserResult, error) {"))
+			Ω(content).Should(ContainSubstring("switch {\n\tcase goaclient.IsProblem(resp):\n\t\tresult.Problem, err = c.DecodeProblem(resp)\n" +
+				"\tcase resp.StatusCode == 200:\n\t\tresult.OK, err = c.DecodeUser(resp)\n\tcase resp.StatusCode == 404:\n\t\tresult.NotFound, err = c.DecodeError(resp)\n" +
+				"\tdefault:\n\t\tresult.Raw, err = ioutil.ReadAll(resp.Body)"))
+			Ω(content).Should(MatchRegexp(`Problem\s+\*goaclient.Problem\n`))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) DecodeProblem(resp *http.Response) (*goaclient.Problem, error) {"))
+		})
+	})
+
+	Context("with a media type with a required attribute", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			userMT := &design.MediaTypeDefinition{
+				UserTypeDefinition: &design.UserTypeDefinition{
+					AttributeDefinition: &design.AttributeDefinition{
+						Type: design.Object{
+							"id":   &design.AttributeDefinition{Type: design.Integer},
+							"name": &design.AttributeDefinition{Type: design.String},
+						},
+						Validation: &dslengine.ValidationDefinition{Required: []string{"name"}},
+					},
+					TypeName: "User",
+				},
+				Identifier: "application/vnd.user+json",
+			}
+			design.Design = &design.APIDefinition{
+				Name:       "testapi",
+				MediaTypes: map[string]*design.MediaTypeDefinition{userMT.Identifier: userMT},
+				Resources: map[string]*design.ResourceDefinition{
+					"user": {
+						Name: "user",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name:   "show",
+								Routes: []*design.RouteDefinition{{Verb: "GET", Path: ""}},
+								Responses: map[string]*design.ResponseDefinition{
+									"OK": {Name: "OK", Status: 200, MediaType: userMT.Identifier},
+								},
+							},
+						},
+					},
+				},
+			}
+			design.GeneratedMediaTypes = make(design.MediaTypeRoot)
+			userRes := design.Design.Resources["user"]
+			showAct := userRes.Actions["show"]
+			showAct.Parent = userRes
+			showAct.Routes[0].Parent = showAct
+		})
+
+		It("does not validate the decoded responses", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "datatypes.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).ShouldNot(ContainSubstring("Validate()"))
+		})
+
+		Context("with types only enabled", func() {
+			BeforeEach(func() {
+				os.Args = append(os.Args, "--types-only")
+				design.Design.Resources["user"].Actions["show"].Payload = &design.UserTypeDefinition{
+					AttributeDefinition: &design.AttributeDefinition{
+						Type: design.Object{
+							"name": &design.AttributeDefinition{Type: design.String},
+						},
+					},
+					TypeName: "ShowUserPayload",
+				}
+			})
+
+			It("only generates the data structures of the types", func() {
+				Ω(genErr).Should(BeNil())
+				clientDir := filepath.Join(outDir, "client")
+				Ω(files).Should(ConsistOf(clientDir, filepath.Join(clientDir, "datatypes.go")))
+				_, err := os.Stat(filepath.Join(clientDir, "testapi-cli"))
+				Ω(os.IsNotExist(err)).Should(BeTrue())
+				content, err := ioutil.ReadFile(filepath.Join(clientDir, "datatypes.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).Should(ContainSubstring("type User struct {"))
+				Ω(content).Should(ContainSubstring("type ShowUserPayload struct {"))
+				Ω(content).ShouldNot(ContainSubstring("Client"))
+				_, err = parser.ParseFile(token.NewFileSet(), "datatypes.go", content, 0)
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+		})
+
+		Context("with response validation enabled", func() {
+			BeforeEach(func() {
+				os.Args = append(os.Args, "--validate-responses")
+			})
+
+			It("validates the decoded responses", func() {
+				Ω(genErr).Should(BeNil())
+				content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "datatypes.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).Should(ContainSubstring("func (ut *User) Validate() (err error) {"))
+				Ω(content).Should(ContainSubstring("err = goa.MergeErrors(err, goa.MissingAttributeError(`response`, \"name\"))"))
+				Ω(content).Should(ContainSubstring("\n\t}\n\terr = decoded.Validate()\n\treturn &decoded, err"))
+			})
+		})
+	})
+
+	Context("with a media type with a string enum attribute", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			attrs := design.Object{
+				"id": &design.AttributeDefinition{Type: design.Integer},
+				"color": &design.AttributeDefinition{
+					Type:       design.String,
+					Validation: &dslengine.ValidationDefinition{Values: []interface{}{"red", "dark-green"}},
+				},
+			}
+			bottleMT := &design.MediaTypeDefinition{
+				UserTypeDefinition: &design.UserTypeDefinition{
+					AttributeDefinition: &design.AttributeDefinition{Type: attrs},
+					TypeName:            "Bottle",
+				},
+				Identifier: "application/vnd.bottle+json",
+			}
+			bottleMT.Views = map[string]*design.ViewDefinition{
+				"default": {
+					AttributeDefinition: &design.AttributeDefinition{Type: attrs},
+					Name:                "default",
+					Parent:              bottleMT,
+				},
+			}
+			design.Design = &design.APIDefinition{
+				Name:       "testapi",
+				MediaTypes: map[string]*design.MediaTypeDefinition{bottleMT.Identifier: bottleMT},
+				Resources: map[string]*design.ResourceDefinition{
+					"bottle": {
+						Name: "bottle",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name: "show",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: ""},
+								},
+								Responses: map[string]*design.ResponseDefinition{
+									"OK": {Name: "OK", Status: 200, MediaType: bottleMT.Identifier},
+								},
+							},
+						},
+					},
+				},
+			}
+			design.GeneratedMediaTypes = make(design.MediaTypeRoot)
+			bottleRes := design.Design.Resources["bottle"]
+			showAct := bottleRes.Actions["show"]
+			showAct.Parent = bottleRes
+			showAct.Routes[0].Parent = showAct
+		})
+
+		It("generates a named type and constants for the enum values", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "datatypes.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("type BottleColor string"))
+			Ω(content).Should(MatchRegexp(`BottleColorRed +BottleColor = "red"`))
+			Ω(content).Should(MatchRegexp(`BottleColorDarkGreen +BottleColor = "dark-green"`))
+			Ω(content).Should(MatchRegexp(`Color +\*BottleColor +` + "`" + `json:"color,omitempty"`))
+			Ω(strings.Count(string(content), "type BottleColor string")).Should(Equal(1))
+		})
+	})
+
+	Context("with a media type with links", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			accountAttrs := design.Object{
+				"id":   &design.AttributeDefinition{Type: design.Integer},
+				"href": &design.AttributeDefinition{Type: design.String},
+				"name": &design.AttributeDefinition{Type: design.String},
+			}
+			accountMT := &design.MediaTypeDefinition{
+				UserTypeDefinition: &design.UserTypeDefinition{
+					AttributeDefinition: &design.AttributeDefinition{Type: accountAttrs},
+					TypeName:            "Account",
+				},
+				Identifier: "application/vnd.account+json",
+			}
+			accountMT.Views = map[string]*design.ViewDefinition{
+				"default": {
+					AttributeDefinition: &design.AttributeDefinition{Type: accountAttrs},
+					Name:                "default",
+					Parent:              accountMT,
+				},
+				"link": {
+					AttributeDefinition: &design.AttributeDefinition{
+						Type: design.Object{
+							"id":   &design.AttributeDefinition{Type: design.Integer},
+							"href": &design.AttributeDefinition{Type: design.String},
+						},
+					},
+					Name:   "link",
+					Parent: accountMT,
+				},
+			}
+			bottleAttrs := design.Object{
+				"id":      &design.AttributeDefinition{Type: design.Integer},
+				"account": &design.AttributeDefinition{Type: accountMT},
+			}
+			bottleMT := &design.MediaTypeDefinition{
+				UserTypeDefinition: &design.UserTypeDefinition{
+					AttributeDefinition: &design.AttributeDefinition{Type: bottleAttrs},
+					TypeName:            "Bottle",
+				},
+				Identifier: "application/vnd.bottle+json",
+			}
+			bottleMT.Links = map[string]*design.LinkDefinition{
+				"account": {Name: "account", Parent: bottleMT},
+			}
+			bottleMT.Views = map[string]*design.ViewDefinition{
+				"default": {
+					AttributeDefinition: &design.AttributeDefinition{
+						Type: design.Object{
+							"id":    &design.AttributeDefinition{Type: design.Integer},
+							"links": &design.AttributeDefinition{Type: design.String},
+						},
+					},
+					Name:   "default",
+					Parent: bottleMT,
+				},
+			}
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				MediaTypes: map[string]*design.MediaTypeDefinition{
+					accountMT.Identifier: accountMT,
+					bottleMT.Identifier:  bottleMT,
+				},
+				Resources: map[string]*design.ResourceDefinition{
+					"bottle": {
+						Name: "bottle",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name: "show",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: ""},
+								},
+								Responses: map[string]*design.ResponseDefinition{
+									"OK": {Name: "OK", Status: 200, MediaType: bottleMT.Identifier},
+								},
+							},
+						},
+					},
+				},
+			}
+			design.GeneratedMediaTypes = make(design.MediaTypeRoot)
+			bottleRes := design.Design.Resources["bottle"]
+			showAct := bottleRes.Actions["show"]
+			showAct.Parent = bottleRes
+			showAct.Routes[0].Parent = showAct
+		})
+
+		It("generates the links data structure and the methods following them", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "datatypes.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(MatchRegexp(`Links +\*BottleLinks`))
+			Ω(content).Should(ContainSubstring("type BottleLinks struct {"))
+			Ω(content).Should(ContainSubstring("type AccountLink struct {"))
+			Ω(content).Should(ContainSubstring("func (l *BottleLinks) FollowAccount(ctx context.Context, c *Client) (*http.Response, error) {"))
+			Ω(content).Should(ContainSubstring("return c.followLink(ctx, *l.Account.Href)"))
+			client, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(client).Should(ContainSubstring("func (c *Client) followLink(ctx context.Context, href string) (*http.Response, error) {"))
+			Ω(client).Should(ContainSubstring("req, err := http.NewRequest(\"GET\", u.String(), nil)\n\tif err != nil {\n\t\treturn nil, err\n\t}\n\treq = req.WithContext(ctx)\n"))
+		})
+	})
+
+	Context("with If-Match arguments enabled", func() {
+		BeforeEach(func() {
+			os.Args = append(os.Args, "--if-match")
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"update": {
+								Name:   "update",
+								Routes: []*design.RouteDefinition{{Verb: "PUT", Path: ""}},
+							},
+							"show": {
+								Name:   "show",
+								Routes: []*design.RouteDefinition{{Verb: "GET", Path: ""}},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			for _, a := range fooRes.Actions {
+				a.Parent = fooRes
+				a.Routes[0].Parent = a
+			}
+		})
+
+		It("sets the If-Match header for PUT actions only", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) NewUpdateFooRequest(ctx context.Context, path string, ifMatch string) (*http.Request, error) {"))
+			Ω(content).Should(ContainSubstring("if ifMatch != \"\" {\n\t\treq.Header.Set(\"If-Match\", ifMatch)\n\t}"))
+			Ω(content).Should(ContainSubstring("func (c *Client) NewShowFooRequest(ctx context.Context, path string) (*http.Request, error) {"))
+			Ω(strings.Count(string(content), "ifMatch string")).Should(Equal(2))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "commands.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring(`cc.Flags().StringVar(&cmd.IfMatch, "if-match", "", "Value of the If-Match request header")`))
+			Ω(content).Should(ContainSubstring("resp, err := c.UpdateFoo(ctx, path, cmd.IfMatch)"))
+		})
+	})
+
+	Context("with idempotency keys enabled", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			os.Args = append(os.Args, "--idempotency")
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"create": {
+								Name: "create",
+								Routes: []*design.RouteDefinition{
+									{Verb: "POST", Path: ""},
+								},
+							},
+							"show": {
+								Name: "show",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: "/:id"},
+								},
+								Params: &design.AttributeDefinition{
+									Type: design.Object{
+										"id": &design.AttributeDefinition{Type: design.String},
+									},
+								},
+								QueryParams: &design.AttributeDefinition{
+									Type: design.Object{},
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			for _, a := range fooRes.Actions {
+				a.Parent = fooRes
+				a.Routes[0].Parent = a
+			}
+		})
+
+		It("sets the Idempotency-Key header for POST actions only", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) NewCreateFooRequest(ctx context.Context, path string, idempotencyKey string) (*http.Request, error) {"))
+			Ω(content).Should(ContainSubstring(`req.Header.Set("Idempotency-Key", idempotencyKey)`))
+			Ω(content).Should(ContainSubstring("func (c *Client) NewShowFooRequest(ctx context.Context, path string) (*http.Request, error) {"))
+			Ω(strings.Count(string(content), "idempotencyKey string")).Should(Equal(2))
+		})
+
+		It("escapes the path parameters", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring(`return fmt.Sprintf("/%s", url.PathEscape(id))`))
+		})
+
+		It("applies the request mutators to all actions", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithRequestMutator(mutators ...func(*http.Request) error) Option {"))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(strings.Count(string(content), "for _, mutate := range c.mutators {")).Should(Equal(2))
+			Ω(strings.Count(string(content), "if err := mutate(req); err != nil {")).Should(Equal(2))
+		})
+	})
+
+	Context("with an action with multiple routes", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"update": {
+								Name: "update",
+								Routes: []*design.RouteDefinition{
+									{Verb: "PUT", Path: "/:id"},
+									{Verb: "POST", Path: "/:id/update"},
+								},
+								Params: &design.AttributeDefinition{
+									Type: design.Object{
+										"id": &design.AttributeDefinition{Type: design.String},
+									},
+								},
+								QueryParams: &design.AttributeDefinition{
+									Type: design.Object{},
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			for _, a := range fooRes.Actions {
+				a.Parent = fooRes
+				for _, r := range a.Routes {
+					r.Parent = a
+				}
+			}
+		})
+
+		It("selects the route used to build the request", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("var updateFooRoutes = []goaclient.Route{"))
+			Ω(content).Should(ContainSubstring(`{Verb: "PUT", Path: "/:id"},`))
+			Ω(content).Should(ContainSubstring(`{Verb: "POST", Path: "/:id/update"},`))
+			Ω(content).Should(ContainSubstring("actionRoute := goaclient.SelectRoute(ctx, updateFooRoutes, path)"))
+			Ω(content).Should(ContainSubstring("req, err := http.NewRequest(actionRoute.Verb, u.String(), nil)"))
+			Ω(content).Should(ContainSubstring("req = goaclient.WithRequestRoute(req, actionRoute)"))
+		})
+	})
+
+	Context("with an action with GET and HEAD routes", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name: "show",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: "/:id"},
+									{Verb: "HEAD", Path: "/:id"},
+								},
+								Params: &design.AttributeDefinition{
+									Type: design.Object{
+										"id": &design.AttributeDefinition{Type: design.String},
+									},
+								},
+								QueryParams: &design.AttributeDefinition{
+									Type: design.Object{},
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			for _, a := range fooRes.Actions {
+				a.Parent = fooRes
+				for _, r := range a.Routes {
+					r.Parent = a
+				}
+			}
+		})
+
+		It("generates one request builder per route", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) NewShowFooRequest(ctx context.Context, path string) (*http.Request, error) {"))
+			Ω(content).Should(ContainSubstring("return c.newShowFooRequest(ctx, showFooRoutes[0], path)"))
+			Ω(content).Should(ContainSubstring("func (c *Client) NewShowFooRequest2(ctx context.Context, path string) (*http.Request, error) {"))
+			Ω(content).Should(ContainSubstring("return c.newShowFooRequest(ctx, showFooRoutes[1], path)"))
+			Ω(content).Should(ContainSubstring("func (c *Client) newShowFooRequest(ctx context.Context, actionRoute goaclient.Route, path string) (*http.Request, error) {"))
+			Ω(content).Should(ContainSubstring("req, err := c.newShowFooRequest(ctx, actionRoute, path)"))
+		})
+
+		Context("and a query parameter named route", func() {
+			BeforeEach(func() {
+				design.Design.Resources["foo"].Actions["show"].QueryParams.Type = design.Object{
+					"route": &design.AttributeDefinition{Type: design.String},
+				}
+			})
+
+			It("generates request builders sending the parameter", func() {
+				Ω(genErr).Should(BeNil())
+				out, err := runGeneratedTest(filepath.Join(outDir, "client"), routeParamTest)
+				Ω(err).ShouldNot(HaveOccurred(), out)
+			})
+		})
+	})
+
+	Context("with an action with response headers", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"list": {
+								Name: "list",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: ""},
+								},
+								Responses: map[string]*design.ResponseDefinition{
+									"OK": {
+										Name:   "OK",
+										Status: 200,
+										Headers: &design.AttributeDefinition{
+											Type: design.Object{
+												"X-Total": &design.AttributeDefinition{Type: design.Integer},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			for _, a := range fooRes.Actions {
+				a.Parent = fooRes
+				for _, r := range a.Routes {
+					r.Parent = a
+				}
+			}
+		})
+
+		It("generates a function decoding the response headers", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("type ListFooResponseHeaders struct {"))
+			Ω(content).Should(ContainSubstring("XTotal *int"))
+			Ω(content).Should(ContainSubstring("func DecodeListFooHeaders(resp *http.Response) (*ListFooResponseHeaders, error) {"))
+			Ω(content).Should(ContainSubstring(`if raw := resp.Header.Get("X-Total"); raw != "" {`))
+			Ω(content).Should(ContainSubstring("v, err := strconv.Atoi(raw)"))
+			Ω(content).Should(ContainSubstring("headers.XTotal = &v"))
+		})
+
+		Context("routed as HEAD", func() {
+			BeforeEach(func() {
+				attrs := design.Object{"id": &design.AttributeDefinition{Type: design.Integer}}
+				fooMT := &design.MediaTypeDefinition{
+					UserTypeDefinition: &design.UserTypeDefinition{
+						AttributeDefinition: &design.AttributeDefinition{Type: attrs},
+						TypeName:            "Foo",
+					},
+					Identifier: "application/vnd.foo+json",
+				}
+				fooMT.Views = map[string]*design.ViewDefinition{
+					"default": {
+						AttributeDefinition: &design.AttributeDefinition{Type: attrs},
+						Name:                "default",
+						Parent:              fooMT,
+					},
+				}
+				design.Design.MediaTypes = map[string]*design.MediaTypeDefinition{fooMT.Identifier: fooMT}
+				list := design.Design.Resources["foo"].Actions["list"]
+				list.Routes[0].Verb = "HEAD"
+				list.Responses["OK"].MediaType = fooMT.Identifier
+			})
+
+			It("generates a method decoding the headers without reading the body", func() {
+				Ω(genErr).Should(BeNil())
+				content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).Should(ContainSubstring("func (c *Client) ListFooAndDecodeHeaders(ctx context.Context, path string) (*http.Response, *ListFooResponseHeaders, error) {"))
+				Ω(content).Should(ContainSubstring("\tresp.Body.Close()\n\theaders, err := DecodeListFooHeaders(resp)\n"))
+				Ω(content).ShouldNot(ContainSubstring("ListFooAndDecode("))
+				Ω(content).ShouldNot(ContainSubstring("ListFooResult"))
+			})
+		})
+	})
+
+	Context("with an action consuming a specific content type", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			payload := &design.UserTypeDefinition{
+				AttributeDefinition: &design.AttributeDefinition{
+					Type: design.Object{
+						"name": &design.AttributeDefinition{Type: design.String},
+					},
+				},
+				TypeName: "CreateFooPayload",
+			}
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"create": {
+								Name: "create",
+								Routes: []*design.RouteDefinition{
+									{Verb: "POST", Path: ""},
+								},
+								Payload:  payload,
+								Metadata: dslengine.MetadataDefinition{"client:content-type": {"application/xml"}},
+							},
+							"update": {
+								Name: "update",
+								Routes: []*design.RouteDefinition{
+									{Verb: "PUT", Path: ""},
+								},
+								Payload: payload,
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			for _, a := range fooRes.Actions {
+				a.Parent = fooRes
+				a.Routes[0].Parent = a
+			}
+		})
+
+		It("encodes the payload with the action content type", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring(`rest, err := c.encodeBody(payload, &body, "application/xml")`))
+			Ω(content).Should(ContainSubstring(`req.Header.Set("Content-Type", "application/xml")`))
+			Ω(content).Should(ContainSubstring(`rest, err := c.encodeBody(payload, &body, "*/*") // Use default encoder`))
+			Ω(strings.Count(string(content), `req.Header.Set("Content-Type", "`)).Should(Equal(1))
+		})
+
+		It("generates raw request builders for actions with payloads", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) NewCreateFooRequestRaw(ctx context.Context, path string, body io.Reader, contentType string) (*http.Request, error) {"))
+			Ω(content).Should(ContainSubstring("func (c *Client) NewUpdateFooRequestRaw(ctx context.Context, path string, body io.Reader, contentType string) (*http.Request, error) {"))
+			Ω(content).Should(ContainSubstring(`req, err := http.NewRequest("POST", u.String(), body)`))
+			Ω(content).Should(ContainSubstring(`req.Header.Set("Content-Type", contentType)`))
+			Ω(strings.Count(string(content), `c.encodeBody(payload, &body, "`)).Should(Equal(2))
+		})
+
+		It("generates request builders streaming payloads above the streaming threshold", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithStreamingThreshold(n int64) Option {"))
+			Ω(content).Should(ContainSubstring("if _, err := io.CopyN(body, pr, c.streaming+1); err != nil {"))
+			Ω(content).Should(ContainSubstring("req.ContentLength = -1"))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("if rest != nil {\n\t\tstreamBody(req, rest)\n\t}"))
+		})
+
+		It("generates request builders encoding the payload with a given content type", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) NewCreateFooRequestAs(ctx context.Context, path string, payload *CreateFooPayload, contentType string) (*http.Request, error) {"))
+			Ω(content).Should(ContainSubstring("func (c *Client) NewUpdateFooRequestAs(ctx context.Context, path string, payload *CreateFooPayload, contentType string) (*http.Request, error) {"))
+			Ω(content).Should(ContainSubstring("rest, err := c.encodeBody(payload, &body, mediaType)"))
+			Ω(content).Should(ContainSubstring("req, err := c.NewCreateFooRequestRaw(ctx, path, &body, contentType)"))
+		})
+	})
+
+	Context("with an action marked as the health check", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"status": {
+						Name:     "status",
+						BasePath: "/status",
+						Actions: map[string]*design.ActionDefinition{
+							"health": {
+								Name: "health",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: "/health"},
+								},
+								QueryParams: &design.AttributeDefinition{
+									Type: design.Object{
+										"verbose": &design.AttributeDefinition{Type: design.Boolean},
+									},
+								},
+								Metadata: dslengine.MetadataDefinition{"healthcheck": {}},
+							},
+							"show": {
+								Name: "show",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: ""},
+								},
+							},
+						},
+					},
+				},
+			}
+			statusRes := design.Design.Resources["status"]
+			for _, a := range statusRes.Actions {
+				a.Parent = statusRes
+				a.Routes[0].Parent = a
+			}
+		})
+
+		It("generates a Ping method calling the health check action", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) Ping(ctx context.Context) error {"))
+			Ω(content).Should(ContainSubstring(`resp, err := c.HealthStatus(ctx, "/status/health", nil)`))
+			Ω(content).Should(ContainSubstring("if resp.StatusCode < 200 || resp.StatusCode > 299 {"))
+		})
+
+		It("generates path functions returning the static path of static routes", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "status.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func HealthStatusPath() string {\n\treturn StatusBasePath + \"/health\"\n}"))
+			Ω(content).Should(ContainSubstring("func ShowStatusPath() string {\n\treturn StatusBasePath\n}"))
+			Ω(content).ShouldNot(ContainSubstring(`fmt.Sprintf(`))
+		})
+
+		It("generates a constant holding the path prefix shared by the resource routes", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "status.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring(`const StatusBasePath = "/status"`))
+		})
+
+		Context("with path wildcards", func() {
+			BeforeEach(func() {
+				health := design.Design.Resources["status"].Actions["health"]
+				health.Routes[0].Path = "/health/:id"
+				health.Params = &design.AttributeDefinition{
+					Type: design.Object{"id": &design.AttributeDefinition{Type: design.String}},
+				}
+			})
+
+			It("fails", func() {
+				Ω(genErr).Should(MatchError(ContainSubstring("health check action cannot have path wildcards")))
+			})
+		})
+	})
+
+	Context("with a payload with fields named after different conventions", func() {
+		BeforeEach(func() {
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"create": {
+								Name:   "create",
+								Routes: []*design.RouteDefinition{{Verb: "POST", Path: ""}},
+								Payload: &design.UserTypeDefinition{
+									AttributeDefinition: &design.AttributeDefinition{
+										Type: design.Object{
+											"first_name": &design.AttributeDefinition{Type: design.String},
+											"lastName":   &design.AttributeDefinition{Type: design.String},
+											"zip-code":   &design.AttributeDefinition{Type: design.String},
+										},
+									},
+									TypeName: "CreateFooPayload",
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			createAct := fooRes.Actions["create"]
+			createAct.Parent = fooRes
+			createAct.Routes[0].Parent = createAct
+		})
+
+		It("uses the attribute names as JSON keys", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(MatchRegexp("FirstName +\\*string +`json:\"first_name,omitempty\" xml:\"first_name,omitempty\"`"))
+			Ω(content).Should(MatchRegexp("LastName +\\*string +`json:\"lastName,omitempty\""))
+			Ω(content).Should(MatchRegexp("ZipCode +\\*string +`json:\"zip-code,omitempty\""))
+		})
+
+		Context("with the snake JSON naming strategy", func() {
+			BeforeEach(func() {
+				os.Args = append(os.Args, "--json-naming=snake")
+			})
+
+			It("uses snake_case JSON keys", func() {
+				Ω(genErr).Should(BeNil())
+				content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).Should(MatchRegexp("FirstName +\\*string +`json:\"first_name,omitempty\" xml:\"first_name,omitempty\"`"))
+				Ω(content).Should(MatchRegexp("LastName +\\*string +`json:\"last_name,omitempty\" xml:\"lastName,omitempty\"`"))
+				Ω(content).Should(MatchRegexp("ZipCode +\\*string +`json:\"zip_code,omitempty\""))
+			})
+		})
+
+		Context("with the camel JSON naming strategy", func() {
+			BeforeEach(func() {
+				os.Args = append(os.Args, "--json-naming=camel")
+			})
+
+			It("uses camelCase JSON keys", func() {
+				Ω(genErr).Should(BeNil())
+				content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).Should(MatchRegexp("FirstName +\\*string +`json:\"firstName,omitempty\" xml:\"first_name,omitempty\"`"))
+				Ω(content).Should(MatchRegexp("LastName +\\*string +`json:\"lastName,omitempty\""))
+				Ω(content).Should(MatchRegexp("ZipCode +\\*string +`json:\"zipCode,omitempty\""))
+			})
+		})
+
+		Context("with the asis JSON naming strategy", func() {
+			BeforeEach(func() {
+				os.Args = append(os.Args, "--json-naming=asis")
+			})
+
+			It("uses the attribute names as JSON keys", func() {
+				Ω(genErr).Should(BeNil())
+				content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).Should(MatchRegexp("LastName +\\*string +`json:\"lastName,omitempty\""))
+				Ω(content).Should(MatchRegexp("ZipCode +\\*string +`json:\"zip-code,omitempty\""))
+			})
+		})
+
+		Context("with an unknown JSON naming strategy", func() {
+			BeforeEach(func() {
+				os.Args = append(os.Args, "--json-naming=kebab")
+			})
+
+			It("fails", func() {
+				Ω(genErr).Should(MatchError(`invalid JSON naming strategy "kebab", must be one of snake, camel or asis`))
+			})
+		})
+	})
+
+	Context("with an action with a typed payload", func() {
+		BeforeEach(func() {
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"create": {
+								Name:   "create",
+								Routes: []*design.RouteDefinition{{Verb: "POST", Path: ""}},
+								Payload: &design.UserTypeDefinition{
+									AttributeDefinition: &design.AttributeDefinition{
+										Type: design.Object{
+											"name": &design.AttributeDefinition{Type: design.String},
+											"size": &design.AttributeDefinition{
+												Type:     design.Integer,
+												Metadata: dslengine.MetadataDefinition{"struct:field:json": {"fooSize"}},
+											},
+											"tags": &design.AttributeDefinition{Type: &design.Array{ElemType: &design.AttributeDefinition{Type: design.String}}},
+										},
+										Validation: &dslengine.ValidationDefinition{Required: []string{"name"}},
+									},
+									TypeName: "CreateFooPayload",
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			createAct := fooRes.Actions["create"]
+			createAct.Parent = fooRes
+			createAct.Routes[0].Parent = createAct
+		})
+
+		It("generates a request builder taking a generic map", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("var createFooFields = []*goaclient.PayloadField{"))
+			Ω(content).Should(ContainSubstring(`{Name: "name", Key: "name", Kind: "string", Required: true},`))
+			Ω(content).Should(ContainSubstring(`{Name: "size", Key: "fooSize", Kind: "integer"},`))
+			Ω(content).Should(ContainSubstring(`{Name: "tags", Key: "tags", Kind: "array", Elem: &goaclient.PayloadField{Kind: "string"}},`))
+			Ω(content).Should(ContainSubstring("func (c *Client) NewCreateFooRequestFromMap(ctx context.Context, path string, data map[string]interface{}) (*http.Request, error) {"))
+			Ω(content).Should(ContainSubstring("coerced, err := goaclient.CoerceMap(data, createFooFields)"))
+			Ω(content).Should(ContainSubstring("var payload *CreateFooPayload"))
+			Ω(content).Should(ContainSubstring("return c.NewCreateFooRequest(ctx, path, payload)"))
+		})
+	})
+
+	Context("with the examples flag", func() {
+		BeforeEach(func() {
+			os.Args = append(os.Args, "--examples")
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"create": {
+								Name:   "create",
+								Routes: []*design.RouteDefinition{{Verb: "POST", Path: "/:id"}},
+								Params: &design.AttributeDefinition{
+									Type: design.Object{
+										"id": &design.AttributeDefinition{Type: design.Integer, Example: 42},
+									},
+								},
+								QueryParams: &design.AttributeDefinition{
+									Type: design.Object{
+										"dry_run": &design.AttributeDefinition{Type: design.Boolean, Example: true},
+									},
+								},
+								Payload: &design.UserTypeDefinition{
+									AttributeDefinition: &design.AttributeDefinition{
+										Type: design.Object{
+											"name": &design.AttributeDefinition{
+												Type:     design.String,
+												Metadata: dslengine.MetadataDefinition{"struct:field:json": {"fullName"}},
+											},
+										},
+										Example: map[string]interface{}{"name": "bob"},
+									},
+									TypeName: "CreateFooPayload",
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			createAct := fooRes.Actions["create"]
+			createAct.Parent = fooRes
+			createAct.Routes[0].Parent = createAct
+		})
+
+		It("generates an example calling the action with the design examples", func() {
+			Ω(genErr).Should(BeNil())
+			Ω(files).Should(ContainElement(filepath.Join(outDir, "client", "example_test.go")))
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "example_test.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("package client"))
+			Ω(content).Should(ContainSubstring("func ExampleClient_CreateFoo() {"))
+			Ω(content).Should(ContainSubstring("path := CreateFooPath(42)"))
+			Ω(content).Should(ContainSubstring("var payload *CreateFooPayload"))
+			Ω(content).Should(ContainSubstring("json.Unmarshal([]byte(`{\"fullName\":\"bob\"}`), &payload)"))
+			Ω(content).Should(ContainSubstring("dryRun := true"))
+			Ω(content).Should(ContainSubstring("resp, err := c.CreateFoo(context.Background(), path, payload, &dryRun)"))
+		})
+	})
+
+	Context("with a payload field with a default value", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"create": {
+								Name: "create",
+								Routes: []*design.RouteDefinition{
+									{Verb: "POST", Path: ""},
+								},
+								Payload: &design.UserTypeDefinition{
+									AttributeDefinition: &design.AttributeDefinition{
+										Type: design.Object{
+											"name": &design.AttributeDefinition{
+												Type:     design.String,
+												Metadata: dslengine.MetadataDefinition{"struct:field:json": []string{"full_name"}},
+											},
+											"vintage": &design.AttributeDefinition{Type: design.Integer, DefaultValue: 2016},
+										},
+									},
+									TypeName: "CreateFooPayload",
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			createAct := fooRes.Actions["create"]
+			createAct.Parent = fooRes
+			createAct.Routes[0].Parent = createAct
+		})
+
+		It("always encodes the field", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(MatchRegexp("Vintage +int +`json:\"vintage\""))
+			Ω(content).Should(MatchRegexp("Name +\\*string +`json:\"full_name,omitempty\""))
+		})
+
+		Context("with omitempty enabled", func() {
+			BeforeEach(func() {
+				os.Args = append(os.Args, "--omitempty")
+			})
+
+			It("omits the field when empty", func() {
+				Ω(genErr).Should(BeNil())
+				content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).Should(MatchRegexp("Vintage +int +`json:\"vintage,omitempty\""))
+				Ω(content).Should(MatchRegexp("Name +\\*string +`json:\"full_name,omitempty\""))
+			})
+		})
+
+		Context("with streaming uploads enabled", func() {
+			BeforeEach(func() {
+				os.Args = append(os.Args, "--streaming-uploads")
+			})
+
+			It("generates a request builder streaming the body", func() {
+				Ω(genErr).Should(BeNil())
+				content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).Should(ContainSubstring("func (c *Client) NewCreateFooRequestChunked(ctx context.Context, path string, body io.Reader, contentType string) (*http.Request, error) {"))
+				Ω(content).Should(ContainSubstring("req, err := c.NewCreateFooRequestRaw(ctx, path, ioutil.NopCloser(body), contentType)"))
+				Ω(content).Should(ContainSubstring("req.ContentLength = -1"))
+				Ω(content).Should(ContainSubstring("func (c *Client) NewCreateFooRequest(ctx context.Context, path string, payload *CreateFooPayload) (*http.Request, error) {"))
+			})
+		})
+
+		Context("with content MD5 enabled", func() {
+			BeforeEach(func() {
+				os.Args = append(os.Args, "--content-md5")
+			})
+
+			It("sets the Content-MD5 header to the digest of the encoded body", func() {
+				Ω(genErr).Should(BeNil())
+				content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).Should(ContainSubstring("sum := md5.Sum(body.Bytes())"))
+				Ω(content).Should(ContainSubstring(`req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))`))
+				Ω(strings.Count(string(content), "md5.Sum")).Should(Equal(1))
+			})
+		})
+	})
+
+	Context("with actions defining timeouts", func() {
+		BeforeEach(func() {
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name:     "show",
+								Routes:   []*design.RouteDefinition{{Verb: "GET", Path: "/show"}},
+								Metadata: dslengine.MetadataDefinition{"client:timeout": []string{"30s"}},
+							},
+							"export": {
+								Name:     "export",
+								Routes:   []*design.RouteDefinition{{Verb: "GET", Path: "/export"}},
+								Metadata: dslengine.MetadataDefinition{"client:timeout": []string{"1h30m"}},
+							},
+							"list": {
+								Name:   "list",
+								Routes: []*design.RouteDefinition{{Verb: "GET", Path: ""}},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			for _, a := range fooRes.Actions {
+				a.Parent = fooRes
+				a.Routes[0].Parent = a
+			}
+		})
+
+		It("wraps the request contexts with the action timeouts", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("tctx, cancel := context.WithTimeout(req.Context(), 30*time.Second)"))
+			Ω(content).Should(ContainSubstring("tctx, cancel := context.WithTimeout(req.Context(), 90*time.Minute)"))
+			Ω(strings.Count(string(content), "tctx, cancel := c.withTimeout(req.Context())")).Should(Equal(1))
+			Ω(content).Should(ContainSubstring("return cancelOnClose(resp, err, cancel)"))
+			Ω(content).Should(ContainSubstring("if err != nil {\n\t\treturn nil, err\n\t}\n\treq = req.WithContext(ctx)\n"))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithTimeout(d time.Duration) Option {"))
+		})
+
+		It("bounds the time spent waiting on the rate limiter with the action timeouts", func() {
+			Ω(genErr).Should(BeNil())
+			out, err := runGeneratedTest(filepath.Join(outDir, "client"), timeoutRateLimitTest)
+			Ω(err).ShouldNot(HaveOccurred(), out)
+		})
+
+		Context("with an invalid timeout", func() {
+			BeforeEach(func() {
+				design.Design.Resources["foo"].Actions["show"].Metadata["client:timeout"] = []string{"soon"}
+			})
+
+			It("returns an error", func() {
+				Ω(genErr).Should(HaveOccurred())
+				Ω(genErr.Error()).Should(ContainSubstring(`invalid client:timeout metadata "soon"`))
+			})
+		})
+	})
+
+	Context("with an action consuming application/octet-stream", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"upload": {
+								Name: "upload",
+								Routes: []*design.RouteDefinition{
+									{Verb: "PUT", Path: ""},
+								},
+								Payload: &design.UserTypeDefinition{
+									AttributeDefinition: &design.AttributeDefinition{Type: design.String},
+									TypeName:            "UploadFooPayload",
+								},
+								Metadata: dslengine.MetadataDefinition{"client:content-type": []string{"application/octet-stream"}},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			uploadAct := fooRes.Actions["upload"]
+			uploadAct.Parent = fooRes
+			uploadAct.Routes[0].Parent = uploadAct
+		})
+
+		It("streams the request body without encoding it", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) UploadFoo(ctx context.Context, path string, body io.Reader) (*http.Response, error) {"))
+			Ω(content).Should(ContainSubstring(`req, err := http.NewRequest("PUT", u.String(), body)`))
+			Ω(content).Should(ContainSubstring(`req.Header.Set("Content-Type", "application/octet-stream")`))
+			Ω(content).ShouldNot(ContainSubstring("c.Encoder.Encode"))
+			Ω(content).ShouldNot(ContainSubstring("UploadFooPayload"))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "commands.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("c.UploadFoo(ctx, path, strings.NewReader(cmd.Payload))"))
+		})
+	})
+
+	Context("with a GET action declaring a payload", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"search": {
+								Name: "search",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: "/search"},
+								},
+								Payload: &design.UserTypeDefinition{
+									AttributeDefinition: &design.AttributeDefinition{
+										Type: design.Object{
+											"query": &design.AttributeDefinition{Type: design.String},
+										},
+									},
+									TypeName: "SearchFooPayload",
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			searchAct := fooRes.Actions["search"]
+			searchAct.Parent = fooRes
+			searchAct.Routes[0].Parent = searchAct
+		})
+
+		It("encodes the payload and sends it with the GET request", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) SearchFoo(ctx context.Context, path string, payload *SearchFooPayload) (*http.Response, error) {"))
+			Ω(content).Should(ContainSubstring("rest, err := c.encodeBody(payload, &body, "))
+			Ω(content).Should(ContainSubstring(`req, err := http.NewRequest("GET", u.String(), &body)`))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "commands.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring(`cc.Flags().StringVar(&cmd.Payload, "payload", "", "Request JSON body")`))
+			Ω(content).Should(ContainSubstring("c.SearchFoo(ctx, path, &payload)"))
+		})
+	})
+
+	Context("with a DELETE action declaring a payload", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"delete": {
+								Name: "delete",
+								Routes: []*design.RouteDefinition{
+									{Verb: "DELETE", Path: ""},
+								},
+								Payload: &design.UserTypeDefinition{
+									AttributeDefinition: &design.AttributeDefinition{
+										Type: design.Object{
+											"reason": &design.AttributeDefinition{Type: design.String},
+										},
+									},
+									TypeName: "DeleteFooPayload",
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			deleteAct := fooRes.Actions["delete"]
+			deleteAct.Parent = fooRes
+			deleteAct.Routes[0].Parent = deleteAct
+		})
+
+		It("sends no body when the payload is nil", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("var body bytes.Buffer\n\tvar rest io.ReadCloser\n\tif payload != nil {\n\t\tvar err error\n\t\trest, err = c.encodeBody(payload, &body, "))
+			Ω(content).Should(ContainSubstring(`req, err := http.NewRequest("DELETE", u.String(), &body)`))
+		})
+
+		Context("with content MD5 enabled", func() {
+			BeforeEach(func() {
+				os.Args = append(os.Args, "--content-md5")
+			})
+
+			It("only sets the digest of non-nil payloads", func() {
+				Ω(genErr).Should(BeNil())
+				content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).Should(ContainSubstring("if payload != nil {\n\t\tsum := md5.Sum(body.Bytes())"))
+				Ω(content).Should(ContainSubstring("if payload != nil {\n\t\terr := c.Encoder.Encode(payload, &body, "))
+			})
+		})
+	})
+
+	Context("with a hash payload", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"tag": {
+								Name: "tag",
+								Routes: []*design.RouteDefinition{
+									{Verb: "POST", Path: ""},
+								},
+								Payload: &design.UserTypeDefinition{
+									AttributeDefinition: &design.AttributeDefinition{
+										Type: &design.Hash{
+											KeyType:  &design.AttributeDefinition{Type: design.String},
+											ElemType: &design.AttributeDefinition{Type: design.Integer},
+										},
+									},
+									TypeName: "TagFooPayload",
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			tagAct := fooRes.Actions["tag"]
+			tagAct.Parent = fooRes
+			tagAct.Routes[0].Parent = tagAct
+		})
+
+		It("encodes the map in JSON and builds it from the CLI entry flags", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("type TagFooPayload map[string]int"))
+			Ω(content).Should(ContainSubstring("func (c *Client) TagFoo(ctx context.Context, path string, payload TagFooPayload) (*http.Response, error) {"))
+			Ω(content).Should(ContainSubstring("c.encodeBody(payload, &body, "))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "commands.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring(`cc.Flags().StringArrayVar(&cmd.Entries, "entry", nil, `))
+			Ω(content).Should(ContainSubstring("key := elems[0]"))
+			Ω(content).Should(ContainSubstring("var value int"))
+			Ω(content).Should(ContainSubstring("payload = make(map[string]int)"))
+			Ω(content).Should(ContainSubstring("c.TagFoo(ctx, path, payload)"))
+		})
+	})
+
+	Context("with a PATCH action and patch pointers enabled", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			os.Args = append(os.Args, "--patch-pointers")
+			payload := func(name string) *design.UserTypeDefinition {
+				return &design.UserTypeDefinition{
+					AttributeDefinition: &design.AttributeDefinition{
+						Type: design.Object{
+							"name":    &design.AttributeDefinition{Type: design.String},
+							"vintage": &design.AttributeDefinition{Type: design.Integer},
+						},
+						Validation: &dslengine.ValidationDefinition{Required: []string{"name", "vintage"}},
+					},
+					TypeName: name,
+				}
+			}
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"create": {
+								Name: "create",
+								Routes: []*design.RouteDefinition{
+									{Verb: "POST", Path: ""},
+								},
+								Payload: payload("CreateFooPayload"),
+							},
+							"update": {
+								Name: "update",
+								Routes: []*design.RouteDefinition{
+									{Verb: "PATCH", Path: ""},
+								},
+								Payload: payload("UpdateFooPayload"),
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			for _, a := range fooRes.Actions {
+				a.Parent = fooRes
+				a.Routes[0].Parent = a
+			}
+		})
+
+		It("generates optional pointer fields for the PATCH payload only", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(MatchRegexp("Name +\\*string +`json:\"name,omitempty\""))
+			Ω(content).Should(MatchRegexp("Vintage +\\*int +`json:\"vintage,omitempty\""))
+			Ω(content).Should(MatchRegexp("Name +string +`json:\"name\""))
+			Ω(content).Should(MatchRegexp("Vintage +int +`json:\"vintage\""))
+			Ω(content).Should(ContainSubstring("// Fields that are not set are omitted from the request body."))
+			Ω(design.Design.Resources["foo"].Actions["create"].Payload.IsRequired("name")).Should(BeTrue())
+		})
+	})
+
+	Context("with an action consuming form encoded payloads", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"token": {
+								Name: "token",
+								Routes: []*design.RouteDefinition{
+									{Verb: "POST", Path: ""},
+								},
+								Payload: &design.UserTypeDefinition{
+									AttributeDefinition: &design.AttributeDefinition{
+										Type: design.Object{
+											"grant_type": &design.AttributeDefinition{Type: design.String},
+											"expires_in": &design.AttributeDefinition{Type: design.Integer},
+										},
+										Validation: &dslengine.ValidationDefinition{Required: []string{"grant_type"}},
+									},
+									TypeName: "TokenFooPayload",
+								},
+								Metadata: dslengine.MetadataDefinition{"client:content-type": {"application/x-www-form-urlencoded"}},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			for _, a := range fooRes.Actions {
+				a.Parent = fooRes
+				a.Routes[0].Parent = a
+			}
+		})
+
+		It("encodes the payload attributes in the request body", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("form := url.Values{}"))
+			Ω(content).Should(ContainSubstring(`form.Set("grant_type", payload.GrantType)`))
+			Ω(content).Should(ContainSubstring("if payload.ExpiresIn != nil {"))
+			Ω(content).Should(ContainSubstring("strconv.Itoa(*payload.ExpiresIn)"))
+			Ω(content).Should(ContainSubstring("body.WriteString(form.Encode())"))
+			Ω(content).Should(ContainSubstring(`req.Header.Set("Content-Type", "application/x-www-form-urlencoded")`))
+			Ω(content).ShouldNot(ContainSubstring("c.Encoder.Encode(payload"))
+		})
+	})
+
+	Context("with an action streaming server-sent events", func() {
+		BeforeEach(func() {
+			eventMT := &design.MediaTypeDefinition{
+				UserTypeDefinition: &design.UserTypeDefinition{
+					AttributeDefinition: &design.AttributeDefinition{
+						Type: design.Object{
+							"id": &design.AttributeDefinition{Type: design.Integer},
+						},
+					},
+					TypeName: "Event",
+				},
+				Identifier: "text/event-stream",
+			}
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				MediaTypes: map[string]*design.MediaTypeDefinition{
+					eventMT.Identifier: eventMT,
+				},
+				Resources: map[string]*design.ResourceDefinition{
+					"user": {
+						Name: "user",
+						Actions: map[string]*design.ActionDefinition{
+							"events": {
+								Name: "events",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: "/events"},
+								},
+								Responses: map[string]*design.ResponseDefinition{
+									"OK": {Name: "OK", Status: 200, MediaType: eventMT.Identifier},
+								},
+							},
+						},
+					},
+				},
+			}
+			userRes := design.Design.Resources["user"]
+			eventsAct := userRes.Actions["events"]
+			eventsAct.Parent = userRes
+			eventsAct.Routes[0].Parent = eventsAct
+		})
+
+		It("generates a method decoding the events", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "user.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) EventsUserEvents(ctx context.Context, path string) (<-chan *Event, <-chan error) {"))
+			Ω(content).Should(ContainSubstring("resp, err := c.EventsUser(ctx, path)"))
+			Ω(content).Should(ContainSubstring("scanner := bufio.NewScanner(resp.Body)"))
+			Ω(content).Should(ContainSubstring(`if strings.HasPrefix(line, "data:") {`))
+			Ω(content).Should(ContainSubstring(`if err := c.Decoder.Decode(&decoded, &data, ""); err != nil {`))
+			Ω(content).Should(ContainSubstring("case events <- &decoded:"))
+			Ω(content).Should(ContainSubstring(`req.Header.Set("Accept", "text/event-stream")`))
+			Ω(content).Should(ContainSubstring("resp, err := c.send(tctx, req)"))
+			Ω(content).ShouldNot(ContainSubstring("c.sendShared(tctx, req)"))
+		})
+	})
+
+	Context("with an action streaming newline-delimited JSON", func() {
+		BeforeEach(func() {
+			lineMT := &design.MediaTypeDefinition{
+				UserTypeDefinition: &design.UserTypeDefinition{
+					AttributeDefinition: &design.AttributeDefinition{
+						Type: design.Object{
+							"id": &design.AttributeDefinition{Type: design.Integer},
+						},
+					},
+					TypeName: "Line",
+				},
+				Identifier: "application/x-ndjson",
+			}
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				MediaTypes: map[string]*design.MediaTypeDefinition{
+					lineMT.Identifier: lineMT,
+				},
+				Resources: map[string]*design.ResourceDefinition{
+					"user": {
+						Name: "user",
+						Actions: map[string]*design.ActionDefinition{
+							"export": {
+								Name: "export",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: "/export"},
+								},
+								Responses: map[string]*design.ResponseDefinition{
+									"OK": {Name: "OK", Status: 200, MediaType: lineMT.Identifier},
+								},
+							},
+						},
+					},
+				},
+			}
+			userRes := design.Design.Resources["user"]
+			exportAct := userRes.Actions["export"]
+			exportAct.Parent = userRes
+			exportAct.Routes[0].Parent = exportAct
+		})
+
+		It("generates a method decoding the lines", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "user.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) ExportUserLines(ctx context.Context, path string) (<-chan *Line, <-chan error) {"))
+			Ω(content).Should(ContainSubstring("resp, err := c.ExportUser(ctx, path)"))
+			Ω(content).Should(ContainSubstring("decoder := json.NewDecoder(resp.Body)"))
+			Ω(content).Should(ContainSubstring("} else if err != io.EOF {"))
+			Ω(content).Should(ContainSubstring("case values <- &decoded:"))
+			Ω(content).Should(ContainSubstring(`req.Header.Set("Accept", "application/x-ndjson")`))
+			Ω(content).Should(ContainSubstring("resp, err := c.send(tctx, req)"))
+			Ω(content).ShouldNot(ContainSubstring("ExportUserEvents"))
+		})
+	})
+
+	Context("with an action returning a multipart response", func() {
+		BeforeEach(func() {
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"user": {
+						Name: "user",
+						Actions: map[string]*design.ActionDefinition{
+							"report": {
+								Name: "report",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: "/report"},
+								},
+								Responses: map[string]*design.ResponseDefinition{
+									"OK": {Name: "OK", Status: 200, MediaType: "multipart/mixed"},
+								},
+							},
+						},
+					},
+				},
+			}
+			userRes := design.Design.Resources["user"]
+			reportAct := userRes.Actions["report"]
+			reportAct.Parent = userRes
+			reportAct.Routes[0].Parent = reportAct
+		})
+
+		It("generates a method reading the parts", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "user.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) ReportUserParts(ctx context.Context, path string) ([]*goaclient.Part, *http.Response, error) {"))
+			Ω(content).Should(ContainSubstring("resp, err := c.ReportUser(ctx, path)"))
+			Ω(content).Should(ContainSubstring("parts, err := goaclient.ReadParts(resp)"))
+			Ω(content).Should(ContainSubstring(`req.Header.Set("Accept", "multipart/mixed")`))
+		})
+	})
+
+	Context("with an excluded resource", func() {
+		BeforeEach(func() {
+			os.Args = append(os.Args, "--exclude=admin")
+			newMT := func(typeName, identifier string) *design.MediaTypeDefinition {
+				attrs := design.Object{"id": &design.AttributeDefinition{Type: design.Integer}}
+				mt := &design.MediaTypeDefinition{
+					UserTypeDefinition: &design.UserTypeDefinition{
+						AttributeDefinition: &design.AttributeDefinition{Type: attrs},
+						TypeName:            typeName,
+					},
+					Identifier: identifier,
+				}
+				mt.Views = map[string]*design.ViewDefinition{
+					"default": {
+						AttributeDefinition: &design.AttributeDefinition{Type: attrs},
+						Name:                "default",
+						Parent:              mt,
+					},
+				}
+				return mt
+			}
+			userMT := newMT("User", "application/vnd.user+json")
+			auditMT := newMT("Audit", "application/vnd.audit+json")
+			newAction := func(name string, mt *design.MediaTypeDefinition) *design.ActionDefinition {
+				return &design.ActionDefinition{
+					Name:   name,
+					Routes: []*design.RouteDefinition{{Verb: "GET", Path: ""}},
+					Responses: map[string]*design.ResponseDefinition{
+						"OK": {Name: "OK", Status: 200, MediaType: mt.Identifier},
+					},
+				}
+			}
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				MediaTypes: map[string]*design.MediaTypeDefinition{
+					userMT.Identifier:  userMT,
+					auditMT.Identifier: auditMT,
+				},
+				Resources: map[string]*design.ResourceDefinition{
+					"user": {
+						Name: "user",
+						Actions: map[string]*design.ActionDefinition{
+							"show": newAction("show", userMT),
+						},
+					},
+					"admin": {
+						Name: "admin",
+						Actions: map[string]*design.ActionDefinition{
+							"show":  newAction("show", userMT),
+							"audit": newAction("audit", auditMT),
+						},
+					},
+				},
+			}
+			design.GeneratedMediaTypes = make(design.MediaTypeRoot)
+			for _, res := range design.Design.Resources {
+				for _, act := range res.Actions {
+					act.Parent = res
+					act.Routes[0].Parent = act
+				}
+			}
+		})
+
+		It("omits the excluded resources but keeps the types of the other resources", func() {
+			Ω(genErr).Should(BeNil())
+			_, err := os.Stat(filepath.Join(outDir, "client", "admin.go"))
+			Ω(os.IsNotExist(err)).Should(BeTrue())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "user.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) ShowUser(ctx context.Context, path string) (*http.Response, error) {"))
+			types, err := ioutil.ReadFile(filepath.Join(outDir, "client", "datatypes.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(types).Should(ContainSubstring("type User struct {"))
+			Ω(types).ShouldNot(ContainSubstring("type Audit struct {"))
+		})
+
+		Context("with included actions instead", func() {
+			BeforeEach(func() {
+				os.Args = append(os.Args, "--exclude=", "--include=*.show")
+			})
+
+			It("only generates the included actions", func() {
+				Ω(genErr).Should(BeNil())
+				content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "admin.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).Should(ContainSubstring("func (c *Client) ShowAdmin("))
+				Ω(content).ShouldNot(ContainSubstring("func (c *Client) AuditAdmin("))
+			})
+		})
+
+		Context("with an excluded action instead", func() {
+			BeforeEach(func() {
+				os.Args = append(os.Args, "--exclude=admin.audit")
+			})
+
+			It("only omits the excluded action", func() {
+				Ω(genErr).Should(BeNil())
+				content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "admin.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).Should(ContainSubstring("func (c *Client) ShowAdmin("))
+				Ω(content).ShouldNot(ContainSubstring("func (c *Client) AuditAdmin("))
+				types, err := ioutil.ReadFile(filepath.Join(outDir, "client", "datatypes.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(types).Should(ContainSubstring("type User struct {"))
+				Ω(types).ShouldNot(ContainSubstring("type Audit struct {"))
+			})
+		})
+	})
+
+	Context("with an action of a resource", func() {
+		BeforeEach(func() {
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"bottle": {
+						Name: "bottle",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name: "show",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: ""},
+								},
+							},
+						},
+					},
+				},
+			}
+			bottleRes := design.Design.Resources["bottle"]
+			showAct := bottleRes.Actions["show"]
+			showAct.Parent = bottleRes
+			showAct.Routes[0].Parent = showAct
+		})
+
+		It("generates the flat client methods only", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "bottle.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) ShowBottle(ctx context.Context, path string) (*http.Response, error) {"))
+			Ω(content).ShouldNot(ContainSubstring("BottleClient"))
+		})
+
+		Context("with grouped clients enabled", func() {
+			BeforeEach(func() {
+				os.Args = append(os.Args, "--grouped")
+			})
+
+			It("generates a resource client and keeps the flat methods", func() {
+				Ω(genErr).Should(BeNil())
+				content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "bottle.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).Should(ContainSubstring("type BottleClient struct {"))
+				Ω(content).Should(ContainSubstring("func (c *Client) Bottles() *BottleClient {"))
+				Ω(content).Should(ContainSubstring("func (r *BottleClient) Show(ctx context.Context, path string) (*http.Response, error) {"))
+				Ω(content).Should(ContainSubstring("return r.client.ShowBottle(ctx, path)"))
+				Ω(content).Should(ContainSubstring("func (c *Client) ShowBottle(ctx context.Context, path string) (*http.Response, error) {"))
+			})
+		})
+	})
+
+	Context("with websocket wrappers enabled", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			os.Args = append(os.Args, "--ws-wrappers")
+			userMT := &design.MediaTypeDefinition{
+				UserTypeDefinition: &design.UserTypeDefinition{
+					AttributeDefinition: &design.AttributeDefinition{
+						Type: design.Object{
+							"id": &design.AttributeDefinition{Type: design.Integer},
+						},
+					},
+					TypeName: "User",
+				},
+				Identifier: "application/vnd.user+json",
+			}
+			collMT := &design.MediaTypeDefinition{
+				UserTypeDefinition: &design.UserTypeDefinition{
+					AttributeDefinition: &design.AttributeDefinition{
+						Type: &design.Array{ElemType: &design.AttributeDefinition{Type: userMT}},
+					},
+					TypeName: "UserCollection",
+				},
+				Identifier: "application/vnd.user+json; type=collection",
+			}
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				MediaTypes: map[string]*design.MediaTypeDefinition{
+					userMT.Identifier: userMT,
+					collMT.Identifier: collMT,
+				},
+				Resources: map[string]*design.ResourceDefinition{
+					"user": {
+						Name: "user",
+						Actions: map[string]*design.ActionDefinition{
+							"watch": {
+								Name:    "watch",
+								Schemes: []string{"ws"},
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: "/watch"},
+								},
+								Responses: map[string]*design.ResponseDefinition{
+									"OK": {Name: "OK", Status: 200, MediaType: collMT.Identifier},
+								},
+							},
+						},
+					},
+				},
+			}
+			userRes := design.Design.Resources["user"]
+			watchAct := userRes.Actions["watch"]
+			watchAct.Parent = userRes
+			watchAct.Routes[0].Parent = watchAct
+		})
+
+		It("generates a typed wrapper for the websocket connection", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "user.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("type UserWatchStream struct {"))
+			Ω(content).Should(ContainSubstring("func (c *Client) WatchUserStream(ctx context.Context, path string) (*UserWatchStream, error) {"))
+			Ω(content).Should(ContainSubstring("func (s *UserWatchStream) Read() (UserCollection, error) {"))
+			Ω(content).Should(ContainSubstring("func (s *UserWatchStream) Close() error {"))
+		})
+	})
+
+	Context("with an action following cursors", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			userMT := &design.MediaTypeDefinition{
+				UserTypeDefinition: &design.UserTypeDefinition{
+					AttributeDefinition: &design.AttributeDefinition{
+						Type: design.Object{
+							"id":          &design.AttributeDefinition{Type: design.Integer},
+							"next_cursor": &design.AttributeDefinition{Type: design.String},
+						},
+					},
+					TypeName: "User",
+				},
+				Identifier: "application/vnd.user+json",
+			}
+			collMT := &design.MediaTypeDefinition{
+				UserTypeDefinition: &design.UserTypeDefinition{
+					AttributeDefinition: &design.AttributeDefinition{
+						Type: &design.Array{ElemType: &design.AttributeDefinition{Type: userMT}},
+					},
+					TypeName: "UserCollection",
+				},
+				Identifier: "application/vnd.user+json; type=collection",
+			}
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				MediaTypes: map[string]*design.MediaTypeDefinition{
+					userMT.Identifier: userMT,
+					collMT.Identifier: collMT,
+				},
+				Resources: map[string]*design.ResourceDefinition{
+					"user": {
+						Name: "user",
+						Actions: map[string]*design.ActionDefinition{
+							"list": {
+								Name: "list",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: ""},
+								},
+								QueryParams: &design.AttributeDefinition{
+									Type: design.Object{
+										"cursor": &design.AttributeDefinition{Type: design.String},
+									},
+								},
+								Responses: map[string]*design.ResponseDefinition{
+									"OK": {Name: "OK", Status: 200, MediaType: collMT.Identifier},
+								},
+								Metadata: dslengine.MetadataDefinition{"client:cursor": {"next_cursor"}},
+							},
+						},
+					},
+				},
+			}
+			userRes := design.Design.Resources["user"]
+			listAct := userRes.Actions["list"]
+			listAct.Parent = userRes
+			listAct.Routes[0].Parent = listAct
+		})
+
+		It("generates a method accumulating the pages", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "user.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) ListUserAll(ctx context.Context, path string, cursor *string) (UserCollection, error) {"))
+			Ω(content).Should(ContainSubstring("page, err := c.DecodeUserCollection(resp)"))
+			Ω(content).Should(ContainSubstring("next := goaclient.NextCursor(resp, \"cursor\")\n" +
+				"\t\tif next == \"\" && len(page) > 0 && page[len(page)-1].NextCursor != nil {\n" +
+				"\t\t\tnext = *page[len(page)-1].NextCursor\n"))
+			Ω(content).Should(ContainSubstring("cursor = &next"))
+			Ω(content).ShouldNot(ContainSubstring("ListUserPage"))
+		})
+
+		Context("with pagination total headers", func() {
+			BeforeEach(func() {
+				ok := design.Design.Resources["user"].Actions["list"].Responses["OK"]
+				ok.Headers = &design.AttributeDefinition{
+					Type: design.Object{
+						"X-Total-Count": &design.AttributeDefinition{Type: design.Integer},
+						"X-Total-Pages": &design.AttributeDefinition{Type: design.Integer},
+					},
+				}
+			})
+
+			It("generates a method decoding the page and its metadata", func() {
+				Ω(genErr).Should(BeNil())
+				content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "user.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).Should(ContainSubstring("func (c *Client) ListUserPage(ctx context.Context, path string, cursor *string) (UserCollection, goaclient.PageMeta, error) {"))
+				Ω(content).Should(ContainSubstring("meta, err := goaclient.ParsePageMeta(resp)"))
+				Ω(content).Should(ContainSubstring("items, err := c.DecodeUserCollection(resp)"))
+				Ω(content).Should(ContainSubstring("return items, meta, nil"))
+			})
+		})
+
+		It("generates the helpers converting the collection to a slice and back", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "datatypes.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func NewUserCollection(s []*User) UserCollection {\n\treturn UserCollection(s)\n}"))
+			Ω(content).Should(ContainSubstring("func (c UserCollection) Slice() []*User {\n\treturn []*User(c)\n}"))
+		})
+
+		Context("without the cursor query parameter", func() {
+			BeforeEach(func() {
+				design.Design.Resources["user"].Actions["list"].QueryParams = nil
+			})
+
+			It("returns an error", func() {
+				Ω(genErr).Should(HaveOccurred())
+				Ω(genErr.Error()).Should(ContainSubstring("missing query parameter cursor"))
+			})
+		})
+	})
+
+	Context("with an action with an operationId", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name: "show",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: ""},
+								},
+								Metadata: dslengine.MetadataDefinition{"operationId": {"getFoo"}},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			showAct := fooRes.Actions["show"]
+			showAct.Parent = fooRes
+			showAct.Routes[0].Parent = showAct
+		})
+
+		It("names the client methods after the operationId", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) GetFoo(ctx context.Context, path string) (*http.Response, error) {"))
+			Ω(content).Should(ContainSubstring("func (c *Client) NewGetFooRequest(ctx context.Context, path string) (*http.Request, error) {"))
+			Ω(content).ShouldNot(ContainSubstring("ShowFoo("))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "commands.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("resp, err := c.GetFoo(ctx, path)"))
+		})
+	})
+
+	Context("with an action mixing required and optional params", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"list": {
+								Name: "list",
+								Routes: []*design.RouteDefinition{
+									{Verb: "POST", Path: ""},
+								},
+								Payload: &design.UserTypeDefinition{
+									AttributeDefinition: &design.AttributeDefinition{
+										Type: design.Object{
+											"name": &design.AttributeDefinition{Type: design.String},
+										},
+									},
+									TypeName: "ListFooPayload",
+								},
+								QueryParams: &design.AttributeDefinition{
+									Type: design.Object{
+										"zoo":   &design.AttributeDefinition{Type: design.String},
+										"ids":   &design.AttributeDefinition{Type: &design.Array{ElemType: &design.AttributeDefinition{Type: design.Integer}}},
+										"limit": &design.AttributeDefinition{Type: design.Integer},
+										"after": &design.AttributeDefinition{Type: design.String},
+									},
+									Validation: &dslengine.ValidationDefinition{Required: []string{"zoo", "ids"}},
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			listAct := fooRes.Actions["list"]
+			listAct.Parent = fooRes
+			listAct.Routes[0].Parent = listAct
+		})
+
+		It("aligns the arguments types and names", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) NewListFooRequest(ctx context.Context, path string, payload *ListFooPayload, ids []int, zoo string, after *string, limit *int) (*http.Request, error) {"))
+			Ω(content).Should(ContainSubstring("req, err := c.NewListFooRequest(ctx, path, payload, ids, zoo, after, limit)"))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "commands.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("cmd.Ids, cmd.Zoo, &cmd.After, &cmd.Limit)"))
+		})
+
+		Context("with query structs enabled", func() {
+			BeforeEach(func() {
+				os.Args = append(os.Args, "--query-structs")
+			})
+
+			It("replaces the query parameter arguments with a struct", func() {
+				Ω(genErr).Should(BeNil())
+				content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).Should(ContainSubstring("type ListFooQuery struct {"))
+				Ω(content).Should(MatchRegexp(`Ids +\[\]int\n`))
+				Ω(content).Should(MatchRegexp(`Zoo +string\n`))
+				Ω(content).Should(MatchRegexp(`After +\*string\n`))
+				Ω(content).Should(MatchRegexp(`Limit +\*int\n`))
+				Ω(content).Should(ContainSubstring("func (c *Client) NewListFooRequest(ctx context.Context, path string, payload *ListFooPayload, query ListFooQuery) (*http.Request, error) {"))
+				Ω(content).Should(ContainSubstring("req, err := c.NewListFooRequest(ctx, path, payload, query)"))
+				Ω(content).Should(ContainSubstring("if query.After != nil {\n\t\tvalues.Set(\"after\", *query.After)\n\t}"))
+				Ω(content).Should(ContainSubstring("values.Set(\"zoo\", query.Zoo)"))
+				Ω(content).ShouldNot(ContainSubstring("ids []int, zoo string, after *string, limit *int"))
+				content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "commands.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).Should(ContainSubstring("client.ListFooQuery{Ids: cmd.Ids, Zoo: cmd.Zoo, After: &cmd.After, Limit: &cmd.Limit})"))
+			})
+		})
+
+		Context("with ordered query strings enabled", func() {
+			BeforeEach(func() {
+				os.Args = append(os.Args, "--ordered-query")
+			})
+
+			It("appends the query string parameters in the order of the arguments", func() {
+				Ω(genErr).Should(BeNil())
+				content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).ShouldNot(ContainSubstring("values.Encode()"))
+				Ω(content).Should(ContainSubstring("if after != nil {\n\t\tpairs = append(pairs, url.QueryEscape(\"after\")+\"=\"+url.QueryEscape(*after))\n\t}"))
+				Ω(content).Should(ContainSubstring(`u.RawQuery = strings.Join(pairs, "&")`))
+				ids := strings.Index(string(content), `url.QueryEscape("ids")`)
+				zoo := strings.Index(string(content), `url.QueryEscape("zoo")`)
+				after := strings.Index(string(content), `url.QueryEscape("after")`)
+				limit := strings.Index(string(content), `url.QueryEscape("limit")`)
+				Ω(ids).Should(BeNumerically(">", 0))
+				Ω(zoo).Should(BeNumerically(">", ids))
+				Ω(after).Should(BeNumerically(">", zoo))
+				Ω(limit).Should(BeNumerically(">", after))
+			})
+
+			Context("and query structs", func() {
+				BeforeEach(func() {
+					os.Args = append(os.Args, "--query-structs")
+				})
+
+				It("appends the fields of the query struct in order", func() {
+					Ω(genErr).Should(BeNil())
+					content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+					Ω(err).ShouldNot(HaveOccurred())
+					Ω(content).Should(ContainSubstring("if query.After != nil {\n\t\tpairs = append(pairs, url.QueryEscape(\"after\")+\"=\"+url.QueryEscape(*query.After))\n\t}"))
+					out, err := runGeneratedTest(filepath.Join(outDir, "client"), orderedQueryTest)
+					Ω(err).ShouldNot(HaveOccurred(), out)
+				})
+			})
+
+			Context("and a query order", func() {
+				BeforeEach(func() {
+					design.Design.Resources["foo"].Actions["list"].Metadata = dslengine.MetadataDefinition{
+						"client:query-order": {"limit", "zoo", "after"},
+					}
+				})
+
+				It("appends the listed parameters first in the given order", func() {
+					Ω(genErr).Should(BeNil())
+					content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+					Ω(err).ShouldNot(HaveOccurred())
+					limit := strings.Index(string(content), `url.QueryEscape("limit")`)
+					zoo := strings.Index(string(content), `url.QueryEscape("zoo")`)
+					after := strings.Index(string(content), `url.QueryEscape("after")`)
+					ids := strings.Index(string(content), `url.QueryEscape("ids")`)
+					Ω(limit).Should(BeNumerically(">", 0))
+					Ω(zoo).Should(BeNumerically(">", limit))
+					Ω(after).Should(BeNumerically(">", zoo))
+					Ω(ids).Should(BeNumerically(">", after))
+				})
+			})
+		})
+	})
+
+	Context("with a JSON decoder", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			attrs := design.Object{
+				"id": &design.AttributeDefinition{Type: design.Integer},
+			}
+			bottleMT := &design.MediaTypeDefinition{
+				UserTypeDefinition: &design.UserTypeDefinition{
+					AttributeDefinition: &design.AttributeDefinition{Type: attrs},
+					TypeName:            "Bottle",
+				},
+				Identifier: "application/vnd.bottle+json",
+			}
+			bottleMT.Views = map[string]*design.ViewDefinition{
+				"default": {
+					AttributeDefinition: &design.AttributeDefinition{Type: attrs},
+					Name:                "default",
+					Parent:              bottleMT,
+				},
+			}
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Consumes: []*design.EncodingDefinition{
+					{MIMETypes: []string{"application/json"}},
+				},
+				MediaTypes: map[string]*design.MediaTypeDefinition{bottleMT.Identifier: bottleMT},
+				Resources: map[string]*design.ResourceDefinition{
+					"bottle": {
+						Name: "bottle",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name:   "show",
+								Routes: []*design.RouteDefinition{{Verb: "GET", Path: ""}},
+								Responses: map[string]*design.ResponseDefinition{
+									"OK": {Name: "OK", Status: 200, MediaType: bottleMT.Identifier},
+								},
+							},
+						},
+					},
+				},
+			}
+			design.GeneratedMediaTypes = make(design.MediaTypeRoot)
+			bottleRes := design.Design.Resources["bottle"]
+			showAct := bottleRes.Actions["show"]
+			showAct.Parent = bottleRes
+			showAct.Routes[0].Parent = showAct
+		})
+
+		It("generates strict and number decoding options", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithStrictDecoding() Option {"))
+			Ω(content).Should(ContainSubstring("func WithNumberDecoding() Option {"))
+			Ω(content).Should(ContainSubstring("d.DisallowUnknownFields()"))
+			Ω(content).Should(ContainSubstring("d.UseNumber()"))
+			Ω(content).Should(ContainSubstring(`}, "application/json", "*/*")`))
+		})
+
+		It("fails to decode the fields not defined in the design in strict mode", func() {
+			Ω(genErr).Should(BeNil())
+			out, err := runGeneratedTest(filepath.Join(outDir, "client"), strictDecodingTest)
+			Ω(err).ShouldNot(HaveOccurred(), out)
+		})
+
+		It("decodes the gzip compressed responses requested with WithAcceptGzip", func() {
+			Ω(genErr).Should(BeNil())
+			out, err := runGeneratedTest(filepath.Join(outDir, "client"), acceptGzipTest)
+			Ω(err).ShouldNot(HaveOccurred(), out)
+		})
+	})
+
+	Context("with a nested resource", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			intParam := func(names ...string) *design.AttributeDefinition {
+				obj := design.Object{}
+				for _, n := range names {
+					obj[n] = &design.AttributeDefinition{Type: design.Integer}
+				}
+				return &design.AttributeDefinition{Type: obj}
+			}
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"account": {
+						Name:                "account",
+						BasePath:            "/accounts",
+						CanonicalActionName: "show",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name:        "show",
+								Routes:      []*design.RouteDefinition{{Verb: "GET", Path: "/:accountID"}},
+								Params:      intParam("accountID"),
+								QueryParams: intParam(),
+							},
+						},
+					},
+					"bottle": {
+						Name:       "bottle",
+						BasePath:   "/bottles",
+						ParentName: "account",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name:        "show",
+								Routes:      []*design.RouteDefinition{{Verb: "GET", Path: "/:bottleID"}},
+								Params:      intParam("accountID", "bottleID", "verbose"),
+								QueryParams: intParam("verbose"),
+							},
+						},
+					},
+				},
+			}
+			for _, res := range design.Design.Resources {
+				for _, a := range res.Actions {
+					a.Parent = res
+					a.Routes[0].Parent = a
+				}
+			}
+		})
+
+		It("does not generate methods taking the path parameters", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "bottle.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).ShouldNot(ContainSubstring("ShowBottleInAccount"))
+		})
+
+		Context("with nested methods enabled", func() {
+			BeforeEach(func() {
+				os.Args = append(os.Args, "--nested-methods")
+			})
+
+			It("generates methods taking the path parameters of the actions of the nested resources", func() {
+				Ω(genErr).Should(BeNil())
+				content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "bottle.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).Should(ContainSubstring("func (c *Client) ShowBottleInAccount(ctx context.Context, accountID int, bottleID int, verbose *int) (*http.Response, error) {\n" +
+					"\treturn c.ShowBottle(ctx, ShowBottlePath(accountID, bottleID), verbose)\n}"))
+				content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "account.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).ShouldNot(ContainSubstring("ShowAccountIn"))
+			})
+		})
+	})
+
+	Context("with the CLI generation disabled", func() {
+		BeforeEach(func() {
+			os.Args = append(os.Args, "--no-cli")
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name:   "show",
+								Routes: []*design.RouteDefinition{{Verb: "GET", Path: ""}},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			showAct := fooRes.Actions["show"]
+			showAct.Parent = fooRes
+			showAct.Routes[0].Parent = showAct
+		})
+
+		It("only generates the client package", func() {
+			Ω(genErr).Should(BeNil())
+			toolDir := filepath.Join(outDir, "client", "testapi-cli")
+			Ω(files).Should(ContainElement(filepath.Join(outDir, "client", "client.go")))
+			Ω(files).Should(ContainElement(filepath.Join(outDir, "client", "foo.go")))
+			for _, f := range files {
+				Ω(f).ShouldNot(HavePrefix(toolDir))
+			}
+			_, err := os.Stat(toolDir)
+			Ω(os.IsNotExist(err)).Should(BeTrue())
+		})
+	})
+
+	Context("with a versioned API and the versioned flag", func() {
+		BeforeEach(func() {
+			os.Args = append(os.Args, "--versioned")
+			design.Design = &design.APIDefinition{
+				Name:    "testapi",
+				Version: "2.1",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name:   "show",
+								Routes: []*design.RouteDefinition{{Verb: "GET", Path: ""}},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			showAct := fooRes.Actions["show"]
+			showAct.Parent = fooRes
+			showAct.Routes[0].Parent = showAct
+		})
+
+		It("generates the client package in the version directory", func() {
+			Ω(genErr).Should(BeNil())
+			versionDir := filepath.Join(outDir, "client", "v2")
+			Ω(files).Should(ContainElement(filepath.Join(versionDir, "client.go")))
+			Ω(files).Should(ContainElement(filepath.Join(versionDir, "foo.go")))
+			Ω(files).Should(ContainElement(filepath.Join(versionDir, "testapi-cli", "main.go")))
+			content, err := ioutil.ReadFile(filepath.Join(versionDir, "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("package v2\n"))
+			content, err = ioutil.ReadFile(filepath.Join(versionDir, "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("package v2\n"))
+			content, err = ioutil.ReadFile(filepath.Join(versionDir, "testapi-cli", "commands.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(MatchRegexp(`client "[^"]*/client/v2"`))
+		})
+	})
+
+	Context("with multiple decoders", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Consumes: []*design.EncodingDefinition{
+					{MIMETypes: []string{"application/xml"}},
+					{MIMETypes: []string{"application/json"}},
+				},
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name: "show",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: ""},
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			showAct := fooRes.Actions["show"]
+			showAct.Parent = fooRes
+			showAct.Routes[0].Parent = showAct
+		})
+
+		It("sets the Accept header to the decoder MIME types", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("if req.Header.Get(\"Accept\") == \"\" {\n\t\treq.Header.Set(\"Accept\", \"application/xml, application/json\")"))
+		})
+
+		Context("and encoders", func() {
+			BeforeEach(func() {
+				design.Design.Produces = []*design.EncodingDefinition{
+					{MIMETypes: []string{"application/json"}},
+				}
+			})
+
+			It("lists the registered MIME types", func() {
+				Ω(genErr).Should(BeNil())
+				content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).Should(ContainSubstring("func (c *Client) SupportedContentTypes() []string {\n" +
+					"\treturn []string{\"application/json\", \"application/xml\"}\n}"))
+			})
+		})
+	})
+
+	Context("with an action whose payload is an array", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			bottle := &design.UserTypeDefinition{
+				AttributeDefinition: &design.AttributeDefinition{
+					Type: design.Object{
+						"name": &design.AttributeDefinition{Type: design.String},
+					},
+				},
+				TypeName: "Bottle",
+			}
+			design.Design = &design.APIDefinition{
+				Name:  "testapi",
+				Types: map[string]*design.UserTypeDefinition{"Bottle": bottle},
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"bulk": {
+								Name: "bulk",
+								Routes: []*design.RouteDefinition{
+									{Verb: "POST", Path: ""},
+								},
+								Payload: &design.UserTypeDefinition{
+									AttributeDefinition: &design.AttributeDefinition{
+										Type: &design.Array{ElemType: &design.AttributeDefinition{Type: bottle}},
+									},
+									TypeName: "BulkFooPayload",
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			bulkAct := fooRes.Actions["bulk"]
+			bulkAct.Parent = fooRes
+			bulkAct.Routes[0].Parent = bulkAct
+		})
+
+		It("generates methods accepting a slice of the element type", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) BulkFoo(ctx context.Context, path string, payload []*Bottle) (*http.Response, error) {"))
+			Ω(content).Should(ContainSubstring("func (c *Client) NewBulkFooRequest(ctx context.Context, path string, payload []*Bottle) (*http.Request, error) {"))
+			Ω(content).ShouldNot(ContainSubstring("BulkFooPayload"))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "commands.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("var payload []*client.Bottle\n"))
+			Ω(content).Should(ContainSubstring("c.BulkFoo(ctx, path, payload)"))
+		})
+	})
+
+	Context("with an action consuming XML", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Consumes: []*design.EncodingDefinition{
+					{MIMETypes: []string{"application/xml"}},
+				},
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"create": {
+								Name: "create",
+								Routes: []*design.RouteDefinition{
+									{Verb: "POST", Path: ""},
+								},
+								Payload: &design.UserTypeDefinition{
+									AttributeDefinition: &design.AttributeDefinition{
+										Type: design.Object{
+											"id": &design.AttributeDefinition{
+												Type:     design.Integer,
+												Metadata: dslengine.MetadataDefinition{"struct:field:xml": []string{"id", "attr"}},
+											},
+											"name": &design.AttributeDefinition{Type: design.String},
+										},
+									},
+									TypeName: "CreateFooPayload",
+								},
+								Metadata: dslengine.MetadataDefinition{"client:content-type": []string{"application/xml"}},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			createAct := fooRes.Actions["create"]
+			createAct.Parent = fooRes
+			createAct.Routes[0].Parent = createAct
+		})
+
+		It("generates the xml struct tags of the payload", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(MatchRegexp("ID +\\*int +`json:\"id,omitempty\" xml:\"id,attr,omitempty\"`"))
+			Ω(content).Should(MatchRegexp("Name +\\*string +`json:\"name,omitempty\" xml:\"name,omitempty\"`"))
+			Ω(content).Should(ContainSubstring(`rest, err := c.encodeBody(payload, &body, "application/xml")`))
+		})
+	})
+
+	Context("with an action returning a binary response", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"download": {
+								Name: "download",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: "/download"},
+								},
+								Responses: map[string]*design.ResponseDefinition{
+									"OK": {
+										Name:      "OK",
+										Status:    200,
+										MediaType: "application/octet-stream",
+									},
+								},
+							},
+							"show": {
+								Name: "show",
+								Routes: []*design.RouteDefinition{
+									{Verb: "GET", Path: ""},
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			for _, a := range fooRes.Actions {
+				a.Parent = fooRes
+				a.Routes[0].Parent = a
+			}
+		})
+
+		It("generates a method returning the response body unread", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) DownloadFooStream(ctx context.Context, path string) (io.ReadCloser, *http.Response, error) {"))
+			Ω(content).Should(ContainSubstring("return resp.Body, resp, nil"))
+			Ω(content).ShouldNot(ContainSubstring("ShowFooStream"))
+		})
+	})
+
+	Context("with an action secured with basic auth", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			basic := &design.SecuritySchemeDefinition{
+				SchemeName: "admin",
+				Kind:       design.BasicAuthSecurityKind,
+			}
+			design.Design = &design.APIDefinition{
+				Name:            "testapi",
+				SecuritySchemes: []*design.SecuritySchemeDefinition{basic},
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"delete": {
+								Name: "delete",
+								Routes: []*design.RouteDefinition{
+									{Verb: "DELETE", Path: ""},
+								},
+								Security: &design.SecurityDefinition{Scheme: basic},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			deleteAct := fooRes.Actions["delete"]
+			deleteAct.Parent = fooRes
+			deleteAct.Routes[0].Parent = deleteAct
+		})
+
+		It("generates an option setting the credentials", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithAdminAuth(username, password string) Option {"))
+			Ω(content).Should(ContainSubstring("c.AdminSigner.Username = username"))
+			Ω(content).Should(ContainSubstring("c.AdminSigner.Password = password"))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("c.AdminSigner.Sign(ctx, req)"))
+		})
+	})
+
+	Context("with multiple security schemes", func() {
+		BeforeEach(func() {
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				SecuritySchemes: []*design.SecuritySchemeDefinition{
+					{SchemeName: "key", Kind: design.APIKeySecurityKind},
+					{SchemeName: "basic", Kind: design.BasicAuthSecurityKind},
+					{SchemeName: "jwt", Kind: design.JWTSecurityKind},
+				},
+			}
+		})
+
+		It("generates the signers in a stable order", func() {
+			Ω(genErr).Should(BeNil())
+			first, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			schemes := design.Design.SecuritySchemes
+			schemes[0], schemes[2] = schemes[2], schemes[0]
+			_, err = genclient.Generate()
+			Ω(err).ShouldNot(HaveOccurred())
+			second, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(second).Should(Equal(first))
+			basic := strings.Index(string(first), "\tBasicSigner ")
+			jwt := strings.Index(string(first), "\tJWTSigner ")
+			key := strings.Index(string(first), "\tKeySigner ")
+			Ω(basic).Should(BeNumerically(">", 0))
+			Ω(jwt).Should(BeNumerically(">", basic))
+			Ω(key).Should(BeNumerically(">", jwt))
+		})
+
+		It("generates a config struct with the credentials of the schemes", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func NewWithConfig(cfg Config) *Client {"))
+			Ω(content).Should(MatchRegexp(`BasicUsername\s+string`))
+			Ω(content).Should(MatchRegexp(`BasicPassword\s+string`))
+			Ω(content).Should(MatchRegexp(`KeyKey\s+string`))
+			Ω(content).Should(MatchRegexp(`JWTToken\s+string`))
+			Ω(content).ShouldNot(ContainSubstring("RefreshToken"))
+			Ω(content).Should(ContainSubstring("client.BasicSigner.Username = cfg.BasicUsername"))
+			Ω(content).Should(ContainSubstring("client.KeySigner.Key = cfg.KeyKey"))
+			Ω(content).Should(ContainSubstring("client.JWTSigner.Token = cfg.JWTToken"))
+		})
+
+		It("removes the headers of the signers from the requests redirected to another host", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("client.setupRedirects()"))
+			Ω(content).Should(ContainSubstring("if !strings.EqualFold(req.URL.Host, via[0].URL.Host) {"))
+			Ω(content).Should(ContainSubstring(`headers := []string{"Authorization", "Cookie"}`))
+			Ω(content).Should(ContainSubstring("if c.KeySigner.Header != \"\" {\n\t\theaders = append(headers, c.KeySigner.Header)\n\t}"))
+			Ω(content).Should(ContainSubstring("headers = append(headers, c.JWTSigner.Header)"))
+			Ω(content).ShouldNot(ContainSubstring("c.BasicSigner.Header"))
+			Ω(content).Should(ContainSubstring("func WithRedirectAuth() Option {"))
+		})
+	})
+
+	Context("with an API key security scheme using a query string parameter", func() {
+		BeforeEach(func() {
+			design.Design = &design.APIDefinition{
+				Name: "testapi",
+				SecuritySchemes: []*design.SecuritySchemeDefinition{
+					{SchemeName: "key", Kind: design.APIKeySecurityKind, In: "query", Name: "token"},
+					{SchemeName: "jwt", Kind: design.JWTSecurityKind, In: "header", Name: "Authorization"},
+				},
+			}
+		})
+
+		It("initializes the signer with the query string parameter name", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring(`KeySigner: &goaclient.APIKeySigner{Query: "token"},`))
+			Ω(content).Should(ContainSubstring("JWTSigner: &goaclient.JWTSigner{},"))
+		})
+	})
+
+	Context("with a security scheme signed with AWS SigV4", func() {
+		BeforeEach(func() {
+			iam := &design.SecuritySchemeDefinition{SchemeName: "iam", Kind: design.APIKeySecurityKind, In: "header", Name: "Authorization"}
+			design.Design = &design.APIDefinition{
+				Name:            "testapi",
+				SecuritySchemes: []*design.SecuritySchemeDefinition{iam},
+				Metadata:        dslengine.MetadataDefinition{"client:aws-sigv4": {"iam"}},
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name:     "show",
+								Routes:   []*design.RouteDefinition{{Verb: "GET", Path: ""}},
+								Security: &design.SecurityDefinition{Scheme: iam},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			showAct := fooRes.Actions["show"]
+			showAct.Parent = fooRes
+			showAct.Routes[0].Parent = showAct
+		})
+
+		It("signs the requests with the AWS SigV4 signer", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("IamSigner: &goaclient.AWSV4Signer{},"))
+			Ω(content).Should(ContainSubstring("func WithIamCredentials(accessKeyID, secretAccessKey, region string) Option {"))
+			Ω(content).Should(ContainSubstring("client.IamSigner.Region = cfg.IamRegion"))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("if !c.IamSigner.Configured() {"))
+			Ω(content).Should(ContainSubstring("if err := c.IamSigner.Sign(ctx, req); err != nil {"))
+		})
+
+		It("sends the requests with a SigV4 Authorization header", func() {
+			Ω(genErr).Should(BeNil())
+			out, err := runGeneratedTest(filepath.Join(outDir, "client"), awsSigV4Test)
+			Ω(err).ShouldNot(HaveOccurred(), out)
+		})
+	})
+
+	Context("with a security scheme requiring nonces", func() {
+		BeforeEach(func() {
+			key := &design.SecuritySchemeDefinition{SchemeName: "key", Kind: design.APIKeySecurityKind, In: "header", Name: "Authorization"}
+			design.Design = &design.APIDefinition{
+				Name:            "testapi",
+				SecuritySchemes: []*design.SecuritySchemeDefinition{key},
+				Metadata:        dslengine.MetadataDefinition{"client:nonce": {"key"}},
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name:     "show",
+								Routes:   []*design.RouteDefinition{{Verb: "GET", Path: ""}},
+								Security: &design.SecurityDefinition{Scheme: key},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			showAct := fooRes.Actions["show"]
+			showAct.Parent = fooRes
+			showAct.Routes[0].Parent = showAct
+		})
+
+		It("sets the nonce and timestamp headers before signing the requests", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			nonce := strings.Index(string(content), "nonce, err := goaclient.NewNonce()")
+			Ω(nonce).Should(BeNumerically(">", 0))
+			Ω(content).Should(ContainSubstring(`req.Header.Set("X-Nonce", nonce)`))
+			Ω(content).Should(ContainSubstring(`req.Header.Set("X-Timestamp", strconv.FormatInt(time.Now().Unix(), 10))`))
+			Ω(strings.Index(string(content), "if err := c.KeySigner.Sign(ctx, req); err != nil {")).Should(BeNumerically(">", nonce))
+		})
+
+		It("sends each request with a distinct nonce and never retries them", func() {
+			Ω(genErr).Should(BeNil())
+			out, err := runGeneratedTest(filepath.Join(outDir, "client"), nonceTest)
+			Ω(err).ShouldNot(HaveOccurred(), out)
+		})
+	})
+
+	Context("with an action requiring multiple security schemes", func() {
+		BeforeEach(func() {
+			key := &design.SecuritySchemeDefinition{SchemeName: "key", Kind: design.APIKeySecurityKind}
+			jwt := &design.SecuritySchemeDefinition{SchemeName: "jwt", Kind: design.JWTSecurityKind}
+			design.Design = &design.APIDefinition{
+				Name:            "testapi",
+				SecuritySchemes: []*design.SecuritySchemeDefinition{key, jwt},
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name:   "show",
+								Routes: []*design.RouteDefinition{{Verb: "GET", Path: ""}},
+								Security: &design.SecurityDefinition{
+									Scheme:     jwt,
+									Additional: []*design.SecurityDefinition{{Scheme: key}},
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			showAct := fooRes.Actions["show"]
+			showAct.Parent = fooRes
+			showAct.Routes[0].Parent = showAct
+		})
+
+		It("signs the requests with all the signers in order", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			jwt := strings.Index(string(content), "if err := c.JWTSigner.Sign(ctx, req); err != nil {")
+			Ω(jwt).Should(BeNumerically(">", 0))
+			Ω(strings.Index(string(content), "if err := c.KeySigner.Sign(ctx, req); err != nil {")).Should(BeNumerically(">", jwt))
+		})
+	})
+
+	Context("with an action with security configured", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			securitySchemeDef := &design.SecuritySchemeDefinition{
+				SchemeName: "jwt-1",
+				Kind:       design.JWTSecurityKind,
+			}
+			design.Design = &design.APIDefinition{
+				Name:        "testapi",
+				Title:       "dummy API with no resource",
+				Description: "I told you it's dummy",
+				SecuritySchemes: []*design.SecuritySchemeDefinition{
+					securitySchemeDef,
+				},
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name: "show",
+								QueryParams: &design.AttributeDefinition{
+									Type: design.Object{
+										"param": &design.AttributeDefinition{Type: design.Integer},
+										"time":  &design.AttributeDefinition{Type: design.DateTime},
+										"uuid":  &design.AttributeDefinition{Type: design.UUID},
+									},
+								},
+								Routes: []*design.RouteDefinition{
+									{
+										Verb: "GET",
+										Path: "",
+									},
+								},
+								Security: &design.SecurityDefinition{
+									Scheme: securitySchemeDef,
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			showAct := fooRes.Actions["show"]
+			showAct.Parent = fooRes
+			showAct.Routes[0].Parent = showAct
+		})
+
+		It("generates the correct client Fields", func() {
+			Ω(genErr).Should(BeNil())
+			Ω(files).Should(HaveLen(8))
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("JWT1Signer *goaclient.JWTSigner"))
+			Ω(content).Should(ContainSubstring("JWT1Signer: &goaclient.JWTSigner{},"))
+		})
+
+		It("generates a constructor accepting a custom Doer", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func NewWithDoer(doer goaclient.Doer, opts ...Option) *Client {"))
+			Ω(content).Should(ContainSubstring("return newClient(goaclient.NewWithDoer(doer), opts)"))
+			Ω(content).Should(ContainSubstring("return newClient(goaclient.New(c), opts)"))
+		})
+
+		It("generates a default User-Agent that can be overridden", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring(`c.UserAgent = "testapi-client/0"`))
+			Ω(content).Should(ContainSubstring("func WithUserAgent(userAgent string) Option {"))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring(`req.Header.Set("User-Agent", c.UserAgent)`))
+		})
+
+		It("generates retries that replay request bodies", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithRetries(n int) Option {"))
+			Ω(content).Should(ContainSubstring("func setReplayableBody(req *http.Request, body io.Reader) error {"))
+			Ω(content).Should(ContainSubstring("if seeker, ok := body.(io.ReadSeeker); ok {"))
+			Ω(content).Should(ContainSubstring("body, err := req.GetBody()"))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("resp, err := c.sendShared(tctx, req)"))
+		})
+
+		It("generates retries sharing a correlation ID", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring(`if retries > 0 && req.Header.Get(middleware.RequestIDHeader) == "" {`))
+			Ω(content).Should(ContainSubstring("req.Header.Set(middleware.RequestIDHeader, id)"))
+			Ω(content).Should(ContainSubstring(`req.Header.Set("X-Attempt", strconv.Itoa(attempt+1))`))
+		})
+
+		It("generates retries honoring the Retry-After header", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("case http.StatusTooManyRequests, http.StatusBadGateway"))
+			Ω(content).Should(ContainSubstring(`retryAfter := strings.TrimSpace(resp.Header.Get("Retry-After"))`))
+			Ω(content).Should(ContainSubstring("if t, err := http.ParseTime(retryAfter); err == nil {"))
+			Ω(content).Should(ContainSubstring("if expiresWithin(ctx, delay) || expiresWithin(req.Context(), delay) {"))
+			Ω(content).Should(ContainSubstring("case <-time.After(delay):"))
+		})
+
+		It("generates a client context that stops calls once done", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithContext(ctx context.Context) Option {"))
+			Ω(content).Should(ContainSubstring(`var ErrShutdown = errors.New("client is shut down")`))
+			Ω(content).Should(ContainSubstring("resp, err := c.sendWithRetries(ctx, req.WithContext(reqCtx))"))
+		})
+
+		It("generates a rate limiting option", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithRateLimit(r float64, b int) Option {"))
+			Ω(content).Should(ContainSubstring("c.limiter = goaclient.NewRateLimiter(r, b)"))
+			Ω(content).ShouldNot(ContainSubstring(`"golang.org/x/time/rate"`))
+			Ω(content).Should(ContainSubstring("if err := c.limiter.Wait(ctx); err != nil {"))
+		})
+
+		It("generates metrics hooks", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("ObserveRequest(action string, status int, dur time.Duration)"))
+			Ω(content).Should(ContainSubstring("func WithMetrics(m Metrics) Option {"))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("c.observe(MetricShowFoo, start, resp)"))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "metrics.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("// MetricShowFoo is the name of the show action of the foo resource.\n\tMetricShowFoo = \"foo.show\"\n"))
+		})
+
+		It("generates a tracing option", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("StartSpan(ctx context.Context, action string) Span"))
+			Ω(content).Should(ContainSubstring("func WithTracer(t Tracer) Option {"))
+			Ω(content).Should(ContainSubstring("span.Inject(req.Header)"))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("span := c.startSpan(ctx, MetricShowFoo, req)"))
+			Ω(content).Should(ContainSubstring("finishSpan(span, resp, err)"))
+		})
+
+		It("generates a dump option", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithDump(w io.Writer) Option {"))
+			Ω(content).Should(ContainSubstring("dump, err := httputil.DumpRequestOut(req, true)"))
+			Ω(content).Should(ContainSubstring("dump, err = httputil.DumpResponse(resp, true)"))
+		})
+
+		It("generates a trailing slash option", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithTrailingSlash(enabled bool) Option {"))
+			Ω(content).Should(ContainSubstring("func (c *Client) requestPath(ctx context.Context, path string) string {"))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("u := url.URL{Host: c.Host, Scheme: scheme, Path: c.requestPath(ctx, path)}"))
+		})
+
+		It("generates a dynamic path prefix hook", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(MatchRegexp(`PathPrefixFunc\s+func\(ctx context.Context\) string`))
+			Ω(content).Should(ContainSubstring("if c.PathPrefixFunc != nil {\n\t\tpath = strings.TrimRight(c.PathPrefixFunc(ctx), \"/\") + path\n\t}\n\tif c.basePath != \"\" {"))
+		})
+
+		It("generates an HTTP/2 option", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithHTTP2(h2c bool) Option {"))
+			Ω(content).Should(ContainSubstring("c.http2 = &h2c"))
+			Ω(content).Should(ContainSubstring("rt = goaclient.NewHTTP2Transport(c.tlsConfig, *c.http2, dial)"))
+			Ω(content).ShouldNot(ContainSubstring(`"golang.org/x/net/http2"`))
+			Ω(content).Should(ContainSubstring("hc.Transport = rt"))
+		})
+
+		It("generates an option limiting the size of the decoded responses", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithMaxResponseBytes(n int64) Option {"))
+			Ω(content).Should(ContainSubstring(`var ErrResponseTooLarge = errors.New("response body too large")`))
+			Ω(content).Should(ContainSubstring("return &limitedReader{r: io.LimitReader(body, c.maxBody+1), max: c.maxBody}"))
+		})
+
+		It("generates a unix domain socket option", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithUnixSocket(path string) Option {"))
+			Ω(content).Should(ContainSubstring("t.DialContext = c.dial"))
+			Ω(content).Should(ContainSubstring(`return d.DialContext(ctx, "unix", c.unixSocket)`))
+		})
+
+		It("generates a recording option", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithRecording() Option {"))
+			Ω(content).Should(ContainSubstring("func (c *Client) LastRequest() *http.Request {"))
+			Ω(content).Should(ContainSubstring("func (c *Client) LastResponse() *http.Response {"))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("c.record(req, resp)"))
+		})
+
+		It("generates a fixture directory option", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithFixtureDir(dir string) Option {"))
+			Ω(content).Should(ContainSubstring("f, err := goaclient.NewFixture(req)"))
+			Ω(content).Should(ContainSubstring(`file := filepath.Join(c.fixtures.dir, fmt.Sprintf("%s-%d.json", name, c.fixtures.count))`))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("if err := c.writeFixture(MetricShowFoo, req); err != nil {"))
+		})
+
+		It("generates a singleflight option", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithSingleflight() Option {"))
+			Ω(content).Should(ContainSubstring("c.flight = &goaclient.CallGroup{}"))
+			Ω(content).ShouldNot(ContainSubstring(`"golang.org/x/sync/singleflight"`))
+			Ω(content).Should(ContainSubstring("v, err, _ := c.flight.Do(c.cacheKey(req), func() (interface{}, error) {"))
+			Ω(content).Should(ContainSubstring("resp.Body = ioutil.NopCloser(bytes.NewReader(shared.body))"))
+		})
+
+		It("generates Encode and Decode helpers", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) Encode(v interface{}, w io.Writer, ct string) error {"))
+			Ω(content).Should(ContainSubstring("func (c *Client) Decode(v interface{}, r io.Reader, ct string) error {"))
+		})
+
+		It("generates a response cache option", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithCache(maxEntries int, ttl time.Duration) Option {"))
+			Ω(content).Should(ContainSubstring("func (c *Client) cachedResponse(req *http.Request) *http.Response {"))
+			Ω(content).Should(ContainSubstring(`if c.cache == nil || (req.Method != "GET" && req.Method != "HEAD") {`))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("if resp := c.cachedResponse(req); resp != nil {"))
+			Ω(content).Should(ContainSubstring("resp, err = c.cacheResponse(req, resp)"))
+		})
+
+		It("generates a Batch method", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithConcurrency(n int) Option {"))
+			Ω(content).Should(ContainSubstring("func (c *Client) Batch(ctx context.Context, reqs []*http.Request) []BatchResult {"))
+			Ω(strings.Count(string(content), "func (c *Client) Batch(")).Should(Equal(1))
+		})
+
+		It("generates a Clone method", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) Clone() *Client {"))
+			Ω(content).Should(ContainSubstring("clone.headers[name] = append([]string(nil), values...)"))
+		})
+
+		It("generates an option disabling redirects", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithoutRedirects() Option {"))
+			Ω(content).Should(ContainSubstring("return http.ErrUseLastResponse"))
+		})
+
+		It("generates a TLS configuration option", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithTLSConfig(cfg *tls.Config) Option {"))
+			Ω(content).Should(ContainSubstring("client.setupTransport()"))
+			Ω(content).Should(ContainSubstring("t.TLSClientConfig = c.tlsConfig"))
+		})
+
+		It("generates connection pool options", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func WithMaxIdleConns(n int) Option {"))
+			Ω(content).Should(ContainSubstring("func WithMaxConnsPerHost(n int) Option {"))
+			Ω(content).Should(ContainSubstring("t.MaxIdleConnsPerHost = c.maxIdleConns"))
+			Ω(content).Should(ContainSubstring("t.MaxConnsPerHost = c.maxConns"))
+		})
+
+		It("generates a method replacing the transport", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("func (c *Client) SetTransport(rt http.RoundTripper) {"))
+			Ω(content).Should(ContainSubstring("hc.Transport = rt"))
+		})
+
+		It("generates the Signer.Sign call from Action", func() {
+			Ω(genErr).Should(BeNil())
+			Ω(files).Should(HaveLen(8))
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("c.JWT1Signer.Sign(ctx, req)"))
+		})
+
+		It("only signs the requests accepted by the ShouldSign predicate", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(MatchRegexp(`ShouldSign +func\(\*http.Request\) bool`))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("if c.ShouldSign == nil || c.ShouldSign(req) {\n\t\t_, hasToken := goaclient.ContextToken(ctx)\n\t\tauthorized := req.Header.Get(\"Authorization\") != \"\"\n\t\tif !c.JWT1Signer.Configured() && !hasToken {"))
+		})
+
+		It("signs the requests with the token set in the context if any", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("_, hasToken := goaclient.ContextToken(ctx)"))
+			Ω(content).Should(ContainSubstring("if !c.JWT1Signer.Configured() && !hasToken {"))
+		})
+
+		It("fails to build the requests if the signer is not configured", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring(`return nil, fmt.Errorf("cannot sign the show foo request: the JWT1Signer of the client is not configured")`))
+			Ω(content).Should(ContainSubstring("if err := c.JWT1Signer.Sign(ctx, req); err != nil {\n\t\t\treturn nil, err\n\t\t}"))
+		})
+
+		It("sends the requests with the Authorization header set with the client options", func() {
+			Ω(genErr).Should(BeNil())
+			out, err := runGeneratedTest(filepath.Join(outDir, "client"), authorizationTest)
+			Ω(err).ShouldNot(HaveOccurred(), out)
+		})
+	})
+
+	Context("running the generated client", func() {
+		BeforeEach(func() {
+			keyScheme := &design.SecuritySchemeDefinition{
+				SchemeName: "key",
+				Kind:       design.APIKeySecurityKind,
+				In:         "header",
+				Name:       "X-Key",
+			}
+			design.Design = &design.APIDefinition{
+				Name:            "testapi",
+				SecuritySchemes: []*design.SecuritySchemeDefinition{keyScheme},
+				Resources: map[string]*design.ResourceDefinition{
+					"bottle": {
+						Name: "bottle",
+						Actions: map[string]*design.ActionDefinition{
+							"list": {
+								Name:        "list",
+								Routes:      []*design.RouteDefinition{{Verb: "GET", Path: "/bottles"}},
+								QueryParams: &design.AttributeDefinition{Type: design.Object{}},
+								Security:    &design.SecurityDefinition{Scheme: keyScheme},
+							},
+							"delete": {
+								Name:   "delete",
+								Routes: []*design.RouteDefinition{{Verb: "DELETE", Path: "/bottles/:id"}},
+								Params: &design.AttributeDefinition{
+									Type: design.Object{
+										"id": &design.AttributeDefinition{Type: design.Integer},
+									},
+								},
+								QueryParams: &design.AttributeDefinition{Type: design.Object{}},
+							},
+							"show": {
+								Name:   "show",
+								Routes: []*design.RouteDefinition{{Verb: "GET", Path: "/bottles/:id"}},
+								Params: &design.AttributeDefinition{
+									Type: design.Object{
+										"id": &design.AttributeDefinition{Type: design.Integer},
+									},
+								},
+								QueryParams: &design.AttributeDefinition{Type: design.Object{}},
+							},
+							"update": {
+								Name:   "update",
+								Routes: []*design.RouteDefinition{{Verb: "PUT", Path: "/bottles/:id"}},
+								Params: &design.AttributeDefinition{
+									Type: design.Object{
+										"id": &design.AttributeDefinition{Type: design.Integer},
+									},
+								},
+								QueryParams: &design.AttributeDefinition{Type: design.Object{}},
+								Payload: &design.UserTypeDefinition{
+									AttributeDefinition: &design.AttributeDefinition{
+										Type: design.Object{
+											"name": &design.AttributeDefinition{Type: design.String},
+										},
+									},
+									TypeName: "UpdateBottlePayload",
+								},
+							},
+						},
+					},
+				},
+			}
+			bottleRes := design.Design.Resources["bottle"]
+			for _, act := range bottleRes.Actions {
+				act.Parent = bottleRes
+				act.Routes[0].Parent = act
+			}
+		})
+
+		It("returns the errors writing fixtures when no timeout is set", func() {
+			Ω(genErr).Should(BeNil())
+			out, err := runGeneratedTest(filepath.Join(outDir, "client"), fixtureErrorTest)
+			Ω(err).ShouldNot(HaveOccurred(), out)
+		})
+
+		Context("with a response cache", func() {
+			It("shares the cached responses between the callers with the same credentials", func() {
+				Ω(genErr).Should(BeNil())
+				out, err := runGeneratedTest(filepath.Join(outDir, "client"), cacheTest)
+				Ω(err).ShouldNot(HaveOccurred(), out)
+			})
+		})
+
+		Context("with the singleflight option", func() {
+			It("sends identical concurrent requests once", func() {
+				Ω(genErr).Should(BeNil())
+				out, err := runGeneratedTest(filepath.Join(outDir, "client"), singleflightTest)
+				Ω(err).ShouldNot(HaveOccurred(), out)
+			})
+		})
+
+		Context("with a streaming threshold", func() {
+			It("streams the large payloads and buffers the small ones", func() {
+				Ω(genErr).Should(BeNil())
+				out, err := runGeneratedTest(filepath.Join(outDir, "client"), streamingThresholdTest)
+				Ω(err).ShouldNot(HaveOccurred(), out)
+			})
+		})
+
+		Context("with a rate limit", func() {
+			It("delays the requests exceeding the limit", func() {
+				Ω(genErr).Should(BeNil())
+				out, err := runGeneratedTest(filepath.Join(outDir, "client"), rateLimitTest)
+				Ω(err).ShouldNot(HaveOccurred(), out)
+			})
+		})
+
+		Context("with a unix domain socket", func() {
+			It("sends the requests over the socket connections", func() {
+				Ω(genErr).Should(BeNil())
+				out, err := runGeneratedTest(filepath.Join(outDir, "client"), unixSocketTest)
+				Ω(err).ShouldNot(HaveOccurred(), out)
+			})
+		})
+
+		Context("with a custom transport", func() {
+			It("sends the requests with the transport", func() {
+				Ω(genErr).Should(BeNil())
+				out, err := runGeneratedTest(filepath.Join(outDir, "client"), setTransportTest)
+				Ω(err).ShouldNot(HaveOccurred(), out)
+			})
+		})
+
+		Context("without redirects", func() {
+			It("returns the redirect responses", func() {
+				Ω(genErr).Should(BeNil())
+				out, err := runGeneratedTest(filepath.Join(outDir, "client"), withoutRedirectsTest)
+				Ω(err).ShouldNot(HaveOccurred(), out)
+			})
+		})
+
+		Context("following redirects", func() {
+			It("removes the credentials from the requests redirected to another host", func() {
+				Ω(genErr).Should(BeNil())
+				out, err := runGeneratedTest(filepath.Join(outDir, "client"), redirectAuthTest)
+				Ω(err).ShouldNot(HaveOccurred(), out)
+			})
+		})
+
+		Context("with a clone", func() {
+			It("leaves the original client unchanged when configuring the clone", func() {
+				Ω(genErr).Should(BeNil())
+				out, err := runGeneratedTest(filepath.Join(outDir, "client"), cloneTest)
+				Ω(err).ShouldNot(HaveOccurred(), out)
+			})
+		})
+
+		Context("with retries and a Retry-After response header", func() {
+			It("waits for the given duration before retrying", func() {
+				Ω(genErr).Should(BeNil())
+				out, err := runGeneratedTest(filepath.Join(outDir, "client"), retryAfterTest)
+				Ω(err).ShouldNot(HaveOccurred(), out)
+			})
+		})
+
+		Context("with retries", func() {
+			It("sends all the attempts with the same correlation ID", func() {
+				Ω(genErr).Should(BeNil())
+				out, err := runGeneratedTest(filepath.Join(outDir, "client"), correlationIDTest)
+				Ω(err).ShouldNot(HaveOccurred(), out)
+			})
+		})
+
+		Context("with a dump writer", func() {
+			It("writes the requests and responses and keeps the response body readable", func() {
+				Ω(genErr).Should(BeNil())
+				out, err := runGeneratedTest(filepath.Join(outDir, "client"), dumpTest)
+				Ω(err).ShouldNot(HaveOccurred(), out)
+			})
+		})
+
+		Context("sending a batch of requests", func() {
+			It("returns the results in order with the errors of each request", func() {
+				Ω(genErr).Should(BeNil())
+				out, err := runGeneratedTest(filepath.Join(outDir, "client"), batchTest)
+				Ω(err).ShouldNot(HaveOccurred(), out)
+			})
+		})
+
+		Context("with HTTP/2", func() {
+			It("installs a HTTP/2 transport", func() {
+				Ω(genErr).Should(BeNil())
+				out, err := runGeneratedTest(filepath.Join(outDir, "client"), http2TransportTest)
+				Ω(err).ShouldNot(HaveOccurred(), out)
+			})
+		})
+
+		Context("with a TLS configuration", func() {
+			It("installs a transport using the configuration", func() {
+				Ω(genErr).Should(BeNil())
+				out, err := runGeneratedTest(filepath.Join(outDir, "client"), tlsConfigTransportTest)
+				Ω(err).ShouldNot(HaveOccurred(), out)
+			})
+		})
+
+		Context("with transport pool options", func() {
+			It("installs a transport with the pool limits", func() {
+				Ω(genErr).Should(BeNil())
+				out, err := runGeneratedTest(filepath.Join(outDir, "client"), poolTransportTest)
+				Ω(err).ShouldNot(HaveOccurred(), out)
+			})
+		})
+	})
+})
+
+const parsePathTest = `package client
+
+import "testing"
+
+func TestParseShowFooPath(t *testing.T) {
+	path, ok := ParseShowFooPath("/files/a/b%20c")
+	if !ok || path != "a/b c" {
+		t.Errorf("got %q, %v", path, ok)
+	}
+	if _, ok := ParseShowFooPath("/other"); ok {
+		t.Error("unexpected match")
+	}
+}
+`
+
+const orderedQueryTest = `package client
+
+import (
+	"testing"
+
+	"github.com/goadesign/goa"
+	"golang.org/x/net/context"
+)
+
+func TestNewListFooRequest(t *testing.T) {
+	c := New(nil)
+	c.Encoder.Register(goa.NewJSONEncoder, "*/*")
+	after, limit := "a b", 3
+	query := ListFooQuery{Ids: []int{1}, Zoo: "z", After: &after, Limit: &limit}
+	req, err := c.NewListFooRequest(context.Background(), "/foo", &ListFooPayload{}, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := req.URL.RawQuery; q != "ids=1&zoo=z&after=a+b&limit=3" {
+		t.Errorf("got query %q", q)
+	}
+}
+`
+
+const fixtureErrorTest = `package client
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestFixtureError(t *testing.T) {
+	c := New(nil, WithFixtureDir("/nonexistent"))
+	if _, err := c.DeleteBottle(context.Background(), DeleteBottlePath(1)); err == nil {
+		t.Error("expected an error writing the fixture")
+	}
+}
+`
+
+const authorizationTest = `package client
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestAuthorization(t *testing.T) {
+	ctx := context.Background()
+	if _, err := New(nil).NewShowFooRequest(ctx, ShowFooPath(), nil, nil, nil); err == nil {
+		t.Error("expected an error with no signer configured")
+	}
+	cases := []struct {
+		client *Client
+		header string
+	}{
+		{New(nil, WithBearerToken("tok")), "Bearer tok"},
+		{New(nil, WithDefaultHeader("Authorization", "Custom tok")), "Custom tok"},
+	}
+	for _, c := range cases {
+		req, err := c.client.NewShowFooRequest(ctx, ShowFooPath(), nil, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := req.Header.Get("Authorization"); got != c.header {
+			t.Errorf("got Authorization %q, expected %q", got, c.header)
+		}
+	}
+	c := New(nil, WithBearerToken("tok"))
+	c.JWT1Signer.Token = "jwt"
+	req, err := c.NewShowFooRequest(ctx, ShowFooPath(), nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer jwt" {
+		t.Errorf("got Authorization %q, expected the signer token", got)
+	}
+}
+`
+
+const singleflightTest = `package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// slowTransport counts the requests it is given and responds after a delay.
+type slowTransport struct {
+	hits int32
+}
+
+func (t *slowTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.hits, 1)
+	time.Sleep(100 * time.Millisecond)
+	return &http.Response{
+		StatusCode: 200,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(strings.NewReader("body")),
+		Request:    req,
+	}, nil
+}
+
+func TestSingleflight(t *testing.T) {
+	transport := &slowTransport{}
+	c := New(&http.Client{Transport: transport}, WithSingleflight())
+	const n = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := c.ShowBottle(context.Background(), ShowBottlePath(1))
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer resp.Body.Close()
+			if b, err := ioutil.ReadAll(resp.Body); err != nil || string(b) != "body" {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("unexpected response: %v", err)
+	}
+	if hits := atomic.LoadInt32(&transport.hits); hits != 1 {
+		t.Errorf("got %d requests, expected 1", hits)
+	}
+}
+`
+
+const streamingThresholdTest = `package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/goadesign/goa"
+	"golang.org/x/net/context"
+)
+
+// sentRequest describes a request received by flakyTransport.
+type sentRequest struct {
+	contentLength int64
+	body          string
+}
+
+// flakyTransport records the requests it is given and responds with a 503 status to all the
+// requests but the second attempts.
+type flakyTransport struct {
+	requests []sentRequest
+}
+
+func (t *flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	t.requests = append(t.requests, sentRequest{req.ContentLength, string(b)})
+	status := 503
+	if req.Header.Get("X-Attempt") == "2" {
+		status = 200
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Retry-After": {"0"}},
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}, nil
+}
+
+func TestStreamingThreshold(t *testing.T) {
+	cases := []struct {
+		name     string
+		attempts int
+		chunked  bool
+	}{
+		{"small", 2, false},
+		{strings.Repeat("large", 100), 1, true},
+	}
+	for _, tc := range cases {
+		transport := &flakyTransport{}
+		c := New(&http.Client{Transport: transport}, WithStreamingThreshold(64), WithRetries(1))
+		c.Encoder.Register(goa.NewJSONEncoder, "*/*")
+		name := tc.name
+		resp, err := c.UpdateBottle(context.Background(), UpdateBottlePath(1), &UpdateBottlePayload{Name: &name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if len(transport.requests) != tc.attempts {
+			t.Fatalf("%d bytes payload: got %d attempts, expected %d", len(name), len(transport.requests), tc.attempts)
+		}
+		for _, sent := range transport.requests {
+			if !strings.Contains(sent.body, name) {
+				t.Errorf("%d bytes payload: got body %q", len(name), sent.body)
+			}
+			if chunked := sent.contentLength == -1; chunked != tc.chunked {
+				t.Errorf("%d bytes payload: got content length %d", len(name), sent.contentLength)
+			}
+			if !tc.chunked && sent.contentLength != int64(len(sent.body)) {
+				t.Errorf("%d bytes payload: got content length %d for %d bytes", len(name), sent.contentLength, len(sent.body))
+			}
+		}
+	}
+}
+`
+
+const rateLimitTest = `package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// okTransport responds to all the requests with a 200 status.
+type okTransport struct{}
+
+func (okTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: 200,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}, nil
+}
+
+func TestRateLimit(t *testing.T) {
+	c := New(&http.Client{Transport: okTransport{}}, WithRateLimit(5, 1))
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		resp, err := c.DeleteBottle(context.Background(), DeleteBottlePath(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("second request sent after %s, expected it to be delayed", elapsed)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.DeleteBottle(ctx, DeleteBottlePath(1)); err == nil {
+		t.Error("expected an error when the context is done before the limiter allows the request")
+	}
+}
+`
+
+const routeParamTest = `package client
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestRouteParam(t *testing.T) {
+	route := "r"
+	req, err := New(nil).NewShowFooRequest2(context.Background(), "/1", &route)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Method != "HEAD" || req.URL.RawQuery != "route=r" {
+		t.Errorf("got %s %s", req.Method, req.URL)
+	}
+}
+`
+
+const timeoutRateLimitTest = `package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// okTransport responds to all the requests with a 200 status.
+type okTransport struct{}
+
+func (okTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: 200,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}, nil
+}
+
+func TestTimeoutRateLimit(t *testing.T) {
+	c := New(&http.Client{Transport: okTransport{}}, WithRateLimit(1.0/3600, 1))
+	resp, err := c.ShowFoo(context.Background(), ShowFooPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	done := make(chan error)
+	go func() {
+		_, err := c.ShowFoo(context.Background(), ShowFooPath())
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected an error as the limiter delay exceeds the action timeout")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the call waits on the rate limiter past the action timeout")
+	}
+}
+`
+
+const unixSocketTest = `package client
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// listenUnix returns a listener on a unix domain socket in a temporary directory.
+func listenUnix(t *testing.T) (net.Listener, string) {
+	dir, err := ioutil.TempDir("", "socket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "api.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return l, path
+}
+
+// protoHandler responds with the protocol of the request.
+var protoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(r.Proto))
+})
+
+func deleteProto(t *testing.T, c *Client) string {
+	resp, err := c.DeleteBottle(context.Background(), DeleteBottlePath(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestUnixSocket(t *testing.T) {
+	l, path := listenUnix(t)
+	defer os.RemoveAll(filepath.Dir(path))
+	go http.Serve(l, protoHandler)
+	defer l.Close()
+	if proto := deleteProto(t, New(nil, WithUnixSocket(path))); proto != "HTTP/1.1" {
+		t.Errorf("got %s", proto)
+	}
+}
+
+func TestUnixSocketH2C(t *testing.T) {
+	l, path := listenUnix(t)
+	defer os.RemoveAll(filepath.Dir(path))
+	go http.Serve(l, h2c.NewHandler(protoHandler, &http2.Server{}))
+	defer l.Close()
+	if proto := deleteProto(t, New(nil, WithUnixSocket(path), WithHTTP2(true))); proto != "HTTP/2.0" {
+		t.Errorf("got %s", proto)
+	}
+}
+
+func TestUnixSocketTLS(t *testing.T) {
+	l, path := listenUnix(t)
+	defer os.RemoveAll(filepath.Dir(path))
+	srv := httptest.NewUnstartedServer(protoHandler)
+	srv.Listener.Close()
+	srv.Listener = l
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	c := New(nil, WithUnixSocket(path), WithHTTP2(false), WithTLSConfig(&tls.Config{InsecureSkipVerify: true}))
+	c.Scheme = "https"
+	if proto := deleteProto(t, c); proto != "HTTP/2.0" {
+		t.Errorf("got %s", proto)
+	}
+}
+`
+
+const cacheTest = `package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	goaclient "github.com/goadesign/goa/client"
+	"golang.org/x/net/context"
+)
+
+// keyTransport counts the requests it is given and responds with the value of their X-Key header.
+type keyTransport struct {
+	hits int32
+}
+
+func (t *keyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.hits, 1)
+	return &http.Response{
+		StatusCode: 200,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(strings.NewReader(req.Header.Get("X-Key"))),
+		Request:    req,
+	}, nil
+}
+
+func listBody(t *testing.T, c *Client, ctx context.Context) string {
+	resp, err := c.ListBottle(ctx, ListBottlePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestCache(t *testing.T) {
+	transport := &keyTransport{}
+	c := New(&http.Client{Transport: transport}, WithCache(10, time.Minute))
+	c.KeySigner.Header = "X-Key"
+	c.KeySigner.Format = "%s"
+	c.KeySigner.Key = "tenant1"
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if body := listBody(t, c, ctx); body != "tenant1" {
+			t.Errorf("got %q", body)
+		}
+	}
+	if hits := atomic.LoadInt32(&transport.hits); hits != 1 {
+		t.Errorf("got %d requests, expected 1", hits)
+	}
+	if body := listBody(t, c, goaclient.WithToken(ctx, "tenant2")); body != "tenant2" {
+		t.Errorf("got the response to the tenant1 request %q", body)
+	}
+	if hits := atomic.LoadInt32(&transport.hits); hits != 2 {
+		t.Errorf("got %d requests, expected 2", hits)
+	}
+}
+`
+
+const setTransportTest = `package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+// cannedTransport responds to all the requests with the same body and records the last request.
+type cannedTransport struct {
+	req *http.Request
+}
+
+func (t *cannedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.req = req
+	return &http.Response{
+		StatusCode: 200,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(strings.NewReader("canned")),
+		Request:    req,
+	}, nil
+}
+
+func TestSetTransport(t *testing.T) {
+	c := New(&http.Client{})
+	clone := c.Clone()
+	transport := &cannedTransport{}
+	c.SetTransport(transport)
+	resp, err := c.ShowBottle(context.Background(), ShowBottlePath(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "canned" {
+		t.Errorf("got body %q", body)
+	}
+	if transport.req == nil || transport.req.URL.Path != "/bottles/1" {
+		t.Errorf("the transport did not receive the request, got %v", transport.req)
+	}
+	if clone.Client.Client.Transport != nil {
+		t.Errorf("the transport of the clone changed")
+	}
+}
+`
+
+const withoutRedirectsTest = `package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestWithoutRedirects(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.URL.Path == "/bottles/1" {
+			http.Redirect(w, r, "/bottles/2", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	c := New(&http.Client{}, WithoutRedirects())
+	c.Host = u.Host
+	resp, err := c.ShowBottle(context.Background(), ShowBottlePath(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Errorf("got status %d, expected 302", resp.StatusCode)
+	}
+	if loc := resp.Header.Get("Location"); loc != "/bottles/2" {
+		t.Errorf("got Location %q", loc)
+	}
+	if hits != 1 {
+		t.Errorf("got %d requests, expected 1", hits)
+	}
+}
+`
+
+const redirectAuthTest = `package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestRedirectAuth(t *testing.T) {
+	var other http.Header
+	otherSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		other = r.Header
+	}))
+	defer otherSrv.Close()
+	var same http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bottles/1":
+			http.Redirect(w, r, "/bottles/2", http.StatusFound)
+		case "/bottles/2":
+			same = r.Header
+		default:
+			http.Redirect(w, r, otherSrv.URL+"/bottles/3", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+
+	c := New(&http.Client{}, WithBearerToken("secret"))
+	c.Host = u.Host
+	c.KeySigner.Header = "X-Key"
+	c.KeySigner.Format = "%s"
+	c.KeySigner.Key = "key"
+	ctx := context.Background()
+
+	resp, err := c.ShowBottle(ctx, ShowBottlePath(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := same.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("got Authorization %q on the same host, expected it to be kept", got)
+	}
+
+	resp, err = c.ListBottle(ctx, ListBottlePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if other == nil {
+		t.Fatal("the redirect to the other host was not followed")
+	}
+	if got := other.Get("Authorization"); got != "" {
+		t.Errorf("got Authorization %q on the other host, expected it to be removed", got)
+	}
+	if got := other.Get("X-Key"); got != "" {
+		t.Errorf("got X-Key %q on the other host, expected it to be removed", got)
+	}
+
+	other = nil
+	c = New(&http.Client{}, WithBearerToken("secret"), WithRedirectAuth())
+	c.Host = u.Host
+	resp, err = c.ListBottle(ctx, ListBottlePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := other.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("got Authorization %q on the other host with WithRedirectAuth", got)
+	}
+}
+`
+
+const cloneTest = `package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestClone(t *testing.T) {
+	var versions []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		versions = append(versions, r.Header.Get("X-Version"))
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	c := New(&http.Client{}, WithDefaultHeader("X-Version", "1"))
+	c.Host = u.Host
+	clone := c.Clone()
+	WithDefaultHeader("X-Version", "2")(clone)
+	WithDefaultHeader("X-Clone", "true")(clone)
+	ctx := context.Background()
+	for _, client := range []*Client{clone, c} {
+		resp, err := client.ShowBottle(ctx, ShowBottlePath(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if len(versions) != 2 || versions[0] != "2" || versions[1] != "1" {
+		t.Errorf("got X-Version headers %v, expected [2 1]", versions)
+	}
+	if c.headers.Get("X-Clone") != "" {
+		t.Errorf("the default headers of the original client changed: %v", c.headers)
+	}
+}
+`
+
+const awsSigV4Test = `package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+// headerTransport records the headers of the last request it is given.
+type headerTransport struct {
+	header http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.header = req.Header
+	return &http.Response{
+		StatusCode: 200,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}, nil
+}
+
+var authorization = regexp.MustCompile(` + "`" + `^AWS4-HMAC-SHA256 Credential=AKID/\d{8}/eu-west-1/execute-api/aws4_request, ` + "`" + ` +
+	` + "`" + `SignedHeaders=host;x-amz-date, Signature=[0-9a-f]{64}$` + "`" + `)
+
+func TestAWSSigV4(t *testing.T) {
+	transport := &headerTransport{}
+	c := New(&http.Client{Transport: transport}, WithIamCredentials("AKID", "secret", "eu-west-1"))
+	resp, err := c.ShowFoo(context.Background(), ShowFooPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := transport.header.Get("Authorization"); !authorization.MatchString(got) {
+		t.Errorf("got Authorization %q", got)
+	}
+	if transport.header.Get("X-Amz-Date") == "" {
+		t.Error("missing X-Amz-Date header")
+	}
+}
+`
+
+const nonceTest = `package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+// unavailableTransport responds to all the requests with 503 and records their nonces.
+type unavailableTransport struct {
+	nonces []string
+}
+
+func (t *unavailableTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.nonces = append(t.nonces, req.Header.Get("X-Nonce"))
+	return &http.Response{
+		StatusCode: 503,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}, nil
+}
+
+func TestNonce(t *testing.T) {
+	transport := &unavailableTransport{}
+	c := New(&http.Client{Transport: transport}, WithRetries(2))
+	c.KeySigner.Header = "Authorization"
+	c.KeySigner.Key = "key"
+	for i := 0; i < 2; i++ {
+		resp, err := c.ShowFoo(context.Background(), ShowFooPath())
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if len(transport.nonces) != 2 {
+		t.Fatalf("got %d requests, expected 2 as the requests carrying a nonce are not retried", len(transport.nonces))
+	}
+	if transport.nonces[0] == "" || transport.nonces[0] == transport.nonces[1] {
+		t.Errorf("got nonces %q, expected distinct nonces", transport.nonces)
+	}
+}
+`
+
+const retryAfterTest = `package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// throttlingTransport responds to the first request with 429 and a Retry-After header of 1
+// second and to the following ones with 200, recording the time of each request.
+type throttlingTransport struct {
+	times []time.Time
+}
+
+func (t *throttlingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.times = append(t.times, time.Now())
+	resp := &http.Response{
+		StatusCode: 200,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}
+	if len(t.times) == 1 {
+		resp.StatusCode = 429
+		resp.Header.Set("Retry-After", "1")
+	}
+	return resp, nil
+}
+
+func TestRetryAfter(t *testing.T) {
+	transport := &throttlingTransport{}
+	c := New(&http.Client{Transport: transport}, WithRetries(1))
+	resp, err := c.ShowBottle(context.Background(), ShowBottlePath(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Errorf("got status %d, expected the response to the retry", resp.StatusCode)
+	}
+	if len(transport.times) != 2 {
+		t.Fatalf("got %d requests, expected 2", len(transport.times))
+	}
+	if wait := transport.times[1].Sub(transport.times[0]); wait < time.Second || wait > 2*time.Second {
+		t.Errorf("retried after %s, expected 1s", wait)
+	}
+}
+`
+
+const correlationIDTest = `package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+// flakyTransport responds to the first request with 503 and to the following ones with 200,
+// recording the headers of each request.
+type flakyTransport struct {
+	headers []http.Header
+}
+
+func (t *flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.headers = append(t.headers, cloneHeader(req.Header))
+	status := 200
+	if len(t.headers) == 1 {
+		status = 503
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}, nil
+}
+
+func TestCorrelationID(t *testing.T) {
+	transport := &flakyTransport{}
+	c := New(&http.Client{Transport: transport}, WithRetries(1))
+	resp, err := c.ShowBottle(context.Background(), ShowBottlePath(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(transport.headers) != 2 {
+		t.Fatalf("got %d requests, expected 2", len(transport.headers))
+	}
+	first, second := transport.headers[0], transport.headers[1]
+	if id := first.Get("X-Request-Id"); id == "" || second.Get("X-Request-Id") != id {
+		t.Errorf("got X-Request-Id %q then %q, expected the same ID", id, second.Get("X-Request-Id"))
+	}
+	if first.Get("X-Attempt") != "1" || second.Get("X-Attempt") != "2" {
+		t.Errorf("got X-Attempt %q then %q, expected 1 then 2", first.Get("X-Attempt"), second.Get("X-Attempt"))
+	}
+}
+`
+
+const strictDecodingTest = `package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func bottleResponse() *http.Response {
+	return &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(` + "`" + `{"id":1,"unknown":true}` + "`" + `)),
+	}
+}
+
+func TestStrictDecoding(t *testing.T) {
+	bottle, err := New(nil, WithNumberDecoding()).DecodeBottle(bottleResponse())
+	if err != nil {
+		t.Fatalf("got error %s, expected the unknown field to be ignored", err)
+	}
+	if bottle.ID == nil || *bottle.ID != 1 {
+		t.Errorf("got %+v", bottle)
+	}
+	_, err = New(nil, WithStrictDecoding()).DecodeBottle(bottleResponse())
+	if err == nil || !strings.Contains(err.Error(), "unknown") {
+		t.Errorf("got error %v, expected the unknown field to be rejected", err)
+	}
+}
+`
+
+const dumpTest = `package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestDump(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{\"name\":\"dumped\"}"))
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	var dump bytes.Buffer
+	c := New(&http.Client{}, WithDump(&dump))
+	c.Host = u.Host
+	resp, err := c.ShowBottle(context.Background(), ShowBottlePath(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	out := dump.String()
+	if !strings.Contains(out, "GET /bottles/1 HTTP/1.1") {
+		t.Errorf("the dump does not contain the request:\n%s", out)
+	}
+	if !strings.Contains(out, "HTTP/1.1 200 OK") || !strings.Contains(out, "{\"name\":\"dumped\"}") {
+		t.Errorf("the dump does not contain the response:\n%s", out)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload UpdateBottlePayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Name == nil || *payload.Name != "dumped" {
+		t.Errorf("got body %q", body)
+	}
+}
+`
+
+const batchTest = `package client
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// batchTransport responds to the requests with their path as body after a delay decreasing with
+// their position so that they complete out of order. It fails the requests to /bottles/2 and
+// records the maximum number of concurrent requests.
+type batchTransport struct {
+	sync.Mutex
+	active, max int
+}
+
+func (t *batchTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.Lock()
+	t.active++
+	if t.active > t.max {
+		t.max = t.active
+	}
+	t.Unlock()
+	defer func() {
+		t.Lock()
+		t.active--
+		t.Unlock()
+	}()
+	delay := map[string]time.Duration{"/bottles/1": 100, "/bottles/2": 50, "/bottles/3": 10}[req.URL.Path]
+	time.Sleep(delay * time.Millisecond)
+	if req.URL.Path == "/bottles/2" {
+		return nil, errors.New("unavailable")
+	}
+	return &http.Response{
+		StatusCode: 200,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(strings.NewReader(req.URL.Path)),
+		Request:    req,
+	}, nil
+}
+
+func TestBatch(t *testing.T) {
+	transport := &batchTransport{}
+	c := New(&http.Client{Transport: transport}, WithConcurrency(2))
+	ctx := context.Background()
+	var reqs []*http.Request
+	for id := 1; id <= 3; id++ {
+		req, err := c.NewShowBottleRequest(ctx, ShowBottlePath(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		reqs = append(reqs, req)
+	}
+	results := c.Batch(ctx, reqs)
+	if len(results) != 3 {
+		t.Fatalf("got %d results, expected 3", len(results))
+	}
+	for i, path := range []string{"/bottles/1", "", "/bottles/3"} {
+		res := results[i]
+		if path == "" {
+			if res.Err == nil || !strings.Contains(res.Err.Error(), "unavailable") {
+				t.Errorf("got error %v for request %d, expected the transport error", res.Err, i)
+			}
+			continue
+		}
+		if res.Err != nil {
+			t.Fatalf("request %d failed: %s", i, res.Err)
+		}
+		body, _ := ioutil.ReadAll(res.Response.Body)
+		res.Response.Body.Close()
+		if string(body) != path {
+			t.Errorf("got response %q for request %d, expected the response to %s", body, i, path)
+		}
+	}
+	if transport.max != 2 {
+		t.Errorf("got at most %d concurrent requests, expected 2", transport.max)
+	}
+}
+`
+
+const acceptGzipTest = `package client
+
+import (
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestAcceptGzip(t *testing.T) {
+	var accept string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte("{\"id\":7}"))
+		gz.Close()
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	c := New(&http.Client{}, WithAcceptGzip(), WithNumberDecoding())
+	c.Host = u.Host
+	resp, err := c.ShowBottle(context.Background(), ShowBottlePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if accept != "gzip" {
+		t.Errorf("got Accept-Encoding %q, expected gzip", accept)
+	}
+	bottle, err := c.DecodeBottle(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bottle.ID == nil || *bottle.ID != 7 {
+		t.Errorf("got %+v", bottle)
+	}
+}
+`
+
+const http2TransportTest = `package client
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+func TestHTTP2Transport(t *testing.T) {
+	for _, h2c := range []bool{false, true} {
+		hc := &http.Client{Timeout: time.Second}
+		c := New(hc, WithHTTP2(h2c))
+		rt, ok := c.Client.Client.Transport.(*http2.Transport)
+		if !ok {
+			t.Fatalf("got transport %T with h2c %v, expected *http2.Transport", c.Client.Client.Transport, h2c)
+		}
+		if rt.AllowHTTP != h2c {
+			t.Errorf("got AllowHTTP %v, expected %v", rt.AllowHTTP, h2c)
+		}
+		if c.Client.Client.Timeout != time.Second {
+			t.Errorf("the http client timeout was not preserved")
+		}
+		if hc.Transport != nil {
+			t.Errorf("the transport of the given http client changed")
+		}
+	}
+}
+`
+
+const tlsConfigTransportTest = `package client
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+
+	"golang.org/x/net/http2"
+)
+
+func TestTLSConfigTransport(t *testing.T) {
+	cfg := &tls.Config{ServerName: "api.example.com"}
+	c := New(&http.Client{}, WithTLSConfig(cfg))
+	rt, ok := c.Client.Client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("got transport %T, expected *http.Transport", c.Client.Client.Transport)
+	}
+	if rt.TLSClientConfig != cfg {
+		t.Errorf("the transport does not use the given TLS configuration")
+	}
+	if http.DefaultTransport.(*http.Transport).TLSClientConfig == cfg {
+		t.Errorf("the default transport was modified")
+	}
+
+	c = New(&http.Client{}, WithTLSConfig(cfg), WithHTTP2(false))
+	h2, ok := c.Client.Client.Transport.(*http2.Transport)
+	if !ok {
+		t.Fatalf("got transport %T with WithHTTP2, expected *http2.Transport", c.Client.Client.Transport)
+	}
+	if h2.TLSClientConfig != cfg {
+		t.Errorf("the HTTP/2 transport does not use the given TLS configuration")
+	}
+}
+`
+
+const poolTransportTest = `package client
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+
+	"golang.org/x/net/http2"
+)
+
+func TestPoolTransport(t *testing.T) {
+	base := &http.Transport{DisableCompression: true}
+	c := New(&http.Client{Transport: base}, WithMaxIdleConns(20), WithMaxConnsPerHost(5))
+	rt, ok := c.Client.Client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("got transport %T, expected *http.Transport", c.Client.Client.Transport)
+	}
+	if rt == base {
+		t.Fatal("the given transport was modified instead of being copied")
+	}
+	if rt.MaxIdleConns != 20 || rt.MaxIdleConnsPerHost != 20 || rt.MaxConnsPerHost != 5 {
+		t.Errorf("got MaxIdleConns %d, MaxIdleConnsPerHost %d and MaxConnsPerHost %d",
+			rt.MaxIdleConns, rt.MaxIdleConnsPerHost, rt.MaxConnsPerHost)
+	}
+	if !rt.DisableCompression {
+		t.Error("the settings of the given transport were not preserved")
+	}
+	if base.MaxIdleConns != 0 || base.MaxConnsPerHost != 0 {
+		t.Error("the given transport was modified")
+	}
+
+	cfg := &tls.Config{ServerName: "api.example.com"}
+	c = New(&http.Client{}, WithMaxConnsPerHost(5), WithTLSConfig(cfg))
+	rt = c.Client.Client.Transport.(*http.Transport)
+	if rt.MaxConnsPerHost != 5 || rt.TLSClientConfig != cfg {
+		t.Errorf("got MaxConnsPerHost %d and TLS configuration %v", rt.MaxConnsPerHost, rt.TLSClientConfig)
+	}
+
+	// The HTTP/2 transport multiplexes the requests over one connection per host, the pool
+	// options do not apply to it.
+	c = New(&http.Client{}, WithMaxIdleConns(20), WithMaxConnsPerHost(5), WithHTTP2(false))
+	if _, ok := c.Client.Client.Transport.(*http2.Transport); !ok {
+		t.Errorf("got transport %T with WithHTTP2, expected *http2.Transport", c.Client.Client.Transport)
+	}
+}
+`
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/goadesign/goa/goagen/codegen"
+	"github.com/goadesign/goa/goagen/gen_client"
 	"github.com/goadesign/goa/goagen/meta"
 	"github.com/goadesign/goa/goagen/utils"
 	"github.com/spf13/cobra"
@@ -74,13 +75,70 @@ package and tool and the Swagger specification for the API.
 	rootCmd.AddCommand(mainCmd)
 
 	// clientCmd implements the "client" command.
+	var (
+		idempotency    bool
+		ifMatch        bool
+		wsWrappers     bool
+		patchPointers  bool
+		grouped        bool
+		omitEmpty      bool
+		contentMD5     bool
+		noCLI          bool
+		chunked        bool
+		versioned      bool
+		validate       bool
+		queryStructs   bool
+		orderedQuery   bool
+		validatedPaths bool
+		typesOnly      bool
+		nested         bool
+		examples       bool
+		jsonNaming     string
+		include        string
+		exclude        string
+	)
 	clientCmd := &cobra.Command{
 		Use:   "client",
 		Short: "Generate client package and tool",
 		Run:   func(c *cobra.Command, _ []string) { files, err = run("genclient", c) },
 	}
+	clientCmd.Flags().BoolVar(&idempotency, "idempotency", false, "Generate an Idempotency-Key argument for POST, PUT and PATCH actions")
+	clientCmd.Flags().BoolVar(&ifMatch, "if-match", false, "Generate an If-Match argument for PUT and PATCH actions")
+	clientCmd.Flags().BoolVar(&wsWrappers, "ws-wrappers", false, "Generate typed wrappers for websocket connections")
+	clientCmd.Flags().BoolVar(&patchPointers, "patch-pointers", false, "Generate pointer fields for the payloads of PATCH actions")
+	clientCmd.Flags().BoolVar(&grouped, "grouped", false, "Generate resource clients grouping the action methods, e.g. c.Bottles().Show")
+	clientCmd.Flags().BoolVar(&omitEmpty, "omitempty", false, "Omit all the non-required fields from the encoded payloads when empty, including fields with a default value")
+	clientCmd.Flags().BoolVar(&contentMD5, "content-md5", false, "Set the Content-MD5 header of the requests with a payload to the digest of the encoded body")
+	clientCmd.Flags().BoolVar(&noCLI, "no-cli", false, "Only generate the client package, not the CLI tool")
+	clientCmd.Flags().BoolVar(&chunked, "streaming-uploads", false, "Generate request builders sending payloads read from an io.Reader with the chunked transfer encoding")
+	clientCmd.Flags().BoolVar(&versioned, "versioned", false, "Generate the client package in a subdirectory named after the API major version, e.g. client/v2")
+	clientCmd.Flags().BoolVar(&validate, "validate-responses", false, "Validate the decoded response bodies against the design and return the validation errors from the decode helpers")
+	clientCmd.Flags().BoolVar(&queryStructs, "query-structs", false, "Generate a struct holding the query parameters of each action and pass it to the client methods instead of one argument per parameter")
+	clientCmd.Flags().BoolVar(&orderedQuery, "ordered-query", false, "Build the query strings in the order of the client method arguments or of the client:query-order metadata instead of sorting the parameters by name")
+	clientCmd.Flags().BoolVar(&validatedPaths, "validated-paths", false, "Validate the parameters given to the path factory functions against the design and return an error when they are invalid")
+	clientCmd.Flags().BoolVar(&typesOnly, "types-only", false, "Only generate the data structures of the API types, payloads and media types in a standalone package, without the client methods and the CLI tool")
+	clientCmd.Flags().BoolVar(&nested, "nested-methods", false, "Generate methods taking the path parameters of the actions of nested resources, e.g. c.ShowBottleInAccount(ctx, accountID, bottleID)")
+	clientCmd.Flags().BoolVar(&examples, "examples", false, "Generate an example_test.go file with a runnable example per action initialized with the example values of the design")
+	clientCmd.Flags().StringVar(&jsonNaming, "json-naming", "asis", "Naming strategy of the JSON keys of the generated data structures: snake, camel or asis to use the design attribute names")
+	clientCmd.Flags().StringVar(&include, "include", "", "Comma separated glob patterns of the resources or actions (resource.action) to generate")
+	clientCmd.Flags().StringVar(&exclude, "exclude", "", "Comma separated glob patterns of the resources or actions (resource.action) not to generate")
 	rootCmd.AddCommand(clientCmd)
 
+	// facadeCmd implements the "facade" command.
+	var (
+		services  []string
+		facadePkg string
+	)
+	facadeCmd := &cobra.Command{
+		Use:   "facade",
+		Short: "Generate client packages of several services and a facade aggregating them",
+		Run:   func(c *cobra.Command, _ []string) { files, err = runFacade(c, services, facadePkg) },
+	}
+	facadeCmd.Flags().StringSliceVar(&services, "service", nil, "Name and design package import path of a service given as name=path, e.g. bottles=github.com/acme/bottles/design, the service client package is generated in the name subdirectory of the output directory")
+	facadeCmd.Flags().StringVar(&facadePkg, "pkg", "facade", "Name of the generated facade package")
+	facadeCmd.Flags().Bool("versioned", false, "Generate the service client packages in subdirectories named after the API major versions, e.g. bottles/client/v2")
+	rootCmd.AddCommand(facadeCmd)
+
 	// swaggerCmd implements the "swagger" command.
 	swaggerCmd := &cobra.Command{
 		Use:   "swagger",
@@ -205,6 +263,55 @@ func run(pkg string, c *cobra.Command) ([]string, error) {
 	return generate(pkgName, pkgPath, c)
 }
 
+// runFacade generates the client package of each service given as name=design-package in the
+// name subdirectory of the output directory and the facade package aggregating them.
+func runFacade(c *cobra.Command, services []string, pkg string) ([]string, error) {
+	out := c.Flag("out").Value.String()
+	var (
+		files   []string
+		clients []*genclient.FacadeService
+	)
+	for _, service := range services {
+		elems := strings.SplitN(service, "=", 2)
+		if len(elems) != 2 || elems[0] == "" || elems[1] == "" {
+			return files, fmt.Errorf("invalid service %q, must be of the form name=design-package", service)
+		}
+		flags := map[string]string{
+			"design":    elems[1],
+			"out":       filepath.Join(out, elems[0]),
+			"no-cli":    "true",
+			"versioned": c.Flag("versioned").Value.String(),
+		}
+		if debug := c.Flag("debug"); debug.Changed {
+			flags["debug"] = debug.Value.String()
+		}
+		gen, err := meta.NewGenerator(
+			"genclient.Generate",
+			[]*codegen.ImportSpec{codegen.SimpleImport("github.com/goadesign/goa/goagen/gen_client")},
+			flags,
+		)
+		if err != nil {
+			return files, err
+		}
+		genfiles, err := gen.Generate()
+		files = append(files, genfiles...)
+		if err != nil {
+			return files, err
+		}
+		if len(genfiles) == 0 {
+			return files, fmt.Errorf("no client generated for service %s", elems[0])
+		}
+		// The first file generated by the client generator is the client package directory.
+		client, err := genclient.NewFacadeService(elems[0], genfiles[0])
+		if err != nil {
+			return files, err
+		}
+		clients = append(clients, client)
+	}
+	facadeFiles, err := genclient.GenerateFacade(filepath.Join(out, pkg), pkg, clients)
+	return append(files, facadeFiles...), err
+}
+
 func runGen(c *cobra.Command) ([]string, error) {
 	pkgPath := c.Flag("pkg-path").Value.String()
 	pkgSrcPath, err := codegen.PackageSourcePath(pkgPath)